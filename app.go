@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -10,11 +12,33 @@ import (
 // App struct - Wails app context
 type App struct {
 	ctx context.Context
+
+	// env is the environment of the active profile, it is replaced as a whole and never mutated
+	envMu sync.Mutex
+	env   atomic.Pointer[envContext]
+
+	// shellImport is the report of the last shell environment import
+	shellImportMu sync.Mutex
+	shellImport   *ShellImportReport
+
+	certificateWorkflows *certificateWorkflowEngine
+	tfePlanJobs          *tfePlanJobManager
+	tfeCampaigns         *tfeCampaignManager
+	tfeDiscardScheduler  *tfeDiscardScheduler
+	tfeDriftScanner      *tfeDriftScanner
+	awsSSOLogins         *awsSSOLoginManager
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	app := &App{}
+	app.certificateWorkflows = newCertificateWorkflowEngine(app)
+	app.tfePlanJobs = newTFEPlanJobManager(app)
+	app.tfeCampaigns = newTFECampaignManager(app)
+	app.tfeDiscardScheduler = newTFEDiscardScheduler(app)
+	app.tfeDriftScanner = newTFEDriftScanner(app)
+	app.awsSSOLogins = newAWSSSOLoginManager(app)
+	return app
 }
 
 // startup is called when the app starts, before the frontend is loaded
@@ -26,6 +50,23 @@ func (a *App) startup(ctx context.Context) {
 	if err := a.ImportShellEnvironment(); err != nil {
 		fmt.Printf("Warning: failed to import shell environment on startup: %v\n", err)
 	}
+
+	// Certificate renewals interrupted by a previous shutdown are paused so the operator can resume them
+	if err := a.certificateWorkflows.recover(); err != nil {
+		fmt.Printf("Warning: failed to recover certificate workflows: %v\n", err)
+	}
+	if err := a.tfePlanJobs.recover(); err != nil {
+		fmt.Printf("Warning: failed to recover TFE plan jobs: %v\n", err)
+	}
+	if err := a.tfeCampaigns.recover(); err != nil {
+		fmt.Printf("Warning: failed to recover TFE campaigns: %v\n", err)
+	}
+	if err := a.tfeDriftScanner.recover(); err != nil {
+		fmt.Printf("Warning: failed to recover TFE drift scans: %v\n", err)
+	}
+
+	// Stale TFE runs are discarded in the background according to the saved policy
+	a.tfeDiscardScheduler.start()
 }
 
 // domReady is called after front-end resources have been loaded
@@ -43,6 +84,19 @@ func (a *App) beforeClose(ctx context.Context) (prevent bool) {
 // shutdown is called during application termination
 func (a *App) shutdown(ctx context.Context) {
 	// Perform any teardown of resources here
+	a.tfeDiscardScheduler.stop()
+	a.tfeCampaigns.stopAll()
+	a.tfeDriftScanner.stopAll()
+	a.tfePlanJobs.stopAll()
+	a.awsSSOLogins.stopAll()
+}
+
+// emitEvent sends an event to the frontend, it is a no-op outside of the Wails runtime (e.g. in tests)
+func (a *App) emitEvent(name string, data ...interface{}) {
+	if a.ctx == nil || a.ctx.Value("events") == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data...)
 }
 
 // Greet returns a greeting for the given name
@@ -51,6 +51,7 @@ type ArgoConfig struct {
 	Project  string `json:"project"`
 	Username string `json:"username,omitempty"`
 	Password string `json:"password,omitempty"`
+	Profile  string `json:"profile,omitempty"` // environment profile to query, the active one when empty
 }
 
 // GetArgoApps retrieves ArgoCD applications
@@ -69,12 +70,14 @@ func (a *App) GetArgoApps(config ArgoConfig) ([]ArgoApp, error) {
 	}
 
 	// Execute yak argocd status --json with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak argocd status failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -138,11 +141,14 @@ func (a *App) GetArgoAppDetail(config ArgoConfig, appName string) (*ArgoAppDetai
 
 
 	// Execute yak argocd status --json with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	statusCmd := exec.CommandContext(ctx, findYakExecutable(), statusArgs...)
-	statusOutput, err := statusCmd.Output()
+	statusOutput, err := a.runYak(ctx, statusArgs...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak argocd status failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -185,8 +191,7 @@ func (a *App) GetArgoAppDetail(config ArgoConfig, appName string) (*ArgoAppDetai
 	}
 
 
-	getCmd := exec.CommandContext(ctx, findYakExecutable(), getArgs...)
-	getOutput, err := getCmd.Output()
+	getOutput, err := a.runYak(ctx, getArgs...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak argocd get failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -264,9 +269,11 @@ func (a *App) SyncArgoApp(config ArgoConfig, appName string, prune, dryRun bool)
 	}
 
 	// Execute yak argocd sync
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to sync ArgoCD app: %w", err)
 	}
 
@@ -285,9 +292,11 @@ func (a *App) RefreshArgoApp(config ArgoConfig, appName string) error {
 	}
 
 	// Execute yak argocd refresh
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to refresh ArgoCD app: %w", err)
 	}
 
@@ -306,9 +315,11 @@ func (a *App) SuspendArgoApp(config ArgoConfig, appName string) error {
 	}
 
 	// Execute yak argocd suspend
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to suspend ArgoCD app: %w", err)
 	}
 
@@ -327,9 +338,11 @@ func (a *App) UnsuspendArgoApp(config ArgoConfig, appName string) error {
 	}
 
 	// Execute yak argocd unsuspend
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to unsuspend ArgoCD app: %w", err)
 	}
 
@@ -345,28 +358,34 @@ func (a *App) LoginToArgoCD(config ArgoConfig) error {
 	}
 
 	// Execute yak argocd login
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to login to ArgoCD: %w", err)
 	}
 
 	return nil
 }
 
-// GetArgoCDServerFromProfile gets the ArgoCD server from AWS profile
+// GetArgoCDServerFromProfile gets the ArgoCD server of the current AWS profile from the site config
 func (a *App) GetArgoCDServerFromProfile() (string, error) {
-	// Get current AWS profile
-	profile := a.GetCurrentAWSProfile()
+	return a.argoCDServerFor(a.currentEnv())
+}
+
+// argoCDServerFor gets the ArgoCD server of the AWS profile of an environment
+func (a *App) argoCDServerFor(env *envContext) (string, error) {
+	profile := env.get("AWS_PROFILE")
 	if profile == "" {
 		return "", fmt.Errorf("AWS_PROFILE environment variable is not set")
 	}
 
-	// Remove '-sso' suffix if present
-	cleanProfile := strings.TrimSuffix(profile, "-sso")
-	
-	// Construct ArgoCD server URL
-	server := fmt.Sprintf("argocd-%s.doctolib.net", cleanProfile)
+	// The server URL comes from the site config template or per-profile override
+	site, err := loadSiteConfig(env.get("TFINFRA_REPOSITORY_PATH"))
+	if err != nil {
+		return "", err
+	}
 	
-	return server, nil
+	return site.ArgoCD.argoCDServer(profile)
 }
\ No newline at end of file
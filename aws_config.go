@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// awsSSOProfileSuffix marks the SSO login profile linked to a role profile, e.g. "staging" and "staging-sso"
+const awsSSOProfileSuffix = "-sso"
+
+// AWSProfileInfo describes an AWS profile of the config file for the profile picker
+type AWSProfileInfo struct {
+	Name             string   `json:"name"`
+	Region           string   `json:"region,omitempty"`
+	SSOAccountID     string   `json:"ssoAccountId,omitempty"`
+	SSORoleName      string   `json:"ssoRoleName,omitempty"`
+	SSOSession       string   `json:"ssoSession,omitempty"`
+	SSOStartURL      string   `json:"ssoStartUrl,omitempty"`
+	SourceProfile    string   `json:"sourceProfile,omitempty"`
+	RoleARN          string   `json:"roleArn,omitempty"`
+	Chain            []string `json:"chain"`                // source_profile chain starting at this profile
+	SSOProfile       string   `json:"ssoProfile,omitempty"` // linked -sso profile
+	AccountID        string   `json:"accountId,omitempty"`  // account of the role_arn, or of the SSO login
+	RoleName         string   `json:"roleName,omitempty"`   // role of the role_arn, or of the SSO login
+	Kubeconfig       string   `json:"kubeconfig,omitempty"` // expected kubeconfig under TFINFRA_REPOSITORY_PATH/setup/k8senv
+	KubeconfigExists bool     `json:"kubeconfigExists"`
+	Warnings         []string `json:"warnings"`
+}
+
+// awsConfigFile is a parsed AWS config file, sections are keyed by their header without brackets
+type awsConfigFile struct {
+	Path     string
+	Sections map[string]map[string]string
+	Order    []string
+}
+
+// parseAWSConfig parses the INI dialect of the AWS CLI: "#" and ";" comments, "key = value" pairs,
+// indented continuation lines and nested settings such as "s3 =" followed by indented keys,
+// which are stored as "s3.key".
+func parseAWSConfig(r io.Reader) (*awsConfigFile, error) {
+	config := &awsConfigFile{Sections: map[string]map[string]string{}}
+
+	var section map[string]string
+	var lastKey string
+	lineNumber := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header %q", lineNumber, line)
+			}
+			name := strings.Join(strings.Fields(strings.Trim(line, "[]")), " ")
+			if _, ok := config.Sections[name]; !ok {
+				config.Sections[name] = map[string]string{}
+				config.Order = append(config.Order, name)
+			}
+			section = config.Sections[name]
+			lastKey = ""
+			continue
+		}
+
+		if section == nil {
+			return nil, fmt.Errorf("line %d: setting outside of a section", lineNumber)
+		}
+
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		key, value, hasValue := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case indented && lastKey != "" && section[lastKey] == "" && hasValue:
+			// Nested setting of the last key
+			section[lastKey+"."+key] = value
+		case indented && lastKey != "":
+			// Continuation of the last value
+			section[lastKey] = strings.TrimSpace(section[lastKey] + "\n" + line)
+		case hasValue:
+			section[key] = value
+			lastKey = key
+		default:
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNumber, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading AWS config file: %v", err)
+	}
+	return config, nil
+}
+
+// awsConfigPath returns the AWS config file of an environment, AWS_CONFIG_FILE overrides ~/.aws/config
+func awsConfigPath(env *envContext) (string, error) {
+	if path := env.get("AWS_CONFIG_FILE"); path != "" {
+		if strings.HasPrefix(path, "~/") {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to get user home directory: %v", err)
+			}
+			path = filepath.Join(homeDir, path[2:])
+		}
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// loadAWSConfig parses the AWS config file of an environment, a missing file has no sections
+func loadAWSConfig(env *envContext) (*awsConfigFile, error) {
+	path, err := awsConfigPath(env)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &awsConfigFile{Path: path, Sections: map[string]map[string]string{}}, nil
+		}
+		return nil, fmt.Errorf("failed to open AWS config file at %s: %v", path, err)
+	}
+	defer file.Close()
+
+	config, err := parseAWSConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AWS config file at %s: %v", path, err)
+	}
+	config.Path = path
+	return config, nil
+}
+
+// profile returns the settings of a profile, nil when it is not defined
+func (c *awsConfigFile) profile(name string) map[string]string {
+	if name == "default" {
+		if section, ok := c.Sections["default"]; ok {
+			return section
+		}
+	}
+	return c.Sections["profile "+name]
+}
+
+// ssoSession returns the settings of an sso-session section, nil when it is not defined
+func (c *awsConfigFile) ssoSession(name string) map[string]string {
+	return c.Sections["sso-session "+name]
+}
+
+// profileNames returns the names of all profiles in file order
+func (c *awsConfigFile) profileNames() []string {
+	var names []string
+	for _, section := range c.Order {
+		if section == "default" {
+			names = append(names, "default")
+		} else if name, ok := strings.CutPrefix(section, "profile "); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseRoleARN extracts the account and role name of an IAM role ARN
+func parseRoleARN(arn string) (string, string) {
+	// arn:aws:iam::123456789012:role/path/name
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || !strings.HasPrefix(parts[5], "role/") {
+		return "", ""
+	}
+	role := parts[5][strings.LastIndex(parts[5], "/")+1:]
+	return parts[4], role
+}
+
+// describeProfile resolves the metadata of a profile, following its source_profile chain
+func (c *awsConfigFile) describeProfile(name, tfRepoPath string) AWSProfileInfo {
+	settings := c.profile(name)
+	info := AWSProfileInfo{
+		Name:          name,
+		Region:        settings["region"],
+		SSOAccountID:  settings["sso_account_id"],
+		SSORoleName:   settings["sso_role_name"],
+		SSOSession:    settings["sso_session"],
+		SSOStartURL:   settings["sso_start_url"],
+		SourceProfile: settings["source_profile"],
+		RoleARN:       settings["role_arn"],
+		Chain:         []string{name},
+		Warnings:      []string{},
+	}
+	if info.SSOSession != "" && info.SSOStartURL == "" {
+		if session := c.ssoSession(info.SSOSession); session != nil {
+			info.SSOStartURL = session["sso_start_url"]
+		} else {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("sso-session %s is not defined", info.SSOSession))
+		}
+	}
+
+	// Walk source_profile until a profile without one, the region is inherited along the chain
+	seen := map[string]bool{name: true}
+	current := settings
+	for current["source_profile"] != "" {
+		next := current["source_profile"]
+		if seen[next] {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("source_profile loop through %s", next))
+			break
+		}
+		seen[next] = true
+		info.Chain = append(info.Chain, next)
+		current = c.profile(next)
+		if current == nil {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("source profile %s is not defined", next))
+			break
+		}
+		if info.Region == "" {
+			info.Region = current["region"]
+		}
+	}
+
+	if !strings.HasSuffix(name, awsSSOProfileSuffix) && c.profile(name+awsSSOProfileSuffix) != nil {
+		info.SSOProfile = name + awsSSOProfileSuffix
+	}
+
+	info.AccountID, info.RoleName = parseRoleARN(info.RoleARN)
+	if info.AccountID == "" {
+		info.AccountID, info.RoleName = info.SSOAccountID, info.SSORoleName
+	}
+	if info.AccountID == "" && info.SSOProfile != "" {
+		sso := c.profile(info.SSOProfile)
+		info.AccountID, info.RoleName = sso["sso_account_id"], sso["sso_role_name"]
+	}
+
+	if tfRepoPath != "" {
+		info.Kubeconfig = k8senvKubeconfigPath(tfRepoPath, name)
+		if _, err := os.Stat(info.Kubeconfig); err == nil {
+			info.KubeconfigExists = true
+		} else {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("no kubeconfig at %s", info.Kubeconfig))
+		}
+	}
+	return info
+}
+
+// ssoLoginProfile returns the profile whose SSO session provides the credentials of a profile: the profile
+// itself, a profile of its source_profile chain or its linked -sso profile. It is empty when none uses SSO.
+func (c *awsConfigFile) ssoLoginProfile(name string) string {
+	info := c.describeProfile(name, "")
+	candidates := info.Chain
+	if info.SSOProfile != "" {
+		candidates = append(candidates, info.SSOProfile)
+	}
+	for _, candidate := range candidates {
+		if settings := c.profile(candidate); settings["sso_start_url"] != "" || settings["sso_session"] != "" {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// GetAWSProfileDetails returns the profiles of the AWS config file (excluding -sso profiles) with their
+// region, account, role, source_profile chain, linked -sso profile and terraform-infra kubeconfig
+func (a *App) GetAWSProfileDetails() ([]AWSProfileInfo, error) {
+	env := a.currentEnv()
+	config, err := loadAWSConfig(env)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []AWSProfileInfo{}
+	for _, name := range config.profileNames() {
+		if strings.HasSuffix(name, awsSSOProfileSuffix) {
+			continue
+		}
+		profiles = append(profiles, config.describeProfile(name, env.get("TFINFRA_REPOSITORY_PATH")))
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
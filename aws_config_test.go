@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAWSConfig = `# managed by terraform-infra
+[default]
+region = eu-west-1
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+
+[profile staging-sso]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Developer
+
+[profile staging]
+source_profile = staging-sso
+role_arn = arn:aws:iam::222222222222:role/deploy/Deployer
+s3 =
+  max_concurrent_requests = 20
+
+[profile prod]
+; chained through staging
+source_profile = staging
+role_arn = arn:aws:iam::333333333333:role/Admin
+region = eu-central-1
+
+[profile loop-a]
+source_profile = loop-b
+
+[profile loop-b]
+source_profile = loop-a
+`
+
+// TestParseAWSConfig verifies nested settings and section ordering
+func TestParseAWSConfig(t *testing.T) {
+	config, err := parseAWSConfig(strings.NewReader(testAWSConfig))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "staging-sso", "staging", "prod", "loop-a", "loop-b"}, config.profileNames())
+	assert.Equal(t, "20", config.profile("staging")["s3.max_concurrent_requests"])
+	assert.Equal(t, "https://corp.awsapps.com/start", config.ssoSession("corp")["sso_start_url"])
+
+	_, err = parseAWSConfig(strings.NewReader("region = eu-west-1\n"))
+	assert.Error(t, err)
+}
+
+// TestGetAWSProfileDetails verifies AWS_CONFIG_FILE is honored and profiles are described with their chain and kubeconfig
+func TestGetAWSProfileDetails(t *testing.T) {
+	home := t.TempDir()
+	tfinfra := t.TempDir()
+	configFile := filepath.Join(t.TempDir(), "aws-config")
+	require.NoError(t, os.WriteFile(configFile, []byte(testAWSConfig), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tfinfra, "setup", "k8senv", "staging"), 0755))
+	require.NoError(t, os.WriteFile(k8senvKubeconfigPath(tfinfra, "staging"), nil, 0644))
+
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", tfinfra)
+
+	app := NewApp()
+	names, err := app.GetAWSProfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "loop-a", "loop-b", "prod", "staging"}, names)
+
+	profiles, err := app.GetAWSProfileDetails()
+	require.NoError(t, err)
+	byName := map[string]AWSProfileInfo{}
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+
+	staging := byName["staging"]
+	assert.Equal(t, "staging-sso", staging.SSOProfile)
+	assert.Equal(t, []string{"staging", "staging-sso"}, staging.Chain)
+	assert.Equal(t, "222222222222", staging.AccountID)
+	assert.Equal(t, "Deployer", staging.RoleName)
+	assert.True(t, staging.KubeconfigExists)
+	assert.Empty(t, staging.Warnings)
+
+	prod := byName["prod"]
+	assert.Equal(t, []string{"prod", "staging", "staging-sso"}, prod.Chain)
+	assert.Equal(t, "eu-central-1", prod.Region)
+	assert.Equal(t, "333333333333", prod.AccountID)
+	assert.False(t, prod.KubeconfigExists)
+	require.Len(t, prod.Warnings, 1)
+	assert.Contains(t, prod.Warnings[0], "no kubeconfig")
+
+	assert.Contains(t, strings.Join(byName["loop-a"].Warnings, "\n"), "source_profile loop")
+}
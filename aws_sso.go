@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWS SSO session statuses
+const (
+	awsSessionActive      = "active"
+	awsSessionExpired     = "expired"
+	awsSessionNotLoggedIn = "not-logged-in"
+	awsSessionNotSSO      = "not-sso"
+
+	// awsSSOLoginTimeout bounds `aws sso login`, it waits for the operator to approve in the browser
+	awsSSOLoginTimeout = 10 * time.Minute
+)
+
+// awsReauthMarkers are fragments of AWS CLI and SDK errors reported when SSO credentials have expired
+var awsReauthMarkers = []string{
+	"token has expired and refresh failed",
+	"the sso session associated with this profile has expired",
+	"the sso session has expired",
+	"error when retrieving token from sso",
+	"error loading sso token",
+	"unauthorizedssotokenerror",
+	"sso token is expired",
+	"expiredtokenexception",
+	"the security token included in the request is expired",
+	"run aws sso login",
+	"aws sso login --profile",
+}
+
+// AWSSessionStatus reports whether the SSO credentials of an AWS profile are usable
+type AWSSessionStatus struct {
+	Profile     string     `json:"profile"`
+	SSOProfile  string     `json:"ssoProfile,omitempty"` // profile holding the SSO settings, e.g. the linked -sso profile
+	Status      string     `json:"status"`               // active, expired, not-logged-in, not-sso
+	StartURL    string     `json:"startUrl,omitempty"`
+	Region      string     `json:"region,omitempty"`
+	SessionName string     `json:"sessionName,omitempty"`
+	CacheFile   string     `json:"cacheFile,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"` // end of the session, including silent refreshes
+	ExpiresIn   int64      `json:"expiresIn"`           // seconds until expiry, 0 once expired
+	// TokenExpiresAt is when the cached access token expires, the AWS CLI refreshes it silently while
+	// Refreshable is set, until the client registration expires
+	TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"`
+	Refreshable    bool       `json:"refreshable"`
+	Message        string     `json:"message"`
+}
+
+// AWSReauthRequiredError is returned by yak calls that failed because the AWS SSO session has expired
+type AWSReauthRequiredError struct {
+	Profile string
+	Detail  string
+}
+
+// Error implements error, the "reauthentication required" prefix is stable so the frontend can detect it
+func (e *AWSReauthRequiredError) Error() string {
+	return fmt.Sprintf("reauthentication required: the AWS SSO session of profile %s has expired, log in again (%s)", e.Profile, e.Detail)
+}
+
+// AWSSSOLoginEvent is emitted for every output line of `aws sso login` and once it finishes
+type AWSSSOLoginEvent struct {
+	Profile string `json:"profile"`
+	Line    string `json:"line,omitempty"`
+	Done    bool   `json:"done"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// awsSSOCacheEntry is the layout of a token file in ~/.aws/sso/cache. Tokens of sso-session profiles
+// carry a refresh token, usable until the client registration expires.
+type awsSSOCacheEntry struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+}
+
+// awsSSOLoginManager tracks running `aws sso login` processes, one per profile
+type awsSSOLoginManager struct {
+	app     *App
+	mu      sync.Mutex
+	running map[string]*runningAWSSSOLogin
+}
+
+type runningAWSSSOLogin struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newAWSSSOLoginManager creates a login manager without running logins
+func newAWSSSOLoginManager(app *App) *awsSSOLoginManager {
+	return &awsSSOLoginManager{app: app, running: make(map[string]*runningAWSSSOLogin)}
+}
+
+// stopAll kills the running logins, used at shutdown
+func (m *awsSSOLoginManager) stopAll() {
+	m.mu.Lock()
+	running := make([]*runningAWSSSOLogin, 0, len(m.running))
+	for _, r := range m.running {
+		r.cancel()
+		running = append(running, r)
+	}
+	m.mu.Unlock()
+
+	for _, r := range running {
+		<-r.done
+	}
+}
+
+// parseAWSSSOExpiry parses the expiresAt of a cache entry, the AWS CLI has written several layouts
+func parseAWSSSOExpiry(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05UTC", "2006-01-02T15:04:05Z0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized SSO token expiry %q", value)
+}
+
+// findAWSSSOCacheEntry returns the cached token of a session, looked up by the hashed session name or start URL
+// like the AWS CLI does, then by scanning the cache for the start URL
+func findAWSSSOCacheEntry(cacheDir, sessionName, startURL string) (string, *awsSSOCacheEntry, error) {
+	readEntry := func(path string) *awsSSOCacheEntry {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var entry awsSSOCacheEntry
+		if json.Unmarshal(data, &entry) != nil || entry.AccessToken == "" {
+			return nil
+		}
+		return &entry
+	}
+
+	for _, key := range []string{sessionName, startURL} {
+		if key == "" {
+			continue
+		}
+		sum := sha1.Sum([]byte(key))
+		path := filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json")
+		if entry := readEntry(path); entry != nil {
+			return path, entry, nil
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list the AWS SSO cache: %v", err)
+	}
+	var bestPath string
+	var best *awsSSOCacheEntry
+	var bestExpiry time.Time
+	for _, path := range files {
+		entry := readEntry(path)
+		if entry == nil || entry.StartURL != startURL {
+			continue
+		}
+		expiry, err := parseAWSSSOExpiry(entry.ExpiresAt)
+		if err != nil {
+			continue
+		}
+		if best == nil || expiry.After(bestExpiry) {
+			bestPath, best, bestExpiry = path, entry, expiry
+		}
+	}
+	return bestPath, best, nil
+}
+
+// GetAWSSessionStatus reads the SSO cache under ~/.aws/sso/cache and reports when the session of a profile expires.
+// An empty profile checks the AWS_PROFILE of the active environment.
+func (a *App) GetAWSSessionStatus(profile string) (*AWSSessionStatus, error) {
+	if profile == "" {
+		profile = a.GetCurrentAWSProfile()
+	}
+	if profile == "" {
+		return nil, fmt.Errorf("AWS profile is required")
+	}
+
+	config, err := loadAWSConfig(a.currentEnv())
+	if err != nil {
+		return nil, err
+	}
+	if config.profile(profile) == nil {
+		return nil, fmt.Errorf("AWS profile '%s' not found in %s", profile, config.Path)
+	}
+
+	// Role profiles get their credentials from the SSO login of their source_profile or -sso profile
+	status := &AWSSessionStatus{Profile: profile, SSOProfile: config.ssoLoginProfile(profile)}
+	if status.SSOProfile != "" {
+		section := config.profile(status.SSOProfile)
+		status.StartURL = section["sso_start_url"]
+		status.Region = section["sso_region"]
+		status.SessionName = section["sso_session"]
+	}
+	if status.SessionName != "" {
+		session := config.ssoSession(status.SessionName)
+		if session == nil {
+			return nil, fmt.Errorf("sso-session '%s' of AWS profile '%s' not found in %s", status.SessionName, status.SSOProfile, config.Path)
+		}
+		status.StartURL = session["sso_start_url"]
+		status.Region = session["sso_region"]
+	}
+	if status.StartURL == "" {
+		status.Status = awsSessionNotSSO
+		status.Message = fmt.Sprintf("AWS profile %s does not use SSO", profile)
+		return status, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	path, entry, err := findAWSSSOCacheEntry(filepath.Join(homeDir, ".aws", "sso", "cache"), status.SessionName, status.StartURL)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		status.Status = awsSessionNotLoggedIn
+		status.Message = fmt.Sprintf("no SSO session cached for %s, log in with aws sso login --profile %s", status.StartURL, status.SSOProfile)
+		return status, nil
+	}
+
+	expiry, err := parseAWSSSOExpiry(entry.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSO token %s: %v", path, err)
+	}
+	status.CacheFile = path
+	status.TokenExpiresAt = &expiry
+
+	// The AWS CLI refreshes an expired access token with the refresh token until the registration expires
+	sessionEnd := expiry
+	if entry.RefreshToken != "" && entry.RegistrationExpiresAt != "" {
+		registration, err := parseAWSSSOExpiry(entry.RegistrationExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSO registration %s: %v", path, err)
+		}
+		if registration.After(expiry) {
+			status.Refreshable = true
+			sessionEnd = registration
+		}
+	}
+	status.ExpiresAt = &sessionEnd
+
+	switch {
+	case time.Until(sessionEnd) <= 0:
+		status.Status = awsSessionExpired
+		status.Message = fmt.Sprintf("SSO session expired at %s, log in again", sessionEnd.Local().Format(time.RFC1123))
+	case time.Until(expiry) > 0:
+		status.Status = awsSessionActive
+		status.ExpiresIn = int64(time.Until(sessionEnd).Seconds())
+		status.Message = fmt.Sprintf("SSO session valid until %s", sessionEnd.Local().Format(time.RFC1123))
+	default:
+		status.Status = awsSessionActive
+		status.ExpiresIn = int64(time.Until(sessionEnd).Seconds())
+		status.Message = fmt.Sprintf("SSO access token expired at %s, the AWS CLI refreshes it until %s",
+			expiry.Local().Format(time.RFC1123), sessionEnd.Local().Format(time.RFC1123))
+	}
+	return status, nil
+}
+
+// awsReauthError returns an *AWSReauthRequiredError when a failed command reported expired AWS SSO credentials,
+// nil otherwise. The profile is taken from the environment the command ran with.
+func (a *App) awsReauthError(ctx context.Context, err error, output []byte) error {
+	if err == nil {
+		return nil
+	}
+
+	text := string(output)
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		text += "\n" + string(exitError.Stderr)
+	}
+	lower := strings.ToLower(text)
+
+	for _, marker := range awsReauthMarkers {
+		if !strings.Contains(lower, marker) {
+			continue
+		}
+		detail := marker
+		for _, line := range strings.Split(text, "\n") {
+			if strings.Contains(strings.ToLower(line), marker) {
+				detail = strings.TrimSpace(line)
+				break
+			}
+		}
+		return &AWSReauthRequiredError{Profile: a.envFor(ctx).get("AWS_PROFILE"), Detail: detail}
+	}
+	return nil
+}
+
+// LoginAWSSSO starts `aws sso login` for a profile and streams its output as "aws-sso:login" events.
+// It returns once the login has started, the last event has Done set. An empty profile logs in the
+// AWS_PROFILE of the active environment. Role profiles log in with the profile holding their SSO settings.
+func (a *App) LoginAWSSSO(profile string) error {
+	env := a.currentEnv()
+	if profile == "" {
+		profile = env.get("AWS_PROFILE")
+	}
+	if profile == "" {
+		return fmt.Errorf("AWS profile is required")
+	}
+	config, err := loadAWSConfig(env)
+	if err != nil {
+		return err
+	}
+	loginProfile := config.ssoLoginProfile(profile)
+	if loginProfile == "" {
+		return fmt.Errorf("AWS profile %s does not use SSO", profile)
+	}
+
+	m := a.awsSSOLogins
+	m.mu.Lock()
+	if m.running[profile] != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("an AWS SSO login is already running for profile %s", profile)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), awsSSOLoginTimeout)
+	running := &runningAWSSSOLogin{cancel: cancel, done: make(chan struct{})}
+	m.running[profile] = running
+	m.mu.Unlock()
+
+	finish := func() {
+		cancel()
+		m.mu.Lock()
+		delete(m.running, profile)
+		m.mu.Unlock()
+		close(running.done)
+	}
+
+	reader, writer := io.Pipe()
+	cmd := env.command(ctx, "aws", "sso", "login", "--profile", loginProfile)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		finish()
+		return fmt.Errorf("failed to start aws sso login: %v", err)
+	}
+
+	lines := make(chan struct{})
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			a.emitEvent("aws-sso:login", AWSSSOLoginEvent{Profile: profile, Line: scanner.Text()})
+		}
+		io.Copy(io.Discard, reader)
+	}()
+
+	go func() {
+		defer finish()
+		err := cmd.Wait()
+		writer.Close()
+		<-lines
+
+		event := AWSSSOLoginEvent{Profile: profile, Done: true, Success: err == nil}
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				event.Error = fmt.Sprintf("aws sso login timed out after %s", awsSSOLoginTimeout)
+			} else {
+				event.Error = err.Error()
+			}
+		}
+		a.emitEvent("aws-sso:login", event)
+	}()
+	return nil
+}
+
+// CancelAWSSSOLogin stops a running `aws sso login`
+func (a *App) CancelAWSSSOLogin(profile string) error {
+	m := a.awsSSOLogins
+	m.mu.Lock()
+	running := m.running[profile]
+	m.mu.Unlock()
+	if running == nil {
+		return fmt.Errorf("no AWS SSO login is running for profile %s", profile)
+	}
+	running.cancel()
+	<-running.done
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSSOCacheEntry stores a token in the SSO cache under the file name the AWS CLI uses for key
+func writeSSOCacheEntry(t *testing.T, home, key, startURL string, expiresAt time.Time) {
+	dir := filepath.Join(home, ".aws", "sso", "cache")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	sum := sha1.Sum([]byte(key))
+	data := fmt.Sprintf(`{"startUrl": %q, "region": "eu-west-1", "accessToken": "token", "expiresAt": %q}`,
+		startURL, expiresAt.UTC().Format(time.RFC3339))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), []byte(data), 0600))
+}
+
+// writeRefreshableSSOCacheEntry stores a token of an sso-session with its refresh token and client registration expiry
+func writeRefreshableSSOCacheEntry(t *testing.T, home, session, startURL string, expiresAt, registrationExpiresAt time.Time) {
+	dir := filepath.Join(home, ".aws", "sso", "cache")
+	require.NoError(t, os.MkdirAll(dir, 0700))
+	sum := sha1.Sum([]byte(session))
+	data := fmt.Sprintf(`{"startUrl": %q, "region": "eu-west-1", "accessToken": "token", "expiresAt": %q, "refreshToken": "refresh", "clientId": "id", "clientSecret": "secret", "registrationExpiresAt": %q}`,
+		startURL, expiresAt.UTC().Format(time.RFC3339), registrationExpiresAt.UTC().Format(time.RFC3339))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), []byte(data), 0600))
+}
+
+// TestAWSSessionStatus verifies session and legacy SSO profiles are matched with their cached tokens
+func TestAWSSessionStatus(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".aws"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+
+[profile staging]
+sso_session = corp
+sso_account_id = 111111111111
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+
+[profile static]
+region = eu-west-1
+`), 0644))
+
+	app := NewApp()
+
+	status, err := app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionNotLoggedIn, status.Status)
+
+	writeSSOCacheEntry(t, home, "corp", "https://corp.awsapps.com/start", time.Now().Add(time.Hour))
+	status, err = app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionActive, status.Status)
+	assert.Equal(t, "corp", status.SessionName)
+	assert.Greater(t, status.ExpiresIn, int64(3000))
+	assert.False(t, status.Refreshable)
+
+	// An expired access token is refreshed silently while the client registration is valid
+	writeRefreshableSSOCacheEntry(t, home, "corp", "https://corp.awsapps.com/start", time.Now().Add(-time.Minute), time.Now().Add(72*time.Hour))
+	status, err = app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionActive, status.Status)
+	assert.True(t, status.Refreshable)
+	assert.Greater(t, status.ExpiresIn, int64(71*3600))
+	assert.True(t, status.TokenExpiresAt.Before(time.Now()))
+
+	writeRefreshableSSOCacheEntry(t, home, "corp", "https://corp.awsapps.com/start", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	status, err = app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionExpired, status.Status)
+	assert.Zero(t, status.ExpiresIn)
+
+	writeSSOCacheEntry(t, home, "https://legacy.awsapps.com/start", "https://legacy.awsapps.com/start", time.Now().Add(-time.Minute))
+	status, err = app.GetAWSSessionStatus("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionExpired, status.Status)
+	assert.Zero(t, status.ExpiresIn)
+
+	status, err = app.GetAWSSessionStatus("static")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionNotSSO, status.Status)
+
+	_, err = app.GetAWSSessionStatus("missing")
+	assert.Error(t, err)
+}
+
+// testRoleProfilesConfig is the terraform-infra layout: the role profiles of the picker source their -sso profile
+const testRoleProfilesConfig = `
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+
+[profile staging-sso]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile staging]
+role_arn = arn:aws:iam::222222222222:role/Deploy
+source_profile = staging-sso
+`
+
+// TestAWSSessionStatusSourceProfile verifies a role profile reports the session of the -sso profile it sources
+func TestAWSSessionStatusSourceProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".aws"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(testRoleProfilesConfig), 0644))
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"AWS_CONFIG_FILE": ""}))
+
+	writeSSOCacheEntry(t, home, "corp", "https://corp.awsapps.com/start", time.Now().Add(-time.Minute))
+	status, err := app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionExpired, status.Status)
+	assert.Equal(t, "staging-sso", status.SSOProfile)
+	assert.Equal(t, "corp", status.SessionName)
+
+	writeSSOCacheEntry(t, home, "corp", "https://corp.awsapps.com/start", time.Now().Add(time.Hour))
+	status, err = app.GetAWSSessionStatus("staging")
+	require.NoError(t, err)
+	assert.Equal(t, awsSessionActive, status.Status)
+}
+
+// TestAWSReauthError verifies expired SSO credentials are reported as a typed error
+func TestAWSReauthError(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	t.Setenv("AWS_PROFILE", "staging")
+	app := NewApp()
+	ctx := context.Background()
+
+	output, err := exec.Command("/bin/sh", "-c", "echo 'Error when retrieving token from sso: Token has expired and refresh failed' >&2; exit 1").Output()
+	reauthErr := app.awsReauthError(ctx, err, output)
+	var typed *AWSReauthRequiredError
+	require.True(t, errors.As(reauthErr, &typed))
+	assert.Equal(t, "staging", typed.Profile)
+	assert.Contains(t, reauthErr.Error(), "reauthentication required")
+
+	output, err = exec.Command("/bin/sh", "-c", "echo 'application not found'; exit 1").CombinedOutput()
+	assert.NoError(t, app.awsReauthError(ctx, err, output))
+}
+
+// TestRunYakReauth verifies yak failures caused by expired SSO credentials surface as a typed error from every caller
+func TestRunYakReauth(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "yak"), []byte(`#!/bin/sh
+case "$1" in
+certificate) echo "gandi token rejected"; exit 1 ;;
+esac
+echo 'Error when retrieving token from sso: Token has expired and refresh failed' >&2
+exit 1
+`), 0755))
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("AWS_PROFILE", "staging")
+	app := NewApp()
+	ctx := context.Background()
+
+	var typed *AWSReauthRequiredError
+	_, err := app.runYak(ctx, "argocd", "status")
+	assert.True(t, errors.As(err, &typed))
+	_, err = app.runYakCombined(ctx, "rollouts", "list")
+	assert.True(t, errors.As(err, &typed))
+	assert.True(t, errors.As(app.SyncArgoApp(ArgoConfig{}, "web", false, false), &typed))
+
+	// Other failures keep the error of the command
+	output, err := app.runYakCombined(ctx, "certificate", "gandi-check")
+	var exitError *exec.ExitError
+	assert.True(t, errors.As(err, &exitError))
+	assert.Equal(t, "gandi token rejected\n", string(output))
+
+	operation, err := app.CheckGandiToken()
+	require.NoError(t, err)
+	assert.False(t, operation.Success)
+}
+
+// TestLoginAWSSSO verifies the login runs aws sso login for the profile and finishes in the background
+func TestLoginAWSSSO(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	bin := t.TempDir()
+	marker := filepath.Join(t.TempDir(), "args")
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "aws"), []byte("#!/bin/sh\necho \"$@\" > "+marker+"\necho 'Attempting to open the browser'\n"), 0755))
+
+	awsConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(awsConfig, []byte(testRoleProfilesConfig+"\n[profile static]\nregion = eu-west-1\n"), 0644))
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"PATH": bin, "AWS_PROFILE": "staging", "AWS_CONFIG_FILE": awsConfig}))
+
+	assert.ErrorContains(t, app.LoginAWSSSO("static"), "does not use SSO")
+	require.NoError(t, app.LoginAWSSSO(""))
+	app.awsSSOLogins.mu.Lock()
+	running := app.awsSSOLogins.running["staging"]
+	app.awsSSOLogins.mu.Unlock()
+	if running != nil {
+		<-running.done
+	}
+
+	args, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t, "sso login --profile staging-sso\n", string(args))
+	assert.Error(t, app.CancelAWSSSOLogin("staging"), "the login has finished")
+}
@@ -97,14 +97,20 @@ func TestStructDefinitions(t *testing.T) {
 
 	// Test Environment structs
 	envProfile := EnvironmentProfile{
-		Name:                    "test-profile",
-		AWSProfile:              "production",
-		Kubeconfig:              "/path/to/kubeconfig",
-		PATH:                    "/usr/bin:/bin",
-		TfInfraRepositoryPath:   "/path/to/terraform-infra",
-		CreatedAt:               "2024-01-01T00:00:00Z",
+		Name:      "test-profile",
+		Variables: []EnvironmentVariable{
+			{Name: "AWS_PROFILE", Value: "production"},
+			{Name: "KUBECONFIG", Value: "/path/to/kubeconfig"},
+			{Name: "PATH", Value: "/usr/bin:/bin"},
+			{Name: "TFINFRA_REPOSITORY_PATH", Value: "/path/to/terraform-infra"},
+			{Name: "GANDI_TOKEN", Secret: true},
+		},
+		CreatedAt: "2024-01-01T00:00:00Z",
 	}
 	assert.Equal(t, "test-profile", envProfile.Name)
+	awsProfile, ok := envProfile.variable("AWS_PROFILE")
+	assert.True(t, ok)
+	assert.Equal(t, "production", awsProfile.Value)
 }
 
 // TestHelperFunctions tests that helper functions work correctly
@@ -160,6 +166,8 @@ func TestHelperFunctions(t *testing.T) {
 
 // TestMethodSignatures tests that all methods have correct signatures
 func TestMethodSignatures(t *testing.T) {
+	// Notifications are really sent, the settings of the developer must not be used
+	t.Setenv("HOME", t.TempDir())
 	app := NewApp()
 	ctx := context.Background()
 	app.startup(ctx)
@@ -248,10 +256,11 @@ func TestMethodSignatures(t *testing.T) {
 		// This might succeed or fail depending on system setup, don't assert on error
 
 		_, err = app.ListCertificates()
-		assert.NoError(t, err) // This should work as it returns hardcoded list
+		// Fails with a *SiteNotConfiguredError unless a site file lists certificates
 
-		_, err = app.SendCertificateNotification("test-cert", "2024-01-01", "renewal")
-		assert.NoError(t, err) // This should work as it just generates a template
+		result, err := app.SendCertificateNotification("test-cert", "2024-01-01", "renewal")
+		assert.NoError(t, err)
+		assert.False(t, result.Success) // Nothing is sent without a configured transport
 	})
 
 	t.Run("JWT methods exist", func(t *testing.T) {
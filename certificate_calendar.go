@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultCalendarLeadDays are the reminder lead times used when none are given
+var defaultCalendarLeadDays = []int{30, 7, 1}
+
+// ExportCertificateCalendar writes an iCalendar (.ics) file with one event per certificate expiration
+// and a reminder for each lead time, in days before the expiration
+func (a *App) ExportCertificateCalendar(path string, leadDays []int) (*CertificateOperation, error) {
+	if path == "" {
+		return nil, fmt.Errorf("export path is required")
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".ics") {
+		path += ".ics"
+	}
+
+	for _, days := range leadDays {
+		if days < 0 {
+			return nil, fmt.Errorf("lead days cannot be negative, got %d", days)
+		}
+	}
+	if len(leadDays) == 0 {
+		leadDays = defaultCalendarLeadDays
+	}
+
+	report, err := a.GetCertificateStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := buildCertificateCalendar(report.Statuses, leadDays, time.Now())
+
+	if err := writeFileAtomic(path, []byte(calendar), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write calendar file: %v", err)
+	}
+
+	message := fmt.Sprintf("Exported %d certificate expirations to %s", len(report.Statuses), path)
+	if len(report.Skipped) > 0 {
+		var skipped []string
+		for _, cert := range report.Skipped {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", cert.Name, cert.Reason))
+		}
+		message += fmt.Sprintf(", %d certificates left out: %s", len(report.Skipped), strings.Join(skipped, ", "))
+	}
+
+	return &CertificateOperation{
+		Success: true,
+		Message: message,
+		Output:  calendar,
+	}, nil
+}
+
+// buildCertificateCalendar renders certificate expirations as an RFC 5545 calendar
+func buildCertificateCalendar(statuses []CertificateStatus, leadDays []int, now time.Time) string {
+	sorted := append([]CertificateStatus(nil), statuses...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Expiration.Equal(sorted[j].Expiration) {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Expiration.Before(sorted[j].Expiration)
+	})
+
+	seen := make(map[int]bool)
+	var leads []int
+	for _, days := range leadDays {
+		if !seen[days] {
+			seen[days] = true
+			leads = append(leads, days)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(leads)))
+
+	var lines []string
+	lines = append(lines,
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Yak GUI//Certificate Expirations//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Certificate expirations",
+	)
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, cert := range sorted {
+		day := cert.Expiration.UTC()
+		summary := fmt.Sprintf("Certificate %s expires", cert.Name)
+		description := fmt.Sprintf("Certificate: %s\nSubject: %s\nIssuer: %s\nSerial number: %s\nExpires at: %s",
+			cert.Name, cert.Subject, cert.Issuer, cert.SerialNumber, cert.Expiration.UTC().Format(time.RFC3339))
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+certificateEventUID(cert),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+day.Format("20060102"),
+			"DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escapeICalText(summary),
+			"DESCRIPTION:"+escapeICalText(description),
+			"CATEGORIES:CERTIFICATE",
+			"TRANSP:TRANSPARENT",
+		)
+
+		for _, days := range leads {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"DESCRIPTION:"+escapeICalText(fmt.Sprintf("%s in %d days", summary, days)),
+				fmt.Sprintf("TRIGGER;RELATED=START:-P%dD", days),
+				"END:VALARM",
+			)
+		}
+
+		lines = append(lines, "END:VEVENT")
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// certificateEventUID derives a stable UID from the certificate identity so re-imports update the same event
+func certificateEventUID(cert CertificateStatus) string {
+	identity := cert.SerialNumber
+	if identity == "" {
+		identity = cert.Expiration.UTC().Format(time.RFC3339)
+	}
+	sum := sha256.Sum256([]byte(cert.Name + "|" + identity))
+	return hex.EncodeToString(sum[:16]) + "@yak-gui"
+}
+
+// escapeICalText escapes a TEXT property value as defined in RFC 5545 section 3.3.11
+func escapeICalText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// foldICalLine folds content lines longer than 75 octets without splitting UTF-8 sequences
+func foldICalLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const certificateWorkflowsFile = "certificate-workflows.json"
+
+// Workflow statuses
+const (
+	workflowPending   = "pending"
+	workflowRunning   = "running"
+	workflowPaused    = "paused"
+	workflowFailed    = "failed"
+	workflowCompleted = "completed"
+)
+
+// Workflow step statuses
+const (
+	stepPending   = "pending"
+	stepRunning   = "running"
+	stepSucceeded = "succeeded"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+// stepSkippedError reports a step with nothing to do, it does not fail the workflow
+type stepSkippedError struct {
+	reason string
+}
+
+func (e *stepSkippedError) Error() string {
+	return e.reason
+}
+
+// certificateRenewalSteps is the ordered certificate renewal runbook
+var certificateRenewalSteps = []struct {
+	Name  string
+	Title string
+}{
+	{"check-gandi-token", "Check Gandi token"},
+	{"renew", "Renew certificate"},
+	{"refresh-secret", "Refresh certificate secret"},
+	{"describe-secret", "Describe certificate secret"},
+	{"notify", "Send notification"},
+}
+
+// CertificateWorkflowStep represents the state of a single runbook step
+type CertificateWorkflowStep struct {
+	Name       string     `json:"name"`
+	Title      string     `json:"title"`
+	Status     string     `json:"status"` // pending, running, succeeded, failed, skipped
+	Message    string     `json:"message,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	Attempts   int        `json:"attempts"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// CertificateWorkflow represents a persisted certificate renewal runbook execution
+type CertificateWorkflow struct {
+	ID             string                    `json:"id"`
+	Certificate    string                    `json:"certificate"`
+	JiraTicket     string                    `json:"jiraTicket"`
+	OperationDate  string                    `json:"operationDate"`
+	Profile        string                    `json:"profile,omitempty"` // environment profile the workflow was started under
+	Status         string                    `json:"status"`            // pending, running, paused, failed, completed
+	CurrentStep    int                       `json:"currentStep"`
+	Steps          []CertificateWorkflowStep `json:"steps"`
+	PauseRequested bool                      `json:"pauseRequested"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+	UpdatedAt      time.Time                 `json:"updatedAt"`
+}
+
+// certificateWorkflowEngine runs certificate workflows one step at a time and persists every transition
+type certificateWorkflowEngine struct {
+	app     *App
+	mu      sync.Mutex
+	running map[string]bool
+	envs    map[string]*envContext // environment each workflow was started under, kept across pause and resume
+
+	// runStep executes a single step, it is replaceable for tests
+	runStep func(ctx context.Context, wf CertificateWorkflow, step string) (*CertificateOperation, error)
+}
+
+func newCertificateWorkflowEngine(app *App) *certificateWorkflowEngine {
+	engine := &certificateWorkflowEngine{
+		app:     app,
+		running: make(map[string]bool),
+		envs:    make(map[string]*envContext),
+	}
+	engine.runStep = engine.executeStep
+	return engine
+}
+
+// executeStep runs the yak command backing a runbook step with the environment of ctx
+func (e *certificateWorkflowEngine) executeStep(ctx context.Context, wf CertificateWorkflow, step string) (*CertificateOperation, error) {
+	switch step {
+	case "check-gandi-token":
+		return e.app.checkGandiToken(ctx)
+	case "renew":
+		return e.app.renewCertificate(ctx, wf.Certificate, wf.JiraTicket)
+	case "refresh-secret":
+		return e.app.refreshCertificateSecret(ctx, wf.Certificate, wf.JiraTicket)
+	case "describe-secret":
+		return e.app.describeCertificateSecret(ctx, wf.Certificate, 0, 0)
+	case "notify":
+		// The certificate is already renewed, a missing transport must not fail the workflow
+		settings, err := e.app.GetNotificationSettings()
+		if err != nil {
+			return nil, err
+		}
+		if settings.Transport == "" {
+			return nil, &stepSkippedError{reason: "No notification transport configured, notification was not sent"}
+		}
+		return e.app.SendCertificateNotification(wf.Certificate, wf.OperationDate, "renewal")
+	default:
+		return nil, fmt.Errorf("unknown workflow step %s", step)
+	}
+}
+
+// load reads all workflows from disk, the caller must hold e.mu
+func (e *certificateWorkflowEngine) load() ([]CertificateWorkflow, error) {
+	var workflows []CertificateWorkflow
+	if _, err := loadStateFile(certificateWorkflowsFile, &workflows); err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// update applies fn to a single workflow and persists the result, the caller must hold e.mu
+func (e *certificateWorkflowEngine) update(id string, fn func(wf *CertificateWorkflow) error) (*CertificateWorkflow, error) {
+	var workflows []CertificateWorkflow
+	var wf CertificateWorkflow
+	err := updateStateFile(certificateWorkflowsFile, &workflows, func() error {
+		for i := range workflows {
+			if workflows[i].ID != id {
+				continue
+			}
+			if err := fn(&workflows[i]); err != nil {
+				return err
+			}
+			workflows[i].UpdatedAt = time.Now()
+			wf = workflows[i]
+			return nil
+		}
+		return fmt.Errorf("certificate workflow '%s' not found", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.app.emitEvent("certificate-workflow:update", wf)
+	return &wf, nil
+}
+
+// recover pauses workflows that were running when the application last stopped
+func (e *certificateWorkflowEngine) recover() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	workflows, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range workflows {
+		wf := &workflows[i]
+		if wf.Status != workflowRunning || e.running[wf.ID] {
+			continue
+		}
+		if wf.CurrentStep < len(wf.Steps) && wf.Steps[wf.CurrentStep].Status == stepRunning {
+			wf.Steps[wf.CurrentStep].Status = stepPending
+			wf.Steps[wf.CurrentStep].Message = "Interrupted by application restart"
+		}
+		wf.Status = workflowPaused
+		wf.PauseRequested = false
+		wf.UpdatedAt = time.Now()
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return saveStateFile(certificateWorkflowsFile, workflows)
+}
+
+// start launches the background runner for a workflow unless it is already running.
+// Workflows resumed after a restart no longer have their original environment and use the active one.
+func (e *certificateWorkflowEngine) start(id string) {
+	e.mu.Lock()
+	if e.running[id] {
+		e.mu.Unlock()
+		return
+	}
+	e.running[id] = true
+	env, ok := e.envs[id]
+	if !ok {
+		env = e.app.currentEnv()
+		e.envs[id] = env
+	}
+	e.mu.Unlock()
+
+	go e.run(withEnvContext(context.Background(), env), id)
+}
+
+// run executes the remaining steps of a workflow until it completes, fails or is paused
+func (e *certificateWorkflowEngine) run(ctx context.Context, id string) {
+	defer func() {
+		e.mu.Lock()
+		delete(e.running, id)
+		e.mu.Unlock()
+	}()
+
+	for {
+		e.mu.Lock()
+		wf, err := e.update(id, func(wf *CertificateWorkflow) error {
+			if wf.PauseRequested || wf.Status == workflowPaused {
+				wf.Status = workflowPaused
+				wf.PauseRequested = false
+				return nil
+			}
+			if wf.CurrentStep >= len(wf.Steps) {
+				wf.Status = workflowCompleted
+				return nil
+			}
+			now := time.Now()
+			step := &wf.Steps[wf.CurrentStep]
+			step.Status = stepRunning
+			step.Attempts++
+			step.StartedAt = &now
+			step.FinishedAt = nil
+			step.Message = ""
+			wf.Status = workflowRunning
+			return nil
+		})
+		e.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: certificate workflow %s stopped: %v\n", id, err)
+			return
+		}
+		if wf.Status != workflowRunning {
+			return
+		}
+
+		stepName := wf.Steps[wf.CurrentStep].Name
+		result, stepErr := e.runStep(ctx, *wf, stepName)
+
+		e.mu.Lock()
+		wf, err = e.update(id, func(wf *CertificateWorkflow) error {
+			now := time.Now()
+			step := &wf.Steps[wf.CurrentStep]
+			step.FinishedAt = &now
+
+			var skipped *stepSkippedError
+			switch {
+			case errors.As(stepErr, &skipped):
+				step.Status = stepSkipped
+				step.Message = skipped.Error()
+			case stepErr != nil:
+				step.Status = stepFailed
+				step.Message = stepErr.Error()
+			case result == nil || !result.Success:
+				step.Status = stepFailed
+				if result != nil {
+					step.Message = result.Message
+					step.Output = result.Output
+				}
+			default:
+				step.Status = stepSucceeded
+				step.Message = result.Message
+				step.Output = result.Output
+			}
+
+			if step.Status == stepFailed {
+				wf.Status = workflowFailed
+				wf.PauseRequested = false
+				return nil
+			}
+			wf.CurrentStep++
+			return nil
+		})
+		e.mu.Unlock()
+		if err != nil {
+			fmt.Printf("Warning: certificate workflow %s stopped: %v\n", id, err)
+			return
+		}
+		if wf.Status == workflowFailed {
+			return
+		}
+	}
+}
+
+// StartCertificateRenewal creates a certificate renewal workflow and starts running it in the background
+func (a *App) StartCertificateRenewal(certificateName, jiraTicket, operationDate string) (*CertificateWorkflow, error) {
+	if certificateName == "" {
+		return nil, fmt.Errorf("certificate name is required")
+	}
+	if jiraTicket == "" {
+		return nil, fmt.Errorf("JIRA ticket is required")
+	}
+	if operationDate == "" {
+		operationDate = time.Now().Format("2006-01-02")
+	}
+
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	workflows, err := e.load()
+	if err != nil {
+		e.mu.Unlock()
+		return nil, err
+	}
+
+	for _, wf := range workflows {
+		if wf.Certificate == certificateName && wf.Status != workflowCompleted {
+			e.mu.Unlock()
+			return nil, fmt.Errorf("certificate %s already has an unfinished renewal workflow (%s)", certificateName, wf.ID)
+		}
+	}
+
+	env := a.currentEnv()
+	now := time.Now()
+	wf := CertificateWorkflow{
+		ID:            fmt.Sprintf("%s-%d", certificateName, now.UnixNano()),
+		Certificate:   certificateName,
+		JiraTicket:    jiraTicket,
+		OperationDate: operationDate,
+		Profile:       env.profile,
+		Status:        workflowPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	for _, step := range certificateRenewalSteps {
+		wf.Steps = append(wf.Steps, CertificateWorkflowStep{
+			Name:   step.Name,
+			Title:  step.Title,
+			Status: stepPending,
+		})
+	}
+
+	workflows = append(workflows, wf)
+	err = saveStateFile(certificateWorkflowsFile, workflows)
+	if err == nil {
+		e.envs[wf.ID] = env
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	e.start(wf.ID)
+	return &wf, nil
+}
+
+// GetCertificateWorkflows returns all certificate workflows, most recent first
+func (a *App) GetCertificateWorkflows() ([]CertificateWorkflow, error) {
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	workflows, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	if workflows == nil {
+		workflows = []CertificateWorkflow{}
+	}
+
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].CreatedAt.After(workflows[j].CreatedAt)
+	})
+	return workflows, nil
+}
+
+// GetCertificateWorkflow returns a single certificate workflow
+func (a *App) GetCertificateWorkflow(id string) (*CertificateWorkflow, error) {
+	workflows, err := a.GetCertificateWorkflows()
+	if err != nil {
+		return nil, err
+	}
+	for _, wf := range workflows {
+		if wf.ID == id {
+			return &wf, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate workflow '%s' not found", id)
+}
+
+// PauseCertificateWorkflow pauses a workflow once its current step has finished
+func (a *App) PauseCertificateWorkflow(id string) (*CertificateWorkflow, error) {
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	running := e.running[id]
+	return e.update(id, func(wf *CertificateWorkflow) error {
+		switch wf.Status {
+		case workflowRunning, workflowPending:
+			if running {
+				wf.PauseRequested = true
+			} else {
+				wf.Status = workflowPaused
+			}
+			return nil
+		default:
+			return fmt.Errorf("cannot pause a %s workflow", wf.Status)
+		}
+	})
+}
+
+// ResumeCertificateWorkflow continues a paused workflow from its current step
+func (a *App) ResumeCertificateWorkflow(id string) (*CertificateWorkflow, error) {
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	wf, err := e.update(id, func(wf *CertificateWorkflow) error {
+		if wf.Status != workflowPaused && wf.Status != workflowPending {
+			return fmt.Errorf("cannot resume a %s workflow", wf.Status)
+		}
+		wf.Status = workflowPending
+		wf.PauseRequested = false
+		return nil
+	})
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	e.start(id)
+	return wf, nil
+}
+
+// RetryCertificateWorkflowStep re-runs the failed step of a workflow and continues with the remaining steps
+func (a *App) RetryCertificateWorkflowStep(id string) (*CertificateWorkflow, error) {
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	wf, err := e.update(id, func(wf *CertificateWorkflow) error {
+		if wf.Status != workflowFailed {
+			return fmt.Errorf("cannot retry a %s workflow", wf.Status)
+		}
+		wf.Steps[wf.CurrentStep].Status = stepPending
+		wf.Status = workflowPending
+		return nil
+	})
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	e.start(id)
+	return wf, nil
+}
+
+// DeleteCertificateWorkflow removes a workflow that is not currently running
+func (a *App) DeleteCertificateWorkflow(id string) error {
+	e := a.certificateWorkflows
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running[id] {
+		return fmt.Errorf("certificate workflow '%s' is running, pause it first", id)
+	}
+
+	workflows, err := e.load()
+	if err != nil {
+		return err
+	}
+
+	var remaining []CertificateWorkflow
+	found := false
+	for _, wf := range workflows {
+		if wf.ID == id {
+			found = true
+			continue
+		}
+		remaining = append(remaining, wf)
+	}
+	if !found {
+		return fmt.Errorf("certificate workflow '%s' not found", id)
+	}
+
+	delete(e.envs, id)
+	return saveStateFile(certificateWorkflowsFile, remaining)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitCertificateWorkflow waits for the runner of a workflow to stop and returns the workflow
+func waitCertificateWorkflow(t *testing.T, app *App, id string) *CertificateWorkflow {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		app.certificateWorkflows.mu.Lock()
+		running := app.certificateWorkflows.running[id]
+		app.certificateWorkflows.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("certificate workflow %s did not stop", id)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	wf, err := app.GetCertificateWorkflow(id)
+	require.NoError(t, err)
+	return wf
+}
+
+// stepStatuses returns the status of every step of a workflow
+func stepStatuses(wf *CertificateWorkflow) []string {
+	var statuses []string
+	for _, step := range wf.Steps {
+		statuses = append(statuses, step.Status)
+	}
+	return statuses
+}
+
+// TestCertificateWorkflowRun verifies steps run in order and the first failure stops the workflow
+func TestCertificateWorkflowRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		failing  string
+		result   *CertificateOperation
+		err      error
+		status   string
+		current  int
+		steps    []string
+		message  string
+		executed []string
+	}{
+		{
+			name:     "all steps succeed",
+			status:   workflowCompleted,
+			current:  5,
+			steps:    []string{stepSucceeded, stepSucceeded, stepSucceeded, stepSucceeded, stepSucceeded},
+			executed: []string{"check-gandi-token", "renew", "refresh-secret", "describe-secret", "notify"},
+		},
+		{
+			name:     "step returns an error",
+			failing:  "renew",
+			err:      errors.New("yak not found"),
+			status:   workflowFailed,
+			current:  1,
+			steps:    []string{stepSucceeded, stepFailed, stepPending, stepPending, stepPending},
+			message:  "yak not found",
+			executed: []string{"check-gandi-token", "renew"},
+		},
+		{
+			name:     "step reports a failure",
+			failing:  "refresh-secret",
+			result:   &CertificateOperation{Success: false, Message: "secret not found"},
+			status:   workflowFailed,
+			current:  2,
+			steps:    []string{stepSucceeded, stepSucceeded, stepFailed, stepPending, stepPending},
+			message:  "secret not found",
+			executed: []string{"check-gandi-token", "renew", "refresh-secret"},
+		},
+		{
+			name:     "step is skipped",
+			failing:  "notify",
+			err:      &stepSkippedError{reason: "No notification transport configured"},
+			status:   workflowCompleted,
+			current:  5,
+			steps:    []string{stepSucceeded, stepSucceeded, stepSucceeded, stepSucceeded, stepSkipped},
+			executed: []string{"check-gandi-token", "renew", "refresh-secret", "describe-secret", "notify"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			app := NewApp()
+
+			var executed []string
+			app.certificateWorkflows.runStep = func(ctx context.Context, wf CertificateWorkflow, step string) (*CertificateOperation, error) {
+				executed = append(executed, step)
+				if step == tt.failing {
+					return tt.result, tt.err
+				}
+				return &CertificateOperation{Success: true, Message: step + " done"}, nil
+			}
+
+			wf, err := app.StartCertificateRenewal("wildcard-example.com", "OPS-1", "2024-06-01")
+			require.NoError(t, err)
+			wf = waitCertificateWorkflow(t, app, wf.ID)
+
+			assert.Equal(t, tt.status, wf.Status)
+			assert.Equal(t, tt.current, wf.CurrentStep)
+			assert.Equal(t, tt.steps, stepStatuses(wf))
+			assert.Equal(t, tt.executed, executed)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, wf.Steps[wf.CurrentStep].Message)
+			}
+
+			next, err := app.StartCertificateRenewal("wildcard-example.com", "OPS-2", "")
+			if tt.status == workflowCompleted {
+				require.NoError(t, err)
+				waitCertificateWorkflow(t, app, next.ID)
+			} else {
+				assert.Error(t, err, "an unfinished workflow blocks a new renewal")
+			}
+		})
+	}
+}
+
+// TestNotifyStepWithoutTransport verifies the notify step is skipped when no notification transport is configured
+func TestNotifyStepWithoutTransport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	_, err := app.certificateWorkflows.executeStep(context.Background(), CertificateWorkflow{Certificate: "wildcard-example.com", OperationDate: "2024-06-01"}, "notify")
+	var skipped *stepSkippedError
+	require.True(t, errors.As(err, &skipped))
+	assert.Contains(t, skipped.Error(), "No notification transport configured")
+}
+
+// TestRetryCertificateWorkflowStep verifies a failed step is run again and the workflow continues
+func TestRetryCertificateWorkflowStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	fail := true
+	app.certificateWorkflows.runStep = func(ctx context.Context, wf CertificateWorkflow, step string) (*CertificateOperation, error) {
+		if step == "renew" && fail {
+			return nil, errors.New("Gandi API unavailable")
+		}
+		return &CertificateOperation{Success: true}, nil
+	}
+
+	wf, err := app.StartCertificateRenewal("wildcard-example.com", "OPS-1", "")
+	require.NoError(t, err)
+	wf = waitCertificateWorkflow(t, app, wf.ID)
+	require.Equal(t, workflowFailed, wf.Status)
+
+	_, err = app.ResumeCertificateWorkflow(wf.ID)
+	assert.Error(t, err, "a failed workflow is retried, not resumed")
+
+	fail = false
+	_, err = app.RetryCertificateWorkflowStep(wf.ID)
+	require.NoError(t, err)
+	wf = waitCertificateWorkflow(t, app, wf.ID)
+	assert.Equal(t, workflowCompleted, wf.Status)
+	assert.Equal(t, 2, wf.Steps[1].Attempts)
+	assert.Equal(t, 1, wf.Steps[2].Attempts)
+
+	_, err = app.RetryCertificateWorkflowStep(wf.ID)
+	assert.Error(t, err, "a completed workflow cannot be retried")
+}
+
+// TestPauseResumeCertificateWorkflow verifies a pause takes effect after the running step and resume continues from there
+func TestPauseResumeCertificateWorkflow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	renewing := make(chan struct{})
+	release := make(chan struct{})
+	app.certificateWorkflows.runStep = func(ctx context.Context, wf CertificateWorkflow, step string) (*CertificateOperation, error) {
+		if step == "renew" {
+			close(renewing)
+			<-release
+		}
+		return &CertificateOperation{Success: true}, nil
+	}
+
+	wf, err := app.StartCertificateRenewal("wildcard-example.com", "OPS-1", "")
+	require.NoError(t, err)
+	<-renewing
+
+	wf, err = app.PauseCertificateWorkflow(wf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, workflowRunning, wf.Status, "the running step finishes first")
+	assert.True(t, wf.PauseRequested)
+	assert.Error(t, app.DeleteCertificateWorkflow(wf.ID), "a running workflow cannot be deleted")
+
+	close(release)
+	wf = waitCertificateWorkflow(t, app, wf.ID)
+	assert.Equal(t, workflowPaused, wf.Status)
+	assert.False(t, wf.PauseRequested)
+	assert.Equal(t, 2, wf.CurrentStep)
+	assert.Equal(t, []string{stepSucceeded, stepSucceeded, stepPending, stepPending, stepPending}, stepStatuses(wf))
+
+	_, err = app.PauseCertificateWorkflow(wf.ID)
+	assert.Error(t, err, "a paused workflow cannot be paused again")
+
+	_, err = app.ResumeCertificateWorkflow(wf.ID)
+	require.NoError(t, err)
+	wf = waitCertificateWorkflow(t, app, wf.ID)
+	assert.Equal(t, workflowCompleted, wf.Status)
+	assert.Equal(t, 1, wf.Steps[1].Attempts, "resume does not run finished steps again")
+
+	require.NoError(t, app.DeleteCertificateWorkflow(wf.ID))
+	_, err = app.GetCertificateWorkflow(wf.ID)
+	assert.Error(t, err)
+}
+
+// TestRecoverCertificateWorkflows verifies workflows running at the last stop are paused on their interrupted step
+func TestRecoverCertificateWorkflows(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	workflows := []CertificateWorkflow{
+		{ID: "interrupted", Status: workflowRunning, CurrentStep: 1, Steps: []CertificateWorkflowStep{
+			{Name: "check-gandi-token", Status: stepSucceeded},
+			{Name: "renew", Status: stepRunning},
+		}},
+		{ID: "failed", Status: workflowFailed, CurrentStep: 0, Steps: []CertificateWorkflowStep{
+			{Name: "check-gandi-token", Status: stepFailed},
+		}},
+	}
+	require.NoError(t, saveStateFile(certificateWorkflowsFile, workflows))
+	require.NoError(t, app.certificateWorkflows.recover())
+
+	wf, err := app.GetCertificateWorkflow("interrupted")
+	require.NoError(t, err)
+	assert.Equal(t, workflowPaused, wf.Status)
+	assert.Equal(t, stepPending, wf.Steps[1].Status)
+	assert.Equal(t, "Interrupted by application restart", wf.Steps[1].Message)
+
+	wf, err = app.GetCertificateWorkflow("failed")
+	require.NoError(t, err)
+	assert.Equal(t, workflowFailed, wf.Status)
+}
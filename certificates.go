@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
-	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -42,6 +45,18 @@ type CertificateStatus struct {
 	SerialNumber string    `json:"serialNumber"`
 }
 
+// SkippedCertificate is a certificate left out of a status report and why
+type SkippedCertificate struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// CertificateStatusReport holds the statuses of the certificates that could be read and the skipped ones
+type CertificateStatusReport struct {
+	Statuses []CertificateStatus  `json:"statuses"`
+	Skipped  []SkippedCertificate `json:"skipped"`
+}
+
 // CertificateOperation represents an operation result
 type CertificateOperation struct {
 	Success bool   `json:"success"`
@@ -51,26 +66,16 @@ type CertificateOperation struct {
 
 // CheckGandiToken verifies the GANDI_TOKEN is configured correctly
 func (a *App) CheckGandiToken() (*CertificateOperation, error) {
+	return a.checkGandiToken(context.Background())
+}
+
+// checkGandiToken runs CheckGandiToken with the environment of ctx
+func (a *App) checkGandiToken(ctx context.Context) (*CertificateOperation, error) {
 	// Execute yak certificate gandi-check
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), "certificate", "gandi-check")
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return &CertificateOperation{
-			Success: false,
-			Message: "Failed to check Gandi token",
-			Output:  string(output),
-		}, nil
-	}
-
-	return &CertificateOperation{
-		Success: true,
-		Message: "Gandi token is valid",
-		Output:  string(output),
-	}, nil
+	return a.runCertificateCommand(ctx, []string{"certificate", "gandi-check"}, "Gandi token is valid", "Failed to check Gandi token")
 }
 
 // GetCertificateConfig retrieves the certificate configuration from terraform-infra
@@ -87,6 +92,11 @@ func (a *App) GetCertificateConfig() ([]Certificate, error) {
 
 // RenewCertificate initiates the certificate renewal process
 func (a *App) RenewCertificate(certificateName, jiraTicket string) (*CertificateOperation, error) {
+	return a.renewCertificate(context.Background(), certificateName, jiraTicket)
+}
+
+// renewCertificate runs RenewCertificate with the environment of ctx
+func (a *App) renewCertificate(ctx context.Context, certificateName, jiraTicket string) (*CertificateOperation, error) {
 	if certificateName == "" {
 		return nil, fmt.Errorf("certificate name is required")
 	}
@@ -98,29 +108,19 @@ func (a *App) RenewCertificate(certificateName, jiraTicket string) (*Certificate
 	args := []string{"certificate", "renew", "--certificate", certificateName, "-j", jiraTicket}
 	
 	// Execute yak certificate renew with timeout (this can take a while)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return &CertificateOperation{
-			Success: false,
-			Message: fmt.Sprintf("Failed to renew certificate %s", certificateName),
-			Output:  string(output),
-		}, nil
-	}
-
-	return &CertificateOperation{
-		Success: true,
-		Message: fmt.Sprintf("Certificate %s renewal initiated successfully", certificateName),
-		Output:  string(output),
-	}, nil
+	return a.runCertificateCommand(ctx, args, fmt.Sprintf("Certificate %s renewal initiated successfully", certificateName), fmt.Sprintf("Failed to renew certificate %s", certificateName))
 }
 
 // RefreshCertificateSecret refreshes the secret with the new certificate
 func (a *App) RefreshCertificateSecret(certificateName, jiraTicket string) (*CertificateOperation, error) {
+	return a.refreshCertificateSecret(context.Background(), certificateName, jiraTicket)
+}
+
+// refreshCertificateSecret runs RefreshCertificateSecret with the environment of ctx
+func (a *App) refreshCertificateSecret(ctx context.Context, certificateName, jiraTicket string) (*CertificateOperation, error) {
 	if certificateName == "" {
 		return nil, fmt.Errorf("certificate name is required")
 	}
@@ -132,29 +132,19 @@ func (a *App) RefreshCertificateSecret(certificateName, jiraTicket string) (*Cer
 	args := []string{"certificate", "refresh-secret", "--certificate", certificateName, "-j", jiraTicket}
 	
 	// Execute yak certificate refresh-secret with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return &CertificateOperation{
-			Success: false,
-			Message: fmt.Sprintf("Failed to refresh secret for certificate %s", certificateName),
-			Output:  string(output),
-		}, nil
-	}
-
-	return &CertificateOperation{
-		Success: true,
-		Message: fmt.Sprintf("Secret for certificate %s refreshed successfully", certificateName),
-		Output:  string(output),
-	}, nil
+	return a.runCertificateCommand(ctx, args, fmt.Sprintf("Secret for certificate %s refreshed successfully", certificateName), fmt.Sprintf("Failed to refresh secret for certificate %s", certificateName))
 }
 
 // DescribeCertificateSecret describes the certificate secret details
 func (a *App) DescribeCertificateSecret(certificateName string, version int, diffVersion int) (*CertificateOperation, error) {
+	return a.describeCertificateSecret(context.Background(), certificateName, version, diffVersion)
+}
+
+// describeCertificateSecret runs DescribeCertificateSecret with the environment of ctx
+func (a *App) describeCertificateSecret(ctx context.Context, certificateName string, version int, diffVersion int) (*CertificateOperation, error) {
 	if certificateName == "" {
 		return nil, fmt.Errorf("certificate name is required")
 	}
@@ -171,69 +161,125 @@ func (a *App) DescribeCertificateSecret(certificateName string, version int, dif
 	}
 	
 	// Execute yak certificate describe-secret
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.CombinedOutput()
+	return a.runCertificateCommand(ctx, args, fmt.Sprintf("Certificate %s secret details retrieved", certificateName), fmt.Sprintf("Failed to describe secret for certificate %s", certificateName))
+}
+
+// runCertificateCommand runs a yak certificate command and reports a failure in the returned operation,
+// only expired AWS SSO credentials are returned as an error so the GUI can offer to log in again
+func (a *App) runCertificateCommand(ctx context.Context, args []string, success, failure string) (*CertificateOperation, error) {
+	output, err := a.runYakCombined(ctx, args...)
+	var reauthErr *AWSReauthRequiredError
+	if errors.As(err, &reauthErr) {
+		return nil, err
+	}
 	if err != nil {
 		return &CertificateOperation{
 			Success: false,
-			Message: fmt.Sprintf("Failed to describe secret for certificate %s", certificateName),
+			Message: failure,
 			Output:  string(output),
 		}, nil
 	}
 
 	return &CertificateOperation{
 		Success: true,
-		Message: fmt.Sprintf("Certificate %s secret details retrieved", certificateName),
+		Message: success,
 		Output:  string(output),
 	}, nil
 }
 
-// ListCertificates lists available certificates (this would typically parse the config)
+// ListCertificates lists the certificates declared in the site config
 func (a *App) ListCertificates() ([]string, error) {
-	// For now, return some example certificate names
-	// In a real implementation, this would parse the config.yml file
-	examples := []string{
-		"keyless-staging-doctolib.de",
-		"keyless-prod-doctolib.fr", 
-		"wildcard-doctolib.com",
-		"api-doctolib.net",
+	site, err := a.siteConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(site.Certificates.Names) == 0 {
+		return nil, &SiteNotConfiguredError{Setting: "certificates.names"}
 	}
 	
-	return examples, nil
+	return site.Certificates.Names, nil
 }
 
-// SendCertificateNotification sends email notification to technical services
-func (a *App) SendCertificateNotification(certificateName, operationDate, operation string) (*CertificateOperation, error) {
-	// This is a placeholder - in a real implementation you might:
-	// 1. Use a mail service/API
-	// 2. Generate an email template
-	// 3. Send to technicalservices-all@doctolib.com
-	
-	message := fmt.Sprintf(`
-Email would be sent to: technicalservices-all@doctolib.com
+// GetCertificateStatuses computes the expiration status of each known certificate from its stored secret,
+// certificates whose secret cannot be read are listed as skipped with the reason
+func (a *App) GetCertificateStatuses() (*CertificateStatusReport, error) {
+	names, err := a.ListCertificates()
+	if err != nil {
+		return nil, err
+	}
 
-Subject: SSL Certificate %s - %s Scheduled for %s
+	report := &CertificateStatusReport{Statuses: []CertificateStatus{}, Skipped: []SkippedCertificate{}}
+	for _, name := range names {
+		result, err := a.DescribeCertificateSecret(name, 0, 0)
+		if err != nil {
+			// Expired AWS SSO credentials fail every certificate, the user must log in again
+			return nil, err
+		}
+		if !result.Success {
+			reason := strings.TrimSpace(result.Output)
+			if reason == "" {
+				reason = result.Message
+			}
+			report.Skipped = append(report.Skipped, SkippedCertificate{Name: name, Reason: reason})
+			continue
+		}
 
-Dear Technical Services Team,
+		status, err := parseCertificateStatus(name, result.Output, time.Now())
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedCertificate{Name: name, Reason: fmt.Sprintf("failed to compute expiration: %v", err)})
+			continue
+		}
+		report.Statuses = append(report.Statuses, *status)
+	}
 
-This is to inform you that we will be performing an SSL certificate %s operation:
+	return report, nil
+}
 
-Certificate: %s
-Operation: %s
-Scheduled Date: %s
+// parseCertificateStatus extracts the leaf certificate from PEM data and computes its expiration status
+func parseCertificateStatus(name, output string, now time.Time) (*CertificateStatus, error) {
+	var leaf *x509.Certificate
+	rest := []byte(output)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		// Prefer the leaf certificate over intermediates bundled in the chain
+		if leaf == nil || (leaf.IsCA && !cert.IsCA) {
+			leaf = cert
+		}
+	}
 
-Please be aware that some customers may need to manually download our certificate and upload it to their trust store.
+	if leaf == nil {
+		return nil, fmt.Errorf("no PEM certificate found in secret")
+	}
 
-Best regards
-`, operation, certificateName, operationDate, operation, certificateName, operation, operationDate)
+	days := int(leaf.NotAfter.Sub(now).Hours() / 24)
+	status := "valid"
+	if leaf.NotAfter.Before(now) {
+		status = "expired"
+	} else if days < 30 {
+		status = "expiring"
+	}
 
-	return &CertificateOperation{
-		Success: true,
-		Message: "Email notification template generated successfully",
-		Output:  message,
+	return &CertificateStatus{
+		Name:         name,
+		Issuer:       leaf.Issuer.String(),
+		Subject:      leaf.Subject.String(),
+		Expiration:   leaf.NotAfter,
+		DaysUntilExp: days,
+		Status:       status,
+		SerialNumber: leaf.SerialNumber.String(),
 	}, nil
-}
\ No newline at end of file
+}
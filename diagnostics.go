@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a diagnostic check, a warning does not block the GUI but some features will not work
+const (
+	diagnosticPass = "pass"
+	diagnosticWarn = "warn"
+	diagnosticFail = "fail"
+)
+
+// diagnosticCheckTimeout bounds every check of RunDiagnostics, a hung tool or unreachable endpoint
+// fails its own check without holding up the report. It is a variable so tests can shorten it.
+var diagnosticCheckTimeout = 15 * time.Second
+
+// diagnosticVersionPattern extracts a version number from the output of a --version command
+var diagnosticVersionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?([-+][0-9A-Za-z.-]+)?`)
+
+// DiagnosticCheck is the outcome of checking one dependency
+type DiagnosticCheck struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"` // pass, warn, fail
+	Message    string   `json:"message"`
+	Hint       string   `json:"hint,omitempty"`    // how to fix a warning or failure
+	Version    string   `json:"version,omitempty"` // detected version of the tool or service
+	Details    []string `json:"details"`           // locations searched and values found
+	DurationMs int64    `json:"durationMs"`
+}
+
+// DiagnosticsReport is the result of RunDiagnostics, Status is the worst status of the checks
+type DiagnosticsReport struct {
+	StartedAt  time.Time         `json:"startedAt"`
+	DurationMs int64             `json:"durationMs"`
+	Status     string            `json:"status"`
+	Passed     int               `json:"passed"`
+	Warnings   int               `json:"warnings"`
+	Failures   int               `json:"failures"`
+	Checks     []DiagnosticCheck `json:"checks"`
+}
+
+// diagnosticCheck is a named check run by RunDiagnostics
+type diagnosticCheck struct {
+	name string
+	run  func(ctx context.Context) DiagnosticCheck
+}
+
+// diagnosticChecks returns the checks of RunDiagnostics in report order
+func (a *App) diagnosticChecks() []diagnosticCheck {
+	return []diagnosticCheck{
+		{"yak", a.diagnoseYak},
+		{"kubectl", func(ctx context.Context) DiagnosticCheck {
+			return a.diagnoseTool(ctx, "kubectl", []string{"version", "--client"}, "Install kubectl or add its directory to PATH on the Environment page")
+		}},
+		{"aws", func(ctx context.Context) DiagnosticCheck {
+			return a.diagnoseTool(ctx, "aws", []string{"--version"}, "Install the AWS CLI v2 or add its directory to PATH on the Environment page")
+		}},
+		{"AWS credentials", a.diagnoseAWSCredentials},
+		{"kubeconfig", a.diagnoseKubeconfig},
+		{"secret.yml", a.diagnoseSecretConfig},
+		{"Vault", a.diagnoseVault},
+		{"GANDI_TOKEN", a.diagnoseGandiToken},
+		{"TFE token", a.diagnoseTFEToken},
+	}
+}
+
+// RunDiagnostics checks the tools, credentials and configuration yak-gui depends on. The checks run
+// concurrently and each one is bounded by diagnosticCheckTimeout.
+func (a *App) RunDiagnostics() *DiagnosticsReport {
+	checks := a.diagnosticChecks()
+	report := &DiagnosticsReport{StartedAt: time.Now(), Checks: make([]DiagnosticCheck, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check diagnosticCheck) {
+			defer wg.Done()
+			report.Checks[i] = runDiagnosticCheck(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report.Status = diagnosticPass
+	for _, check := range report.Checks {
+		switch check.Status {
+		case diagnosticPass:
+			report.Passed++
+		case diagnosticWarn:
+			report.Warnings++
+			if report.Status == diagnosticPass {
+				report.Status = diagnosticWarn
+			}
+		default:
+			report.Failures++
+			report.Status = diagnosticFail
+		}
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report
+}
+
+// runDiagnosticCheck runs a check under its timeout. A check that does not return in time is reported
+// as failed and left to finish in the background.
+func runDiagnosticCheck(check diagnosticCheck) DiagnosticCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan DiagnosticCheck, 1)
+	go func() {
+		done <- check.run(ctx)
+	}()
+
+	var result DiagnosticCheck
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = DiagnosticCheck{
+			Status:  diagnosticFail,
+			Message: fmt.Sprintf("check timed out after %s", diagnosticCheckTimeout),
+			Hint:    "Check the network connection and that the tool does not wait for input",
+		}
+	}
+	result.Name = check.name
+	result.DurationMs = time.Since(start).Milliseconds()
+	if result.Details == nil {
+		result.Details = []string{}
+	}
+	return result
+}
+
+// detectVersion returns the first version number of a --version output, its first line when there is none
+func detectVersion(output string) string {
+	line := firstLine(output)
+	if version := diagnosticVersionPattern.FindString(line); version != "" {
+		return version
+	}
+	return line
+}
+
+// toolVersion runs an executable with the arguments printing its version
+func (a *App) toolVersion(ctx context.Context, path string, args ...string) (string, error) {
+	output, err := a.currentEnv().command(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if line := firstLine(string(output)); line != "" {
+			return "", fmt.Errorf("%v: %s", err, line)
+		}
+		return "", err
+	}
+	return detectVersion(string(output)), nil
+}
+
+// resolveTool returns the path of an executable in the environment PATH, ok is false when it is not there
+func resolveTool(env *envContext, name string) (string, bool) {
+	path := env.lookPath(name)
+	if !strings.Contains(path, string(filepath.Separator)) {
+		return "", false
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", false
+	}
+	return path, true
+}
+
+// diagnoseYak checks yak is installed where findYakExecutable looks and reports the PATH it runs with
+func (a *App) diagnoseYak(ctx context.Context) DiagnosticCheck {
+	yak, details := searchYakExecutable()
+	check := DiagnosticCheck{Details: details}
+
+	env := a.currentEnv()
+	check.Details = append(check.Details, "environment PATH: "+env.get("PATH"))
+	if shellPath, shellDetails, err := shellPATHSearch(); err == nil {
+		check.Details = append(check.Details, shellDetails...)
+		check.Details = append(check.Details, "shell PATH: "+shellPath)
+	}
+
+	path, ok := resolveTool(env, yak)
+	if !ok {
+		check.Status = diagnosticFail
+		check.Message = "yak executable not found"
+		check.Hint = "Install yak, or load the PATH of your shell on the Environment page so the GUI finds it"
+		return check
+	}
+	check.Details = append(check.Details, "using "+path)
+
+	version, err := a.toolVersion(ctx, path, "--version")
+	if err != nil {
+		check.Status = diagnosticWarn
+		check.Message = fmt.Sprintf("yak found at %s but its version could not be read: %v", path, err)
+		check.Hint = "Run yak --version in a terminal, the installation may be incomplete"
+		return check
+	}
+	check.Status = diagnosticPass
+	check.Version = version
+	check.Message = fmt.Sprintf("yak %s at %s", version, path)
+	return check
+}
+
+// diagnoseTool checks an executable resolves in the environment PATH and reads its version
+func (a *App) diagnoseTool(ctx context.Context, name string, versionArgs []string, hint string) DiagnosticCheck {
+	env := a.currentEnv()
+	check := DiagnosticCheck{Details: []string{"environment PATH: " + env.get("PATH")}}
+
+	path, ok := resolveTool(env, name)
+	if !ok {
+		check.Status = diagnosticFail
+		check.Message = name + " not found in PATH"
+		check.Hint = hint
+		return check
+	}
+	check.Details = append(check.Details, "using "+path)
+
+	version, err := a.toolVersion(ctx, path, versionArgs...)
+	if err != nil {
+		check.Status = diagnosticWarn
+		check.Message = fmt.Sprintf("%s found at %s but its version could not be read: %v", name, path, err)
+		check.Hint = fmt.Sprintf("Run %s %s in a terminal, the installation may be incomplete", name, strings.Join(versionArgs, " "))
+		return check
+	}
+	check.Status = diagnosticPass
+	check.Version = version
+	check.Message = fmt.Sprintf("%s %s at %s", name, version, path)
+	return check
+}
+
+// diagnoseAWSCredentials checks the AWS profile of the environment has a usable session
+func (a *App) diagnoseAWSCredentials(ctx context.Context) DiagnosticCheck {
+	check := DiagnosticCheck{}
+	profile := a.currentEnv().get("AWS_PROFILE")
+	if profile == "" {
+		check.Status = diagnosticWarn
+		check.Message = "no AWS profile selected"
+		check.Hint = "Select an AWS profile on the Environment page"
+		return check
+	}
+	check.Details = append(check.Details, "AWS_PROFILE: "+profile)
+
+	status, err := a.GetAWSSessionStatus(profile)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Check the profile is defined in ~/.aws/config or select another one"
+		return check
+	}
+	if status.StartURL != "" {
+		check.Details = append(check.Details, "SSO start URL: "+status.StartURL)
+	}
+	if status.CacheFile != "" {
+		check.Details = append(check.Details, "SSO token cache: "+status.CacheFile)
+	}
+
+	check.Message = status.Message
+	switch status.Status {
+	case awsSessionActive, awsSessionNotSSO:
+		check.Status = diagnosticPass
+	default:
+		check.Status = diagnosticFail
+		check.Hint = fmt.Sprintf("Log in with the AWS login button or aws sso login --profile %s", profile)
+	}
+	return check
+}
+
+// diagnoseKubeconfig checks the kubeconfig parses and the cluster of its current context answers
+func (a *App) diagnoseKubeconfig(ctx context.Context) DiagnosticCheck {
+	check := DiagnosticCheck{}
+	set, err := loadKubeconfig(a.currentEnv())
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Select an AWS profile so KUBECONFIG points at its terraform-infra k8senv file, or set KUBECONFIG"
+		var kerr *KubeconfigError
+		if errors.As(err, &kerr) && kerr.Kind == kubeconfigErrParse {
+			check.Hint = "Fix or regenerate the kubeconfig file, it is not valid YAML"
+		}
+		return check
+	}
+	for _, file := range set.info.Files {
+		check.Details = append(check.Details, "file: "+file)
+	}
+	if set.info.CurrentContext == "" {
+		check.Status = diagnosticWarn
+		check.Message = "kubeconfig has no current context"
+		check.Hint = "Select a Kubernetes context on the Environment page"
+		return check
+	}
+	check.Details = append(check.Details, "current context: "+set.info.CurrentContext)
+
+	result, err := a.CheckKubeConnectivity(set.info.CurrentContext)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Select another context or regenerate the kubeconfig"
+		return check
+	}
+	check.Details = append(check.Details, "server: "+result.Server)
+	check.Version = result.Version
+	switch {
+	case result.Error == nil:
+		check.Status = diagnosticPass
+		check.Message = fmt.Sprintf("context %s reachable in %d ms", result.Context, result.LatencyMs)
+	case result.Reachable:
+		check.Status = diagnosticWarn
+		check.Message = result.Error.Error()
+		check.Hint = "The API server answered but rejected the request, check the AWS session of the profile"
+	case result.Error.Kind == kubeconfigErrInvalidTLS:
+		check.Status = diagnosticFail
+		check.Message = result.Error.Error()
+		check.Hint = "Regenerate the kubeconfig, its certificate authority data is not valid"
+	default:
+		check.Status = diagnosticFail
+		check.Message = result.Error.Error()
+		check.Hint = "Check the VPN is connected and the cluster endpoint is reachable from this machine"
+	}
+	return check
+}
+
+// diagnoseSecretConfig checks secret.yml is found where LoadSecretConfig looks and parses
+func (a *App) diagnoseSecretConfig(ctx context.Context) DiagnosticCheck {
+	path, details := a.findSecretConfig()
+	check := DiagnosticCheck{Details: details}
+	if path == "" {
+		check.Status = diagnosticFail
+		check.Message = "secret.yml not found in any expected location"
+		check.Hint = "Set TFINFRA_REPOSITORY_PATH to your terraform-infra checkout, it ships setup/yak_config/secret.yml"
+		return check
+	}
+
+	config, err := readSecretConfig(path)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Update the terraform-infra checkout, secret.yml is not valid YAML"
+		return check
+	}
+	if len(config.Platforms) == 0 {
+		check.Status = diagnosticWarn
+		check.Message = fmt.Sprintf("%s defines no platforms", path)
+		check.Hint = "Secrets cannot be browsed without platforms, check the file is the one of terraform-infra"
+		return check
+	}
+	check.Status = diagnosticPass
+	check.Message = fmt.Sprintf("%s defines %d platforms and %d clusters", path, len(config.Platforms), len(config.Clusters))
+	return check
+}
+
+// diagnoseVault checks the Vault server of VAULT_ADDR answers its health endpoint
+func (a *App) diagnoseVault(ctx context.Context) DiagnosticCheck {
+	env := a.currentEnv()
+	check := DiagnosticCheck{}
+	addr := strings.TrimSuffix(env.get("VAULT_ADDR"), "/")
+	if addr == "" {
+		check.Status = diagnosticWarn
+		check.Message = "VAULT_ADDR is not set"
+		check.Hint = "Set VAULT_ADDR on the Environment page or import it from your shell"
+		return check
+	}
+	check.Details = append(check.Details, "VAULT_ADDR: "+addr)
+	switch {
+	case env.get("VAULT_TOKEN") != "":
+		check.Details = append(check.Details, "token: VAULT_TOKEN")
+	case env.get("HOME") != "" && vaultTokenFileExists(env.get("HOME")):
+		check.Details = append(check.Details, "token: ~/.vault-token")
+	default:
+		check.Details = append(check.Details, "token: none in VAULT_TOKEN or ~/.vault-token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/sys/health?standbyok=true&perfstandbyok=true", nil)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = fmt.Sprintf("invalid VAULT_ADDR: %v", err)
+		check.Hint = "Set VAULT_ADDR to the URL of the Vault server, e.g. https://vault.example.com"
+		return check
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = fmt.Sprintf("Vault is not reachable: %v", err)
+		check.Hint = "Check the VPN is connected and VAULT_ADDR is correct"
+		return check
+	}
+	defer resp.Body.Close()
+
+	var health struct {
+		Version string `json:"version"`
+		Sealed  bool   `json:"sealed"`
+	}
+	// Proxies answer with HTML, the status code alone then decides
+	json.NewDecoder(resp.Body).Decode(&health)
+	check.Version = health.Version
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		check.Status = diagnosticPass
+		check.Message = "Vault is reachable and unsealed"
+	case health.Sealed || resp.StatusCode == http.StatusServiceUnavailable:
+		check.Status = diagnosticFail
+		check.Message = "Vault is sealed"
+		check.Hint = "Secrets cannot be read until Vault is unsealed, contact the Vault operators"
+	default:
+		check.Status = diagnosticWarn
+		check.Message = fmt.Sprintf("Vault health check returned HTTP %d", resp.StatusCode)
+		check.Hint = "Check VAULT_ADDR points at the Vault server and not at a proxy"
+	}
+	return check
+}
+
+// vaultTokenFileExists tells whether the vault CLI left a token in the home directory
+func vaultTokenFileExists(home string) bool {
+	_, err := os.Stat(filepath.Join(home, ".vault-token"))
+	return err == nil
+}
+
+// diagnoseGandiToken checks GANDI_TOKEN is set and accepted by yak certificate gandi-check
+func (a *App) diagnoseGandiToken(ctx context.Context) DiagnosticCheck {
+	env := a.currentEnv()
+	check := DiagnosticCheck{}
+	token := env.get("GANDI_TOKEN")
+	if token == "" {
+		check.Status = diagnosticWarn
+		check.Message = "GANDI_TOKEN is not set, certificate operations will fail"
+		check.Hint = "Set GANDI_TOKEN on the Environment page or as a secret variable of the environment profile"
+		return check
+	}
+	check.Details = append(check.Details, "GANDI_TOKEN: "+maskSensitiveValue(token))
+
+	if _, ok := resolveTool(env, findYakExecutable()); !ok {
+		check.Status = diagnosticWarn
+		check.Message = "GANDI_TOKEN is set but cannot be verified without yak"
+		check.Hint = "Install yak to verify the token"
+		return check
+	}
+
+	operation, err := a.checkGandiToken(ctx)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Log in to AWS again, yak needs a valid session to check the token"
+		var reauth *AWSReauthRequiredError
+		if !errors.As(err, &reauth) {
+			check.Hint = "Run yak certificate gandi-check in a terminal for details"
+		}
+		return check
+	}
+	if !operation.Success {
+		check.Status = diagnosticFail
+		check.Message = operation.Message
+		if line := firstLine(operation.Output); line != "" {
+			check.Message += ": " + line
+		}
+		check.Hint = "Create a new personal access token in the Gandi account settings and update GANDI_TOKEN"
+		return check
+	}
+	check.Status = diagnosticPass
+	check.Message = "GANDI_TOKEN is valid"
+	return check
+}
+
+// diagnoseTFEToken checks a TFE token is configured and accepted by the TFE API
+func (a *App) diagnoseTFEToken(ctx context.Context) DiagnosticCheck {
+	check := DiagnosticCheck{}
+	config, err := a.GetTFEConfig()
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		check.Hint = "Check the TFE settings and the site configuration of terraform-infra"
+		return check
+	}
+	if config.Endpoint == "" {
+		check.Status = diagnosticFail
+		check.Message = "no TFE endpoint configured"
+		check.Hint = "Set the TFE endpoint in the TFE settings or with TFE_ENDPOINT"
+		return check
+	}
+	check.Details = append(check.Details, "endpoint: "+config.Endpoint)
+	if config.Token == "" {
+		check.Status = diagnosticFail
+		check.Message = "no TFE token found"
+		check.Hint = fmt.Sprintf("Save a token in the TFE settings, set TFE_TOKEN or %s, or run terraform login %s",
+			terraformTokenEnvVar(tfeHostname(config.Endpoint)), tfeHostname(config.Endpoint))
+		return check
+	}
+	check.Details = append(check.Details, "token source: "+config.TokenSource)
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		return check
+	}
+	var account struct {
+		Data struct {
+			Attributes struct {
+				Username string `json:"username"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/account/details", nil, nil, &account); err != nil {
+		check.Status = diagnosticFail
+		check.Message = err.Error()
+		var apiErr *tfeAPIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			check.Hint = fmt.Sprintf("The token from %s is invalid or expired, create a new one in the TFE user settings", config.TokenSource)
+		} else {
+			check.Hint = "Check the VPN is connected and the TFE endpoint is correct"
+		}
+		return check
+	}
+	check.Status = diagnosticPass
+	check.Message = fmt.Sprintf("authenticated on %s as %s with the token from %s", config.Endpoint, account.Data.Attributes.Username, config.TokenSource)
+	return check
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDiagnosticsTool writes a fake executable running script
+func writeDiagnosticsTool(t *testing.T, dir, name, script string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+}
+
+// TestDetectVersion verifies versions are read from the usual --version formats
+func TestDetectVersion(t *testing.T) {
+	assert.Equal(t, "2.15.0", detectVersion("aws-cli/2.15.0 Python/3.11.6 Darwin/23.2.0 source/arm64\n"))
+	assert.Equal(t, "1.29.1", detectVersion("Client Version: v1.29.1\nKustomize Version: v5.0.4\n"))
+	assert.Equal(t, "1.4.0-rc.1", detectVersion("yak version 1.4.0-rc.1\n"))
+	assert.Equal(t, "dev", detectVersion("\ndev\n"))
+}
+
+// TestRunDiagnostics verifies every dependency is reported with its status, version and search details
+func TestRunDiagnostics(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFE_ORGANIZATION", "")
+	t.Setenv("VAULT_TOKEN", "")
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zshrc"), []byte("export PATH=\"/custom/bin:$PATH\"\n"), 0644))
+
+	bin := t.TempDir()
+	writeDiagnosticsTool(t, bin, "yak", `case "$1" in
+--version) echo "yak version 1.4.2" ;;
+certificate) echo "Gandi token is invalid"; exit 1 ;;
+esac`)
+	writeDiagnosticsTool(t, bin, "aws", `echo "aws-cli/2.15.0 Python/3.11.6 Darwin/23.2.0"`)
+	t.Setenv("PATH", bin+string(filepath.ListSeparator)+os.Getenv("PATH"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false,"version":"1.15.2"}`))
+		case "/api/v2/account/details":
+			if r.Header.Get("Authorization") != "Bearer tfe-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"data":{"attributes":{"username":"operator"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tfinfra := t.TempDir()
+	secretDir := filepath.Join(tfinfra, "setup", "yak_config")
+	require.NoError(t, os.MkdirAll(secretDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(secretDir, "secret.yml"), []byte("platforms:\n  web:\n    vaultRole: web\nclusters:\n  staging:\n    endpoint: https://staging\n"), 0644))
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{
+		"PATH":                    bin,
+		"AWS_PROFILE":             "",
+		"KUBECONFIG":              filepath.Join(home, "missing-kubeconfig"),
+		"TFINFRA_REPOSITORY_PATH": tfinfra,
+		"VAULT_ADDR":              server.URL,
+		"GANDI_TOKEN":             "gandi-token-value",
+		"TFE_ENDPOINT":            server.URL,
+		"TFE_TOKEN":               "tfe-token",
+	}))
+
+	report := app.RunDiagnostics()
+	checks := map[string]DiagnosticCheck{}
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	require.Len(t, checks, 9)
+
+	yak := checks["yak"]
+	assert.Equal(t, diagnosticPass, yak.Status)
+	assert.Equal(t, "1.4.2", yak.Version)
+	assert.Contains(t, yak.Details, "/opt/homebrew/bin/yak: not found")
+	assert.Contains(t, yak.Details, "~/.zshrc: using export PATH=\"/custom/bin:$PATH\"")
+
+	assert.Equal(t, diagnosticPass, checks["aws"].Status)
+	assert.Equal(t, "2.15.0", checks["aws"].Version)
+	assert.Equal(t, diagnosticFail, checks["kubectl"].Status)
+	assert.NotEmpty(t, checks["kubectl"].Hint)
+
+	assert.Equal(t, diagnosticWarn, checks["AWS credentials"].Status)
+	assert.Equal(t, diagnosticFail, checks["kubeconfig"].Status)
+	assert.Equal(t, diagnosticPass, checks["secret.yml"].Status)
+	assert.Contains(t, checks["secret.yml"].Message, "1 platforms")
+
+	assert.Equal(t, diagnosticPass, checks["Vault"].Status)
+	assert.Equal(t, "1.15.2", checks["Vault"].Version)
+	assert.Contains(t, checks["Vault"].Details, "token: none in VAULT_TOKEN or ~/.vault-token")
+
+	gandi := checks["GANDI_TOKEN"]
+	assert.Equal(t, diagnosticFail, gandi.Status)
+	assert.Contains(t, gandi.Message, "Gandi token is invalid")
+	assert.Contains(t, gandi.Details, "GANDI_TOKEN: gand...alue")
+
+	tfe := checks["TFE token"]
+	assert.Equal(t, diagnosticPass, tfe.Status)
+	assert.Contains(t, tfe.Message, "operator")
+	assert.Contains(t, tfe.Details, "token source: TFE_TOKEN")
+
+	assert.Equal(t, diagnosticFail, report.Status)
+	assert.Equal(t, 9, report.Passed+report.Warnings+report.Failures)
+	assert.Equal(t, 1, report.Warnings)
+}
+
+// TestRunDiagnosticCheckTimeout verifies a hung check is reported as failed once its timeout expires
+func TestRunDiagnosticCheckTimeout(t *testing.T) {
+	previous := diagnosticCheckTimeout
+	diagnosticCheckTimeout = 100 * time.Millisecond
+	defer func() { diagnosticCheckTimeout = previous }()
+
+	release := make(chan struct{})
+	defer close(release)
+	check := runDiagnosticCheck(diagnosticCheck{name: "hung", run: func(ctx context.Context) DiagnosticCheck {
+		<-release
+		return DiagnosticCheck{Status: diagnosticPass}
+	}})
+
+	assert.Equal(t, "hung", check.Name)
+	assert.Equal(t, diagnosticFail, check.Status)
+	assert.Contains(t, check.Message, "timed out")
+	assert.NotNil(t, check.Details)
+}
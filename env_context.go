@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// envContext is an immutable snapshot of the environment handed to yak, kubectl and the other tools we run.
+// Variables it does not override fall back to the process environment, which the app never modifies,
+// so a snapshot taken by a job stays valid while the operator switches profiles.
+type envContext struct {
+	profile     string            // environment profile the snapshot was built from, empty when none was loaded
+	kubeContext string            // kubeconfig context pinned for this environment, passed to kubectl as --context
+	vars        map[string]string // overrides on top of the process environment, never mutated after creation
+}
+
+// envContextKey is the context.Context key carrying the envContext of a job
+type envContextKey struct{}
+
+// newEnvContext returns an environment without overrides
+func newEnvContext() *envContext {
+	return &envContext{vars: map[string]string{}}
+}
+
+// get returns the value of a variable, overrides take precedence over the process environment
+func (e *envContext) get(key string) string {
+	if value, ok := e.vars[key]; ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// with returns a copy of the environment with the given variables set
+func (e *envContext) with(vars map[string]string) *envContext {
+	next := &envContext{profile: e.profile, kubeContext: e.kubeContext, vars: make(map[string]string, len(e.vars)+len(vars))}
+	for key, value := range e.vars {
+		next.vars[key] = value
+	}
+	for key, value := range vars {
+		next.vars[key] = value
+	}
+	return next
+}
+
+// withProfile returns a copy of the environment attributed to another environment profile
+func (e *envContext) withProfile(profile string) *envContext {
+	next := e.with(nil)
+	next.profile = profile
+	return next
+}
+
+// withKubeContext returns a copy of the environment pinned to a kubeconfig context, empty unpins it
+func (e *envContext) withKubeContext(name string) *envContext {
+	next := e.with(nil)
+	next.kubeContext = name
+	return next
+}
+
+// environ returns the environment in the KEY=value form expected by exec.Cmd.Env
+func (e *envContext) environ() []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, ok := e.vars[key]; !ok {
+			env = append(env, entry)
+		}
+	}
+
+	keys := make([]string, 0, len(e.vars))
+	for key := range e.vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+e.vars[key])
+	}
+	return env
+}
+
+// lookPath resolves a bare executable name against the PATH of the environment,
+// exec.LookPath only knows the process PATH
+func (e *envContext) lookPath(name string) string {
+	if strings.Contains(name, string(filepath.Separator)) {
+		return name
+	}
+	for _, dir := range filepath.SplitList(e.get("PATH")) {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path
+		}
+	}
+	return name
+}
+
+// command builds a command that runs with this environment. kubectl gets the pinned context as --context,
+// the current-context of the shared kubeconfig files is never changed by the GUI.
+func (e *envContext) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if e.kubeContext != "" && filepath.Base(name) == "kubectl" {
+		args = append([]string{"--context", e.kubeContext}, args...)
+	}
+	cmd := exec.CommandContext(ctx, e.lookPath(name), args...)
+	cmd.Env = e.environ()
+	return cmd
+}
+
+// withEnvContext attaches an environment to a context, commands run under the context use it
+func withEnvContext(ctx context.Context, env *envContext) context.Context {
+	return context.WithValue(ctx, envContextKey{}, env)
+}
+
+// currentEnv returns the environment of the active profile
+func (a *App) currentEnv() *envContext {
+	if env := a.env.Load(); env != nil {
+		return env
+	}
+	return newEnvContext()
+}
+
+// envFor returns the environment attached to ctx, or the active one when ctx carries none
+func (a *App) envFor(ctx context.Context) *envContext {
+	if env, ok := ctx.Value(envContextKey{}).(*envContext); ok && env != nil {
+		return env
+	}
+	return a.currentEnv()
+}
+
+// updateEnv atomically replaces the active environment with the result of fn.
+// Writers are serialized so concurrent setters cannot lose each other's changes,
+// readers always see either the previous or the next snapshot.
+func (a *App) updateEnv(fn func(env *envContext) *envContext) *envContext {
+	a.envMu.Lock()
+	defer a.envMu.Unlock()
+
+	next := fn(a.currentEnv())
+	a.env.Store(next)
+	return next
+}
+
+// yakCommand builds a yak command running with the environment of ctx
+func (a *App) yakCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return a.envFor(ctx).command(ctx, findYakExecutable(), args...)
+}
+
+// runYak runs yak with the environment of ctx and returns its standard output.
+// A failure reporting expired AWS SSO credentials is returned as an *AWSReauthRequiredError.
+func (a *App) runYak(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := a.yakCommand(ctx, args...).Output()
+	if reauthErr := a.awsReauthError(ctx, err, output); reauthErr != nil {
+		return output, reauthErr
+	}
+	return output, err
+}
+
+// runYakCombined is runYak returning standard output and standard error together
+func (a *App) runYakCombined(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := a.yakCommand(ctx, args...).CombinedOutput()
+	if reauthErr := a.awsReauthError(ctx, err, output); reauthErr != nil {
+		return output, reauthErr
+	}
+	return output, err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEnvironmentContextIsolation verifies setters never touch the process environment and jobs keep their snapshot
+func TestEnvironmentContextIsolation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFINFRA_REPOSITORY_PATH", "")
+	t.Setenv("AWS_PROFILE", "from-process")
+	t.Setenv("KUBECONFIG", "/process/kubeconfig")
+
+	app := NewApp()
+	assert.Equal(t, "from-process", app.GetCurrentAWSProfile())
+
+	require.NoError(t, app.SetAWSProfile("staging"))
+	require.NoError(t, app.SetKubeconfig("/staging/kubeconfig"))
+	assert.Equal(t, "staging", app.GetCurrentAWSProfile())
+	assert.Equal(t, "from-process", os.Getenv("AWS_PROFILE"))
+	assert.Equal(t, "/process/kubeconfig", os.Getenv("KUBECONFIG"))
+
+	// A job started now keeps the staging environment after the operator switches to another profile
+	ctx := withEnvContext(context.Background(), app.currentEnv())
+	require.NoError(t, app.SetAWSProfile("prod"))
+	require.NoError(t, app.SaveEnvironmentProfile("production"))
+	require.NoError(t, app.SetAWSProfile("dev"))
+	require.NoError(t, app.LoadEnvironmentProfile("production"))
+
+	assert.Equal(t, "production", app.GetActiveEnvironmentProfile())
+	assert.Equal(t, "prod", app.GetCurrentAWSProfile())
+	assert.Equal(t, "staging", app.envFor(ctx).get("AWS_PROFILE"))
+	assert.Empty(t, app.envFor(ctx).profile)
+	assert.Equal(t, "prod", app.envFor(context.Background()).get("AWS_PROFILE"))
+
+	cmd := app.yakCommand(ctx, "version")
+	assert.Contains(t, cmd.Env, "AWS_PROFILE=staging")
+	assert.NotContains(t, cmd.Env, "AWS_PROFILE=from-process")
+	assert.Contains(t, cmd.Env, "KUBECONFIG=/staging/kubeconfig")
+}
+
+// TestEnvironmentContextLookPath verifies executables are resolved against the PATH of the environment
+func TestEnvironmentContextLookPath(t *testing.T) {
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "sometool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n"), 0755))
+
+	env := newEnvContext().with(map[string]string{"PATH": dir})
+	assert.Equal(t, tool, env.lookPath("sometool"))
+	assert.Equal(t, "missingtool", env.lookPath("missingtool"))
+	assert.Equal(t, "/bin/sh", env.lookPath("/bin/sh"))
+}
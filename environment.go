@@ -2,172 +2,149 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 )
 
 // EnvironmentProfile represents a saved environment configuration
 type EnvironmentProfile struct {
-	Name                    string `json:"name"`
-	AWSProfile              string `json:"aws_profile"`
-	Kubeconfig              string `json:"kubeconfig"`
-	PATH                    string `json:"path"`
-	TfInfraRepositoryPath   string `json:"tf_infra_repository_path"`
-	GandiToken              string `json:"gandi_token"`
-	CreatedAt               string `json:"created_at"`
+	Name      string                `json:"name"`
+	Variables []EnvironmentVariable `json:"variables"`
+	CreatedAt string                `json:"created_at"`
+	UpdatedAt string                `json:"updated_at,omitempty"`
 }
 
 
-// GetCurrentAWSProfile returns the current AWS_PROFILE environment variable
+// GetCurrentAWSProfile returns the AWS_PROFILE of the active environment
 func (a *App) GetCurrentAWSProfile() string {
-	return os.Getenv("AWS_PROFILE")
+	return a.currentEnv().get("AWS_PROFILE")
 }
 
-// SetAWSProfile sets the AWS_PROFILE environment variable and auto-configures KUBECONFIG and kubectl context
+// SetAWSProfile sets AWS_PROFILE in the active environment, auto-configures KUBECONFIG and pins the context
+// named after the profile. AWS_PROFILE, KUBECONFIG and the context change in a single step so that no job sees
+// the new profile with the cluster of the previous one. When the profile has no k8senv kubeconfig, KUBECONFIG
+// is cleared in that same step and the failure is returned as a *KubeconfigError.
 func (a *App) SetAWSProfile(profile string) error {
 	if profile == "" {
 		return fmt.Errorf("AWS profile cannot be empty")
 	}
 	
-	// Set AWS_PROFILE
-	if err := os.Setenv("AWS_PROFILE", profile); err != nil {
-		return fmt.Errorf("failed to set AWS_PROFILE: %v", err)
-	}
-	
-	// Auto-generate KUBECONFIG path if TFINFRA_REPOSITORY_PATH is available
-	tfRepoPath := os.Getenv("TFINFRA_REPOSITORY_PATH")
-	if tfRepoPath != "" {
-		kubeconfigPath := filepath.Join(tfRepoPath, "setup", "k8senv", profile, "config")
+	var kubeErr error
+	a.updateEnv(func(env *envContext) *envContext {
+		vars := map[string]string{"AWS_PROFILE": profile}
+		kubeErr = nil
 		
-		// Check if the kubeconfig file exists
-		if _, err := os.Stat(kubeconfigPath); err == nil {
-			// Set KUBECONFIG
-			if err := os.Setenv("KUBECONFIG", kubeconfigPath); err != nil {
-				return fmt.Errorf("failed to set KUBECONFIG: %v", err)
-			}
-			
-			// Set kubectl context to match the profile
-			cmd := exec.Command("kubectl", "config", "use-context", profile)
-			cmd.Env = os.Environ() // Use current environment including updated KUBECONFIG
-			
-			if output, err := cmd.CombinedOutput(); err != nil {
-				// Don't fail the whole operation if kubectl context setting fails
-				fmt.Printf("Warning: failed to set kubectl context to %s: %v\nOutput: %s\n", profile, err, string(output))
-			}
-		} else {
-			fmt.Printf("Warning: kubeconfig file not found at %s\n", kubeconfigPath)
+		// Auto-generate KUBECONFIG path if TFINFRA_REPOSITORY_PATH is available
+		tfRepoPath := env.get("TFINFRA_REPOSITORY_PATH")
+		if tfRepoPath == "" {
+			return env.with(vars).withKubeContext("")
 		}
-	} else {
-		fmt.Println("Warning: TFINFRA_REPOSITORY_PATH environment variable not set, cannot auto-configure KUBECONFIG")
-	}
-	
-	return nil
+		kubeconfigPath := k8senvKubeconfigPath(tfRepoPath, profile)
+		if _, err := os.Stat(kubeconfigPath); err != nil {
+			vars["KUBECONFIG"] = ""
+			kubeErr = &KubeconfigError{Kind: kubeconfigErrNotFound, Path: kubeconfigPath, Message: "no kubeconfig generated for this AWS profile"}
+			return env.with(vars).withKubeContext("")
+		}
+		vars["KUBECONFIG"] = kubeconfigPath
+		
+		// Pin the context named after the profile
+		next := env.with(vars).withKubeContext("")
+		pinned, err := pinKubeContext(next, profile)
+		if kubeErr = err; err != nil {
+			return next
+		}
+		return pinned
+	})
+	return kubeErr
+}
+
+// k8senvKubeconfigPath returns the kubeconfig terraform-infra generates for an AWS profile
+func k8senvKubeconfigPath(tfRepoPath, awsProfile string) string {
+	return filepath.Join(tfRepoPath, "setup", "k8senv", awsProfile, "config")
 }
 
-// GetKubeconfig returns the current KUBECONFIG environment variable
+// GetKubeconfig returns the KUBECONFIG of the active environment
 func (a *App) GetKubeconfig() string {
-	return os.Getenv("KUBECONFIG")
+	return a.currentEnv().get("KUBECONFIG")
 }
 
-// SetKubeconfig sets the KUBECONFIG environment variable for the current session
+// setEnvVar sets a single variable in the active environment
+func (a *App) setEnvVar(key, value string) {
+	a.updateEnv(func(env *envContext) *envContext {
+		return env.with(map[string]string{key: value})
+	})
+}
+
+// SetKubeconfig sets KUBECONFIG in the active environment for the current session
 func (a *App) SetKubeconfig(path string) error {
 	if path == "" {
 		return fmt.Errorf("Kubeconfig path cannot be empty")
 	}
-	return os.Setenv("KUBECONFIG", path)
+	// A context pinned in the previous kubeconfig may not exist in the new one
+	a.updateEnv(func(env *envContext) *envContext {
+		return env.with(map[string]string{"KUBECONFIG": path}).withKubeContext("")
+	})
+	return nil
 }
 
-// SetPATH sets the PATH environment variable for the current session
+// SetPATH sets PATH in the active environment for the current session
 func (a *App) SetPATH(path string) error {
 	if path == "" {
 		return fmt.Errorf("PATH cannot be empty")
 	}
-	return os.Setenv("PATH", path)
+	a.setEnvVar("PATH", path)
+	return nil
 }
 
-// SetTfInfraRepositoryPath sets the TFINFRA_REPOSITORY_PATH environment variable for the current session
+// SetTfInfraRepositoryPath sets TFINFRA_REPOSITORY_PATH in the active environment for the current session
 func (a *App) SetTfInfraRepositoryPath(path string) error {
 	if path == "" {
 		return fmt.Errorf("TFINFRA_REPOSITORY_PATH cannot be empty")
 	}
-	return os.Setenv("TFINFRA_REPOSITORY_PATH", path)
+	a.setEnvVar("TFINFRA_REPOSITORY_PATH", path)
+	return nil
 }
 
-// SetGandiToken sets the GANDI_TOKEN environment variable for the current session
+// SetGandiToken sets GANDI_TOKEN in the active environment for the current session
 func (a *App) SetGandiToken(token string) error {
 	if token == "" {
 		return fmt.Errorf("GANDI_TOKEN cannot be empty")
 	}
-	return os.Setenv("GANDI_TOKEN", token)
+	a.setEnvVar("GANDI_TOKEN", token)
+	return nil
 }
 
-// GetGandiToken returns the current GANDI_TOKEN environment variable
+// GetGandiToken returns the GANDI_TOKEN of the active environment
 func (a *App) GetGandiToken() string {
-	return os.Getenv("GANDI_TOKEN")
+	return a.currentEnv().get("GANDI_TOKEN")
 }
 
 // IsGandiTokenSet returns whether GANDI_TOKEN is set without revealing the value
 func (a *App) IsGandiTokenSet() bool {
-	token := os.Getenv("GANDI_TOKEN")
+	token := a.currentEnv().get("GANDI_TOKEN")
 	return token != ""
 }
 
-// GetAWSProfiles reads ~/.aws/config and returns available profiles (excluding -sso profiles)
+// GetAWSProfiles returns the profiles of the AWS config file (excluding -sso profiles), AWS_CONFIG_FILE
+// overrides ~/.aws/config
 func (a *App) GetAWSProfiles() ([]string, error) {
-	homeDir, err := os.UserHomeDir()
+	config, err := loadAWSConfig(a.currentEnv())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %v", err)
-	}
-	
-	configPath := filepath.Join(homeDir, ".aws", "config")
-	
-	// Debug logging to help troubleshoot Finder launch issues
-	
-	file, err := os.Open(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil // Return empty list if config doesn't exist
-		}
-		return nil, fmt.Errorf("failed to open AWS config file at %s: %v", configPath, err)
+		return nil, err
 	}
-	defer file.Close()
 	
-	var profiles []string
-	scanner := bufio.NewScanner(file)
-	
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		
-		// Look for profile sections: [profile profile-name] or [default]
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			line = strings.Trim(line, "[]")
-			
-			var profileName string
-			if line == "default" {
-				profileName = "default"
-			} else if strings.HasPrefix(line, "profile ") {
-				profileName = strings.TrimPrefix(line, "profile ")
-			} else {
-				continue // Skip non-profile sections
-			}
-			
-			// Exclude profiles ending with -sso
-			if !strings.HasSuffix(profileName, "-sso") {
-				profiles = append(profiles, profileName)
-			}
+	profiles := []string{}
+	for _, name := range config.profileNames() {
+		// Exclude profiles ending with -sso
+		if !strings.HasSuffix(name, awsSSOProfileSuffix) {
+			profiles = append(profiles, name)
 		}
 	}
 	
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading AWS config file: %v", err)
-	}
-	
 	// Sort profiles for consistent ordering
 	sort.Strings(profiles)
 	return profiles, nil
@@ -175,9 +152,15 @@ func (a *App) GetAWSProfiles() ([]string, error) {
 
 // GetShellPATH attempts to get PATH from common shell configuration files
 func (a *App) GetShellPATH() (string, error) {
+	path, _, err := shellPATHSearch()
+	return path, err
+}
+
+// shellPATHSearch finds PATH like GetShellPATH and returns a line per shell configuration file it looked at
+func shellPATHSearch() (string, []string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %v", err)
+		return "", nil, fmt.Errorf("failed to get user home directory: %v", err)
 	}
 	
 	// Common shell config files to check (in order of preference)
@@ -196,10 +179,12 @@ func (a *App) GetShellPATH() (string, error) {
 	}
 	
 	// Look for PATH exports in shell config files
+	var details []string
 	for _, configFile := range configFiles {
 		configPath := filepath.Join(homeDir, configFile)
 		file, err := os.Open(configPath)
 		if err != nil {
+			details = append(details, fmt.Sprintf("~/%s: not found", configFile))
 			continue // Skip if file doesn't exist
 		}
 		defer file.Close()
@@ -212,13 +197,16 @@ func (a *App) GetShellPATH() (string, error) {
 			if strings.HasPrefix(line, "export PATH=") {
 				path := strings.TrimPrefix(line, "export PATH=")
 				path = strings.Trim(path, "\"'") // Remove quotes
-				return path, nil
+				details = append(details, fmt.Sprintf("~/%s: using %s", configFile, line))
+				return path, details, nil
 			} else if strings.HasPrefix(line, "PATH=") {
 				path := strings.TrimPrefix(line, "PATH=")
 				path = strings.Trim(path, "\"'") // Remove quotes
-				return path, nil
+				details = append(details, fmt.Sprintf("~/%s: using %s", configFile, line))
+				return path, details, nil
 			}
 		}
+		details = append(details, fmt.Sprintf("~/%s: no PATH assignment", configFile))
 	}
 	
 	// Fallback to common macOS paths if we can't find it in config files
@@ -231,104 +219,20 @@ func (a *App) GetShellPATH() (string, error) {
 		"/sbin",
 	}
 	
-	return strings.Join(commonPaths, ":"), nil
-}
-
-// GetShellEnvironment executes shell commands to get the full environment
-func (a *App) GetShellEnvironment() (map[string]string, error) {
-	// Determine user's shell
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "/bin/zsh" // Default to zsh on macOS
-	}
-	
-	// Get home directory for debugging
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
-	}
-	
-	// Try multiple approaches to get full environment
-	var output []byte
-	var cmdErr error
-	
-	// Approach 1: Interactive login shell (most comprehensive)
-	cmd := exec.Command(shell, "-l", "-i", "-c", "env")
-	cmd.Dir = homeDir // Set working directory to home
-	output, cmdErr = cmd.Output()
-	
-	if cmdErr != nil {
-		// Approach 2: Login shell
-		cmd = exec.Command(shell, "-l", "-c", "env")
-		cmd.Dir = homeDir
-		output, cmdErr = cmd.Output()
-	}
-	
-	if cmdErr != nil {
-		// Approach 3: Source .zshrc explicitly
-		cmd = exec.Command(shell, "-c", "source ~/.zshrc && env")
-		cmd.Dir = homeDir
-		output, cmdErr = cmd.Output()
-	}
-	
-	if cmdErr != nil {
-		return nil, fmt.Errorf("failed to get shell environment with all methods: %v", cmdErr)
-	}
-	
-	// Parse environment variables
-	envVars := make(map[string]string)
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && strings.Contains(line, "=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				envVars[parts[0]] = parts[1]
-			}
-		}
-	}
-	
-	
-	return envVars, nil
-}
-
-// ImportShellEnvironment imports environment variables from shell and sets them in the current process
-func (a *App) ImportShellEnvironment() error {
-	shellEnv, err := a.GetShellEnvironment()
-	if err != nil {
-		return fmt.Errorf("failed to get shell environment: %v", err)
-	}
-	
-	// Import key environment variables
-	importantVars := []string{
-		"AWS_PROFILE",
-		"KUBECONFIG", 
-		"PATH",
-		"TFINFRA_REPOSITORY_PATH",
-		"HOME",
-		"GANDI_TOKEN",
-	}
-	
-	for _, varName := range importantVars {
-		if value, exists := shellEnv[varName]; exists && value != "" {
-			if err := os.Setenv(varName, value); err != nil {
-				fmt.Printf("Warning: failed to set %s: %v\n", varName, err)
-			}
-		}
-	}
-	
-	return nil
+	details = append(details, "falling back to the default macOS PATH")
+	return strings.Join(commonPaths, ":"), details, nil
 }
 
 // GetEnvironmentVariables returns a map of current environment variables (with sensitive values masked)
 func (a *App) GetEnvironmentVariables() map[string]string {
+	env := a.currentEnv()
 	envVars := map[string]string{
-		"AWS_PROFILE":              os.Getenv("AWS_PROFILE"),
-		"KUBECONFIG":               os.Getenv("KUBECONFIG"),
-		"HOME":                     os.Getenv("HOME"),
-		"PATH":                     os.Getenv("PATH"),
-		"TFINFRA_REPOSITORY_PATH":  os.Getenv("TFINFRA_REPOSITORY_PATH"),
-		"GANDI_TOKEN":              maskSensitiveValue(os.Getenv("GANDI_TOKEN")),
+		"AWS_PROFILE":              env.get("AWS_PROFILE"),
+		"KUBECONFIG":               env.get("KUBECONFIG"),
+		"HOME":                     env.get("HOME"),
+		"PATH":                     env.get("PATH"),
+		"TFINFRA_REPOSITORY_PATH":  env.get("TFINFRA_REPOSITORY_PATH"),
+		"GANDI_TOKEN":              maskSensitiveValue(env.get("GANDI_TOKEN")),
 	}
 	
 	
@@ -346,94 +250,53 @@ func maskSensitiveValue(value string) string {
 	return value[:4] + "..." + value[len(value)-4:]
 }
 
-// SaveEnvironmentProfile saves the current environment configuration as a profile
+// SaveEnvironmentProfile saves the current environment configuration as a profile.
+// Tokens, secrets, passwords and keys are stored as secret variables.
 func (a *App) SaveEnvironmentProfile(name string) error {
 	if name == "" {
 		return fmt.Errorf("profile name cannot be empty")
 	}
 	
-	// Get current environment variables (DO NOT store sensitive tokens in profiles)
-	profile := EnvironmentProfile{
-		Name:                  name,
-		AWSProfile:            os.Getenv("AWS_PROFILE"),
-		Kubeconfig:            os.Getenv("KUBECONFIG"),
-		PATH:                  os.Getenv("PATH"),
-		TfInfraRepositoryPath: os.Getenv("TFINFRA_REPOSITORY_PATH"),
-		GandiToken:            "", // Never save sensitive tokens in profiles
-		CreatedAt:             time.Now().Format(time.RFC3339),
-	}
-	
-	// Get home directory for storing profiles
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
-	}
-	
-	// Create .yak-gui directory if it doesn't exist
-	yakGuiDir := filepath.Join(homeDir, ".yak-gui")
-	if err := os.MkdirAll(yakGuiDir, 0755); err != nil {
-		return fmt.Errorf("failed to create .yak-gui directory: %v", err)
-	}
-	
-	// Load existing profiles
 	profiles, err := a.GetEnvironmentProfiles()
 	if err != nil {
-		profiles = []EnvironmentProfile{}
+		return fmt.Errorf("failed to get profiles: %v", err)
 	}
-	
-	// Update or add profile
-	found := false
-	for i, p := range profiles {
+	var existing EnvironmentProfile
+	for _, p := range profiles {
 		if p.Name == name {
-			profiles[i] = profile
-			found = true
-			break
+			existing = p
 		}
 	}
-	if !found {
-		profiles = append(profiles, profile)
-	}
 	
-	// Save profiles to JSON file
-	profilesPath := filepath.Join(yakGuiDir, "environment-profiles.json")
-	data, err := json.MarshalIndent(profiles, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal profiles: %v", err)
+	// The well-known variables and everything set in the GUI, HOME is left to the process
+	env := a.currentEnv()
+	names := map[string]bool{"AWS_PROFILE": true, "KUBECONFIG": true, "PATH": true, "TFINFRA_REPOSITORY_PATH": true}
+	for key := range env.vars {
+		if key != "HOME" {
+			names[key] = true
+		}
 	}
 	
-	if err := os.WriteFile(profilesPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write profiles file: %v", err)
+	var variables []EnvironmentVariable
+	for key := range names {
+		value := env.get(key)
+		if value == "" {
+			continue
+		}
+		previous, _ := existing.variable(key)
+		variables = append(variables, EnvironmentVariable{
+			Name:   key,
+			Value:  value,
+			Secret: previous.Secret || isSensitiveVariable(key),
+		})
 	}
 	
-	return nil
+	return a.SaveEnvironmentProfileVariables(name, variables)
 }
 
-// GetEnvironmentProfiles returns all saved environment profiles
+// GetEnvironmentProfiles returns all saved environment profiles, secret values are never included
 func (a *App) GetEnvironmentProfiles() ([]EnvironmentProfile, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
-	}
-	
-	profilesPath := filepath.Join(homeDir, ".yak-gui", "environment-profiles.json")
-	
-	// Check if file exists
-	if _, err := os.Stat(profilesPath); os.IsNotExist(err) {
-		return []EnvironmentProfile{}, nil
-	}
-	
-	// Read profiles file
-	data, err := os.ReadFile(profilesPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read profiles file: %v", err)
-	}
-	
-	var profiles []EnvironmentProfile
-	if err := json.Unmarshal(data, &profiles); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal profiles: %v", err)
-	}
-	
-	return profiles, nil
+	return loadEnvironmentProfiles()
 }
 
 // LoadEnvironmentProfile loads a saved environment profile and applies it
@@ -460,66 +323,110 @@ func (a *App) LoadEnvironmentProfile(name string) error {
 		return fmt.Errorf("profile '%s' not found", name)
 	}
 	
-	// Apply the profile environment variables (skip sensitive tokens)
-	envVars := map[string]string{
-		"AWS_PROFILE":            targetProfile.AWSProfile,
-		"KUBECONFIG":             targetProfile.Kubeconfig,
-		"PATH":                   targetProfile.PATH,
-		"TFINFRA_REPOSITORY_PATH": targetProfile.TfInfraRepositoryPath,
-		// Note: GANDI_TOKEN is not restored from profiles for security
+	envVars, err := targetProfile.envVars()
+	if err != nil {
+		return err
 	}
 	
-	for key, value := range envVars {
-		if value != "" {
-			if err := os.Setenv(key, value); err != nil {
-				fmt.Printf("Warning: failed to set %s: %v\n", key, err)
-			}
-		}
-	}
+	// The variables and the profile name are switched in a single step, jobs keep the snapshot they started with
+	a.updateEnv(func(env *envContext) *envContext {
+		return withProfileKubeContext(env.with(envVars).withProfile(name))
+	})
 	
 	return nil
 }
 
-// DeleteEnvironmentProfile deletes a saved environment profile
-func (a *App) DeleteEnvironmentProfile(name string) error {
-	if name == "" {
-		return fmt.Errorf("profile name cannot be empty")
+// envForProfile returns the environment of a saved profile without making it the active one.
+// An empty name or the name of the active profile returns the active environment. Other profiles start
+// from the process environment, never from the overrides of the active profile, and must set AWS_PROFILE.
+func (a *App) envForProfile(name string) (*envContext, error) {
+	current := a.currentEnv()
+	if name == "" || name == current.profile {
+		return current, nil
 	}
 	
 	profiles, err := a.GetEnvironmentProfiles()
 	if err != nil {
-		return fmt.Errorf("failed to get profiles: %v", err)
+		return nil, fmt.Errorf("failed to get profiles: %v", err)
 	}
-	
-	// Remove the profile
-	var updatedProfiles []EnvironmentProfile
-	found := false
 	for _, p := range profiles {
 		if p.Name != name {
-			updatedProfiles = append(updatedProfiles, p)
-		} else {
-			found = true
+			continue
 		}
+		envVars, err := p.envVars()
+		if err != nil {
+			return nil, err
+		}
+		// Otherwise the profile would silently query with the credentials of the process
+		if envVars["AWS_PROFILE"] == "" {
+			return nil, fmt.Errorf("profile '%s' does not set AWS_PROFILE", name)
+		}
+		env := newEnvContext().with(envVars).withProfile(name)
+		
+		// Without an explicit kubeconfig the profile would talk to the cluster of the active one
+		if envVars["KUBECONFIG"] == "" {
+			kubeconfig := ""
+			if tfRepoPath := env.get("TFINFRA_REPOSITORY_PATH"); tfRepoPath != "" {
+				path := k8senvKubeconfigPath(tfRepoPath, envVars["AWS_PROFILE"])
+				if _, err := os.Stat(path); err == nil {
+					kubeconfig = path
+				}
+			}
+			env = env.with(map[string]string{"KUBECONFIG": kubeconfig})
+		}
+		
+		return withProfileKubeContext(env), nil
 	}
-	
-	if !found {
-		return fmt.Errorf("profile '%s' not found", name)
-	}
-	
-	// Save updated profiles
-	homeDir, err := os.UserHomeDir()
+	return nil, fmt.Errorf("profile '%s' not found", name)
+}
+
+// profileContext returns a context carrying the environment of a saved profile, see envForProfile
+func (a *App) profileContext(name string) (context.Context, error) {
+	env, err := a.envForProfile(name)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
+		return nil, err
+	}
+	return withEnvContext(context.Background(), env), nil
+}
+
+// GetActiveEnvironmentProfile returns the name of the loaded environment profile, empty when none was loaded
+func (a *App) GetActiveEnvironmentProfile() string {
+	return a.currentEnv().profile
+}
+
+// DeleteEnvironmentProfile deletes a saved environment profile
+func (a *App) DeleteEnvironmentProfile(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
 	}
 	
-	profilesPath := filepath.Join(homeDir, ".yak-gui", "environment-profiles.json")
-	data, err := json.MarshalIndent(updatedProfiles, "", "  ")
+	var removed *EnvironmentProfile
+	err := updateEnvironmentProfiles(func(profiles []EnvironmentProfile) ([]EnvironmentProfile, error) {
+		updatedProfiles := []EnvironmentProfile{}
+		for i, p := range profiles {
+			if p.Name != name {
+				updatedProfiles = append(updatedProfiles, p)
+			} else {
+				removed = &profiles[i]
+			}
+		}
+		if removed == nil {
+			return nil, fmt.Errorf("profile '%s' not found", name)
+		}
+		return updatedProfiles, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal profiles: %v", err)
+		return err
 	}
 	
-	if err := os.WriteFile(profilesPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write profiles file: %v", err)
+	// Drop the secret variables of the profile
+	if _, err := storeEnvironmentProfileVariables(name, removed.Variables, nil); err != nil {
+		fmt.Printf("Warning: failed to delete secrets of profile %s: %v\n", name, err)
+	}
+
+	// Drop the TFE settings and stored token of the profile
+	if err := deleteTFEProfileSettings(name); err != nil {
+		fmt.Printf("Warning: failed to delete TFE settings of profile %s: %v\n", name, err)
 	}
 	
 	return nil
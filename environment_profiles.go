@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	environmentProfilesFile       = "environment-profiles.json"
+	environmentProfilesBackupFile = "environment-profiles.v1.json"
+
+	// environmentProfilesVersion is the schema version of environment-profiles.json. Version 1 was a bare
+	// array of profiles with fixed fields and is migrated on first read.
+	environmentProfilesVersion = 2
+)
+
+// EnvironmentVariable is a variable set by an environment profile. Secret values are kept in the
+// encrypted secret store and never written to environment-profiles.json or returned to the frontend.
+type EnvironmentVariable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// environmentProfilesDocument is the layout of environment-profiles.json
+type environmentProfilesDocument struct {
+	Version  int                  `json:"version"`
+	Profiles []EnvironmentProfile `json:"profiles"`
+}
+
+// legacyEnvironmentProfile is a profile of the version 1 schema
+type legacyEnvironmentProfile struct {
+	Name                  string `json:"name"`
+	AWSProfile            string `json:"aws_profile"`
+	Kubeconfig            string `json:"kubeconfig"`
+	PATH                  string `json:"path"`
+	TfInfraRepositoryPath string `json:"tf_infra_repository_path"`
+	GandiToken            string `json:"gandi_token"`
+	CreatedAt             string `json:"created_at"`
+}
+
+// environmentProfileSecretName returns the secret store entry of a secret profile variable
+func environmentProfileSecretName(profile, variable string) string {
+	return "environment-profile:" + profile + ":" + variable
+}
+
+// validateEnvironmentVariableName checks a name can be exported to the tools we run
+func validateEnvironmentVariableName(name string) error {
+	if name == "" {
+		return fmt.Errorf("variable name cannot be empty")
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Errorf("invalid variable name %q: only letters, digits and _ are allowed and it cannot start with a digit", name)
+		}
+	}
+	return nil
+}
+
+// variable returns a variable of the profile
+func (p EnvironmentProfile) variable(name string) (EnvironmentVariable, bool) {
+	for _, v := range p.Variables {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return EnvironmentVariable{}, false
+}
+
+// migrateLegacyEnvironmentProfiles converts version 1 profiles, a saved GANDI_TOKEN becomes a secret variable
+func migrateLegacyEnvironmentProfiles(legacy []legacyEnvironmentProfile) ([]EnvironmentProfile, error) {
+	profiles := make([]EnvironmentProfile, 0, len(legacy))
+	for _, old := range legacy {
+		profile := EnvironmentProfile{Name: old.Name, Variables: []EnvironmentVariable{}, CreatedAt: old.CreatedAt}
+		for _, v := range []EnvironmentVariable{
+			{Name: "AWS_PROFILE", Value: old.AWSProfile},
+			{Name: "KUBECONFIG", Value: old.Kubeconfig},
+			{Name: "PATH", Value: old.PATH},
+			{Name: "TFINFRA_REPOSITORY_PATH", Value: old.TfInfraRepositoryPath},
+		} {
+			if v.Value != "" {
+				profile.Variables = append(profile.Variables, v)
+			}
+		}
+		if old.GandiToken != "" {
+			if err := setSecret(environmentProfileSecretName(old.Name, "GANDI_TOKEN"), old.GandiToken); err != nil {
+				return nil, err
+			}
+			profile.Variables = append(profile.Variables, EnvironmentVariable{Name: "GANDI_TOKEN", Secret: true})
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// readEnvironmentProfiles loads environment-profiles.json, the caller must hold its state lock.
+// Version 1 files are migrated in place, keeping a copy as environment-profiles.v1.json, and files
+// readable by other users are restricted to the owner.
+func readEnvironmentProfiles(path string) ([]EnvironmentProfile, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return []EnvironmentProfile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %v", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(path, 0600); err != nil {
+			return nil, fmt.Errorf("failed to restrict permissions of profiles file: %v", err)
+		}
+	}
+
+	data, err := readStateData(path, validStateJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var legacy []legacyEnvironmentProfile
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal profiles: %v", err)
+		}
+		if err := writeFileAtomic(filepath.Join(filepath.Dir(path), environmentProfilesBackupFile), data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to back up version 1 profiles: %v", err)
+		}
+		profiles, err := migrateLegacyEnvironmentProfiles(legacy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate profiles: %v", err)
+		}
+		if err := writeEnvironmentProfiles(path, profiles); err != nil {
+			return nil, err
+		}
+		return profiles, nil
+	}
+
+	var document environmentProfilesDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profiles: %v", err)
+	}
+	if document.Version > environmentProfilesVersion {
+		return nil, fmt.Errorf("profiles file has schema version %d, this version of yak-gui supports up to %d", document.Version, environmentProfilesVersion)
+	}
+	if document.Profiles == nil {
+		document.Profiles = []EnvironmentProfile{}
+	}
+	return document.Profiles, nil
+}
+
+// writeEnvironmentProfiles saves environment-profiles.json, the caller must hold its state lock
+func writeEnvironmentProfiles(path string, profiles []EnvironmentProfile) error {
+	return writeStateJSON(path, environmentProfilesDocument{
+		Version:  environmentProfilesVersion,
+		Profiles: profiles,
+	})
+}
+
+// loadEnvironmentProfiles reads environment-profiles.json under its state lock
+func loadEnvironmentProfiles() ([]EnvironmentProfile, error) {
+	var profiles []EnvironmentProfile
+	err := withStateLock(environmentProfilesFile, func(path string) error {
+		var err error
+		profiles, err = readEnvironmentProfiles(path)
+		return err
+	})
+	return profiles, err
+}
+
+// updateEnvironmentProfiles runs a read-modify-write cycle of environment-profiles.json under its state lock
+func updateEnvironmentProfiles(fn func(profiles []EnvironmentProfile) ([]EnvironmentProfile, error)) error {
+	return withStateLock(environmentProfilesFile, func(path string) error {
+		profiles, err := readEnvironmentProfiles(path)
+		if err != nil {
+			return err
+		}
+		profiles, err = fn(profiles)
+		if err != nil {
+			return err
+		}
+		return writeEnvironmentProfiles(path, profiles)
+	})
+}
+
+// storeEnvironmentProfileVariables moves secret values of a profile to the secret store and drops the
+// secrets of variables the profile no longer has as secrets. A secret without a value keeps the stored one.
+// Variables with a sensitive name are always stored as secrets.
+func storeEnvironmentProfileVariables(name string, previous, variables []EnvironmentVariable) ([]EnvironmentVariable, error) {
+	stored := make([]EnvironmentVariable, 0, len(variables))
+	secrets := make(map[string]bool)
+	for _, v := range variables {
+		// Credentials never reach the profiles file, whatever the caller asked for
+		if isSensitiveVariable(v.Name) {
+			v.Secret = true
+		}
+		if v.Secret {
+			secrets[v.Name] = true
+			if v.Value != "" {
+				if err := setSecret(environmentProfileSecretName(name, v.Name), v.Value); err != nil {
+					return nil, err
+				}
+			}
+			v.Value = ""
+		}
+		stored = append(stored, v)
+	}
+
+	for _, v := range previous {
+		if v.Secret && !secrets[v.Name] {
+			if err := setSecret(environmentProfileSecretName(name, v.Name), ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stored, nil
+}
+
+// SaveEnvironmentProfileVariables replaces the variables of a profile, creating the profile if needed.
+// Secret variables sent without a value keep their stored value.
+func (a *App) SaveEnvironmentProfileVariables(name string, variables []EnvironmentVariable) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	seen := make(map[string]bool)
+	for _, v := range variables {
+		if err := validateEnvironmentVariableName(v.Name); err != nil {
+			return err
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %s is defined twice", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	sorted := append([]EnvironmentVariable(nil), variables...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	return updateEnvironmentProfiles(func(profiles []EnvironmentProfile) ([]EnvironmentProfile, error) {
+		now := time.Now().Format(time.RFC3339)
+		for i, p := range profiles {
+			if p.Name != name {
+				continue
+			}
+			stored, err := storeEnvironmentProfileVariables(name, p.Variables, sorted)
+			if err != nil {
+				return nil, err
+			}
+			profiles[i].Variables = stored
+			profiles[i].UpdatedAt = now
+			return profiles, nil
+		}
+
+		stored, err := storeEnvironmentProfileVariables(name, nil, sorted)
+		if err != nil {
+			return nil, err
+		}
+		return append(profiles, EnvironmentProfile{Name: name, Variables: stored, CreatedAt: now, UpdatedAt: now}), nil
+	})
+}
+
+// envVars returns the variables a profile sets with secret values read from the secret store.
+// Empty values keep the current ones.
+func (p EnvironmentProfile) envVars() (map[string]string, error) {
+	envVars := make(map[string]string, len(p.Variables))
+	for _, v := range p.Variables {
+		value := v.Value
+		if v.Secret {
+			secret, _, err := getSecret(environmentProfileSecretName(p.Name, v.Name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret %s of profile %s: %v", v.Name, p.Name, err)
+			}
+			value = secret
+		}
+		if strings.TrimSpace(value) != "" {
+			envVars[v.Name] = value
+		}
+	}
+	return envVars, nil
+}
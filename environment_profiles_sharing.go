@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// portableProfilesVersion is the version of the exported profiles format
+	portableProfilesVersion = 1
+
+	// tfinfraTeamProfilesPath is the team-wide profile set shipped with terraform-infra, relative to TFINFRA_REPOSITORY_PATH
+	tfinfraTeamProfilesPath = "setup/yak_config/yak-gui-profiles.yaml"
+)
+
+// Conflict resolutions of ImportEnvironmentProfiles when a profile with the same name exists
+const (
+	profileConflictSkip      = "skip"
+	profileConflictOverwrite = "overwrite"
+	profileConflictRename    = "rename"
+)
+
+// portablePlaceholders are the variables replaced by placeholders in exported values, most specific first
+var portablePlaceholders = []string{"TFINFRA_REPOSITORY_PATH", "HOME"}
+
+// portableProfiles is the YAML layout of exported profiles
+type portableProfiles struct {
+	Version  int               `yaml:"version"`
+	Profiles []portableProfile `yaml:"profiles"`
+}
+
+// portableProfile is an exported profile, secrets are listed by name without their value
+type portableProfile struct {
+	Name      string            `yaml:"name"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Secrets   []string          `yaml:"secrets,omitempty"`
+}
+
+// EnvironmentProfileImportResult tells what happened to an imported profile
+type EnvironmentProfileImportResult struct {
+	Name       string   `json:"name"`
+	ImportedAs string   `json:"importedAs,omitempty"`
+	Action     string   `json:"action"` // created, overwritten, renamed, skipped
+	Warnings   []string `json:"warnings"`
+}
+
+// portableValue replaces the current values of the placeholder variables at the start of path list elements
+func portableValue(value string, env *envContext) string {
+	elements := strings.Split(value, string(filepath.ListSeparator))
+	for i, element := range elements {
+		for _, name := range portablePlaceholders {
+			base := strings.TrimSuffix(env.get(name), "/")
+			if base == "" {
+				continue
+			}
+			if element == base || strings.HasPrefix(element, base+"/") {
+				elements[i] = "${" + name + "}" + strings.TrimPrefix(element, base)
+				break
+			}
+		}
+	}
+	return strings.Join(elements, string(filepath.ListSeparator))
+}
+
+// expandPortableValue replaces the placeholders of an imported value by the values of the current environment
+func expandPortableValue(value string, env *envContext) (string, error) {
+	for _, name := range portablePlaceholders {
+		placeholder := "${" + name + "}"
+		if !strings.Contains(value, placeholder) {
+			continue
+		}
+		current := env.get(name)
+		if current == "" {
+			return "", fmt.Errorf("%s is not set", name)
+		}
+		value = strings.ReplaceAll(value, placeholder, strings.TrimSuffix(current, "/"))
+	}
+	return value, nil
+}
+
+// ExportEnvironmentProfiles writes profiles as portable YAML and returns the written path. User-specific
+// paths are replaced by ${HOME} and ${TFINFRA_REPOSITORY_PATH} and secret values are left out.
+// An empty list exports all profiles.
+func (a *App) ExportEnvironmentProfiles(names []string, exportPath string) (string, error) {
+	if exportPath == "" {
+		return "", fmt.Errorf("export path is required")
+	}
+	if lower := strings.ToLower(exportPath); !strings.HasSuffix(lower, ".yaml") && !strings.HasSuffix(lower, ".yml") {
+		exportPath += ".yaml"
+	}
+
+	profiles, err := a.GetEnvironmentProfiles()
+	if err != nil {
+		return "", err
+	}
+	byName := make(map[string]EnvironmentProfile, len(profiles))
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+	if len(names) == 0 {
+		for _, p := range profiles {
+			names = append(names, p.Name)
+		}
+	}
+
+	env := a.currentEnv()
+	export := portableProfiles{Version: portableProfilesVersion, Profiles: []portableProfile{}}
+	for _, name := range names {
+		profile, ok := byName[name]
+		if !ok {
+			return "", fmt.Errorf("profile '%s' not found", name)
+		}
+		portable := portableProfile{Name: name, Variables: map[string]string{}}
+		for _, v := range profile.Variables {
+			// Profiles saved before sensitive names were forced secret may hold credentials in clear
+			if v.Secret || isSensitiveVariable(v.Name) {
+				portable.Secrets = append(portable.Secrets, v.Name)
+			} else {
+				portable.Variables[v.Name] = portableValue(v.Value, env)
+			}
+		}
+		export.Profiles = append(export.Profiles, portable)
+	}
+
+	data, err := yaml.Marshal(export)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal profiles: %v", err)
+	}
+	if err := writeFileAtomic(exportPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write profiles: %v", err)
+	}
+	return exportPath, nil
+}
+
+// readPortableProfiles parses and validates an exported profile set
+func readPortableProfiles(path string) (*portableProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %v", err)
+	}
+	var set portableProfiles
+	if err := yaml.UnmarshalStrict(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles %s: %v", path, err)
+	}
+	if set.Version > portableProfilesVersion {
+		return nil, fmt.Errorf("profiles %s have format version %d, this version of yak-gui supports up to %d", path, set.Version, portableProfilesVersion)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range set.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("profiles %s contain a profile without name", path)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("profiles %s define %s twice", path, p.Name)
+		}
+		seen[p.Name] = true
+		for name := range p.Variables {
+			if err := validateEnvironmentVariableName(name); err != nil {
+				return nil, fmt.Errorf("profile %s: %v", p.Name, err)
+			}
+		}
+		for _, name := range p.Secrets {
+			if err := validateEnvironmentVariableName(name); err != nil {
+				return nil, fmt.Errorf("profile %s: %v", p.Name, err)
+			}
+			if _, ok := p.Variables[name]; ok {
+				return nil, fmt.Errorf("profile %s: %s is both a variable and a secret", p.Name, name)
+			}
+		}
+	}
+	return &set, nil
+}
+
+// uniqueProfileName returns name with the first numeric suffix not taken by a saved profile
+func uniqueProfileName(name string, taken map[string]int) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// importPortableProfiles adds an exported profile set to the saved profiles. Secrets are created without
+// value, an overwritten profile keeps the stored values of its secrets.
+func (a *App) importPortableProfiles(set *portableProfiles, conflict string) ([]EnvironmentProfileImportResult, error) {
+	switch conflict {
+	case profileConflictSkip, profileConflictOverwrite, profileConflictRename:
+	default:
+		return nil, fmt.Errorf("unsupported conflict resolution '%s', use skip, overwrite or rename", conflict)
+	}
+
+	env := a.currentEnv()
+	results := []EnvironmentProfileImportResult{}
+	err := updateEnvironmentProfiles(func(profiles []EnvironmentProfile) ([]EnvironmentProfile, error) {
+		index := make(map[string]int, len(profiles))
+		for i, p := range profiles {
+			index[p.Name] = i
+		}
+		now := time.Now().Format(time.RFC3339)
+
+		for _, portable := range set.Profiles {
+			result := EnvironmentProfileImportResult{Name: portable.Name, ImportedAs: portable.Name, Warnings: []string{}}
+
+			names := make([]string, 0, len(portable.Variables))
+			for name := range portable.Variables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			variables := []EnvironmentVariable{}
+			for _, name := range names {
+				expanded, err := expandPortableValue(portable.Variables[name], env)
+				if err != nil {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("%s left out: %v", name, err))
+					continue
+				}
+				variables = append(variables, EnvironmentVariable{Name: name, Value: expanded})
+			}
+			for _, name := range portable.Secrets {
+				variables = append(variables, EnvironmentVariable{Name: name, Secret: true})
+			}
+			sort.Slice(variables, func(i, j int) bool { return variables[i].Name < variables[j].Name })
+
+			i, exists := index[portable.Name]
+			var previous []EnvironmentVariable
+			switch {
+			case exists && conflict == profileConflictSkip:
+				result.Action = "skipped"
+				result.ImportedAs = ""
+				results = append(results, result)
+				continue
+			case exists && conflict == profileConflictOverwrite:
+				previous = profiles[i].Variables
+				result.Action = "overwritten"
+			case exists:
+				result.ImportedAs = uniqueProfileName(portable.Name, index)
+				result.Action = "renamed"
+			default:
+				result.Action = "created"
+			}
+
+			stored, err := storeEnvironmentProfileVariables(result.ImportedAs, previous, variables)
+			if err != nil {
+				return nil, err
+			}
+			if result.Action == "overwritten" {
+				profiles[i].Variables = stored
+				profiles[i].UpdatedAt = now
+			} else {
+				index[result.ImportedAs] = len(profiles)
+				profiles = append(profiles, EnvironmentProfile{Name: result.ImportedAs, Variables: stored, CreatedAt: now, UpdatedAt: now})
+			}
+
+			for _, name := range portable.Secrets {
+				if _, ok, err := getSecret(environmentProfileSecretName(result.ImportedAs, name)); err == nil && !ok {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("secret %s has no value until it is set", name))
+				}
+			}
+			results = append(results, result)
+		}
+		return profiles, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ImportEnvironmentProfiles adds the profiles of a file written by ExportEnvironmentProfiles.
+// conflict is skip, overwrite or rename and applies to profiles with the name of a saved one.
+func (a *App) ImportEnvironmentProfiles(path, conflict string) ([]EnvironmentProfileImportResult, error) {
+	if path == "" {
+		return nil, fmt.Errorf("import path is required")
+	}
+	set, err := readPortableProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return a.importPortableProfiles(set, conflict)
+}
+
+// ImportTeamEnvironmentProfiles adds the team-wide profiles shipped with terraform-infra
+func (a *App) ImportTeamEnvironmentProfiles(conflict string) ([]EnvironmentProfileImportResult, error) {
+	tfinfraPath := a.currentEnv().get("TFINFRA_REPOSITORY_PATH")
+	if tfinfraPath == "" {
+		return nil, fmt.Errorf("TFINFRA_REPOSITORY_PATH is not set")
+	}
+	path := filepath.Join(tfinfraPath, tfinfraTeamProfilesPath)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no team profiles at %s", path)
+	}
+	return a.ImportEnvironmentProfiles(path, conflict)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportImportEnvironmentProfiles verifies paths become placeholders, secrets stay local and conflicts are resolved
+func TestExportImportEnvironmentProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", filepath.Join(home, "src", "terraform-infra"))
+
+	app := NewApp()
+	require.NoError(t, app.SaveEnvironmentProfileVariables("staging", []EnvironmentVariable{
+		{Name: "AWS_PROFILE", Value: "staging"},
+		{Name: "KUBECONFIG", Value: filepath.Join(home, "src", "terraform-infra", "setup", "k8senv", "staging", "config")},
+		{Name: "PATH", Value: filepath.Join(home, "bin") + ":/usr/bin"},
+		{Name: "VAULT_ADDR", Value: "https://vault.example.com:8200"},
+		{Name: "VAULT_TOKEN", Value: "s.secret", Secret: true},
+	}))
+	// A profile saved before sensitive names were forced secret
+	require.NoError(t, updateEnvironmentProfiles(func(profiles []EnvironmentProfile) ([]EnvironmentProfile, error) {
+		profiles[0].Variables = append(profiles[0].Variables, EnvironmentVariable{Name: "AWS_SECRET_ACCESS_KEY", Value: "legacy-clear-key"})
+		return profiles, nil
+	}))
+
+	exported, err := app.ExportEnvironmentProfiles(nil, filepath.Join(t.TempDir(), "profiles"))
+	require.NoError(t, err)
+	assert.Equal(t, ".yaml", filepath.Ext(exported))
+	data, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "${TFINFRA_REPOSITORY_PATH}/setup/k8senv/staging/config")
+	assert.Contains(t, string(data), "${HOME}/bin:/usr/bin")
+	assert.Contains(t, string(data), "https://vault.example.com:8200")
+	assert.NotContains(t, string(data), "s.secret")
+	assert.NotContains(t, string(data), "legacy-clear-key")
+	assert.Contains(t, string(data), "- AWS_SECRET_ACCESS_KEY")
+	assert.NotContains(t, string(data), home)
+
+	// Another engineer imports the file with their own paths
+	otherHome := t.TempDir()
+	t.Setenv("HOME", otherHome)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", "/work/terraform-infra")
+	other := NewApp()
+	results, err := other.ImportEnvironmentProfiles(exported, profileConflictSkip)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "created", results[0].Action)
+	assert.Contains(t, results[0].Warnings[0], "VAULT_TOKEN")
+
+	env, err := other.envForProfile("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "/work/terraform-infra/setup/k8senv/staging/config", env.get("KUBECONFIG"))
+	assert.Equal(t, filepath.Join(otherHome, "bin")+":/usr/bin", env.get("PATH"))
+
+	results, err = other.ImportEnvironmentProfiles(exported, profileConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, "skipped", results[0].Action)
+	results, err = other.ImportEnvironmentProfiles(exported, profileConflictRename)
+	require.NoError(t, err)
+	assert.Equal(t, "staging-2", results[0].ImportedAs)
+	results, err = other.ImportEnvironmentProfiles(exported, profileConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, "overwritten", results[0].Action)
+
+	profiles, err := other.GetEnvironmentProfiles()
+	require.NoError(t, err)
+	assert.Len(t, profiles, 2)
+
+	_, err = other.ImportEnvironmentProfiles(exported, "merge")
+	assert.Error(t, err)
+}
+
+// TestImportTeamEnvironmentProfiles verifies the team profile set is read from terraform-infra
+func TestImportTeamEnvironmentProfiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tfinfra := t.TempDir()
+	t.Setenv("TFINFRA_REPOSITORY_PATH", tfinfra)
+
+	app := NewApp()
+	_, err := app.ImportTeamEnvironmentProfiles(profileConflictSkip)
+	assert.ErrorContains(t, err, "no team profiles")
+
+	path := filepath.Join(tfinfra, tfinfraTeamProfilesPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(`version: 1
+profiles:
+- name: production
+  variables:
+    AWS_PROFILE: prod
+    KUBECONFIG: ${TFINFRA_REPOSITORY_PATH}/setup/k8senv/prod/config
+`), 0644))
+
+	results, err := app.ImportTeamEnvironmentProfiles(profileConflictSkip)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Warnings)
+
+	env, err := app.envForProfile("production")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tfinfra, "setup/k8senv/prod/config"), env.get("KUBECONFIG"))
+
+	require.NoError(t, os.WriteFile(path, []byte("profiles:\n- name: a\n  variables: {\"BAD NAME\": x}\n"), 0644))
+	_, err = app.ImportTeamEnvironmentProfiles(profileConflictSkip)
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrateEnvironmentProfiles verifies version 1 files are converted, backed up and restricted to the owner
+func TestMigrateEnvironmentProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", "")
+	dir := filepath.Join(home, ".yak-gui")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	legacy := `[{"name": "staging", "aws_profile": "staging", "kubeconfig": "", "path": "/usr/bin", "tf_infra_repository_path": "/src/terraform-infra", "gandi_token": "gandi-secret-token", "created_at": "2024-01-01T00:00:00Z"}]`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, environmentProfilesFile), []byte(legacy), 0644))
+
+	app := NewApp()
+	profiles, err := app.GetEnvironmentProfiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, []EnvironmentVariable{
+		{Name: "AWS_PROFILE", Value: "staging"},
+		{Name: "PATH", Value: "/usr/bin"},
+		{Name: "TFINFRA_REPOSITORY_PATH", Value: "/src/terraform-infra"},
+		{Name: "GANDI_TOKEN", Secret: true},
+	}, profiles[0].Variables)
+
+	info, err := os.Stat(filepath.Join(dir, environmentProfilesFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(filepath.Join(dir, environmentProfilesFile))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "gandi-secret-token")
+	var document environmentProfilesDocument
+	require.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, environmentProfilesVersion, document.Version)
+	backup, err := os.ReadFile(filepath.Join(dir, environmentProfilesBackupFile))
+	require.NoError(t, err)
+	assert.Equal(t, legacy, string(backup))
+
+	require.NoError(t, app.LoadEnvironmentProfile("staging"))
+	assert.Equal(t, "gandi-secret-token", app.GetGandiToken())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, environmentProfilesFile), []byte(`{"version": 3, "profiles": []}`), 0600))
+	_, err = app.GetEnvironmentProfiles()
+	assert.ErrorContains(t, err, "schema version 3")
+}
+
+// TestEnvForProfileIsolation verifies a saved profile never inherits the overrides of the active profile
+func TestEnvForProfileIsolation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFINFRA_REPOSITORY_PATH", "")
+	t.Setenv("TFE_TOKEN", "")
+	app := NewApp()
+
+	require.NoError(t, app.SaveEnvironmentProfileVariables("production", []EnvironmentVariable{{Name: "AWS_PROFILE", Value: "prod-sso"}}))
+	require.NoError(t, app.SaveEnvironmentProfileVariables("tools", []EnvironmentVariable{{Name: "VAULT_ADDR", Value: "https://vault.example.com"}}))
+	app.env.Store(newEnvContext().with(map[string]string{"AWS_PROFILE": "staging-sso", "TFE_TOKEN": "staging-token"}).withProfile("staging"))
+
+	env, err := app.envForProfile("production")
+	require.NoError(t, err)
+	assert.Equal(t, "prod-sso", env.get("AWS_PROFILE"))
+	assert.Empty(t, env.get("TFE_TOKEN"), "the token of the active profile is not used for production")
+
+	_, err = app.envForProfile("tools")
+	assert.ErrorContains(t, err, "does not set AWS_PROFILE")
+}
+
+// TestSaveEnvironmentProfileVariables verifies secret values live in the secret store only
+func TestSaveEnvironmentProfileVariables(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	require.NoError(t, app.SaveEnvironmentProfileVariables("vault", []EnvironmentVariable{
+		{Name: "AWS_PROFILE", Value: "vault-sso"},
+		{Name: "VAULT_ADDR", Value: "https://vault.example.com"},
+		{Name: "VAULT_TOKEN", Value: "s.vault-token", Secret: true},
+		{Name: "GITHUB_TOKEN", Value: "ghp_plain", Secret: false},
+	}))
+	profiles, err := app.GetEnvironmentProfiles()
+	require.NoError(t, err)
+	github, _ := profiles[0].variable("GITHUB_TOKEN")
+	assert.True(t, github.Secret, "a sensitive name is stored as a secret")
+	assert.Empty(t, github.Value)
+
+	// Sending the secret without a value keeps the stored one
+	require.NoError(t, app.SaveEnvironmentProfileVariables("vault", []EnvironmentVariable{
+		{Name: "AWS_PROFILE", Value: "vault-sso"},
+		{Name: "VAULT_ADDR", Value: "https://vault2.example.com"},
+		{Name: "VAULT_TOKEN", Secret: true},
+	}))
+
+	profiles, err = app.GetEnvironmentProfiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	token, _ := profiles[0].variable("VAULT_TOKEN")
+	assert.Empty(t, token.Value)
+
+	env, err := app.envForProfile("vault")
+	require.NoError(t, err)
+	assert.Equal(t, "s.vault-token", env.get("VAULT_TOKEN"))
+	assert.Equal(t, "https://vault2.example.com", env.get("VAULT_ADDR"))
+
+	assert.Error(t, app.SaveEnvironmentProfileVariables("vault", []EnvironmentVariable{{Name: "1BAD"}}))
+	assert.Error(t, app.SaveEnvironmentProfileVariables("vault", []EnvironmentVariable{{Name: "A"}, {Name: "A"}}))
+
+	require.NoError(t, app.DeleteEnvironmentProfile("vault"))
+	_, stored, err := getSecret(environmentProfileSecretName("vault", "VAULT_TOKEN"))
+	require.NoError(t, err)
+	assert.False(t, stored)
+}
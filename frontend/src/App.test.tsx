@@ -17,10 +17,12 @@ const mockAWSProfiles = ['dev', 'staging', 'production']
 const mockEnvironmentProfiles = [
   {
     name: 'Development',
-    aws_profile: 'dev',
-    kubeconfig: '/path/to/dev/config',
-    path: '/usr/local/bin:/usr/bin',
-    tf_infra_repository_path: '/path/to/terraform-infra',
+    variables: [
+      { name: 'AWS_PROFILE', value: 'dev' },
+      { name: 'KUBECONFIG', value: '/path/to/dev/config' },
+      { name: 'PATH', value: '/usr/local/bin:/usr/bin' },
+      { name: 'TFINFRA_REPOSITORY_PATH', value: '/path/to/terraform-infra' }
+    ],
     created_at: '2024-01-01T00:00:00Z'
   }
 ]
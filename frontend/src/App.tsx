@@ -69,13 +69,17 @@ const { Option } = Select;
 
 // Types matching the Go backend
 
+interface EnvironmentVariable {
+  name: string;
+  value?: string;
+  secret?: boolean;
+}
+
 interface EnvironmentProfile {
   name: string;
-  aws_profile: string;
-  kubeconfig: string;
-  path: string;
-  tf_infra_repository_path: string;
+  variables: EnvironmentVariable[];
   created_at: string;
+  updated_at?: string;
 }
 
 // Declare global functions for Wails - consolidated interface for all components
@@ -99,18 +103,35 @@ declare global {
           SetPATH: (path: string) => Promise<void>;
           SetTfInfraRepositoryPath: (path: string) => Promise<void>;
           GetAWSProfiles: () => Promise<string[]>;
+          GetAWSProfileDetails: () => Promise<{ name: string; region?: string; accountId?: string; roleName?: string; ssoProfile?: string; chain: string[]; kubeconfig?: string; kubeconfigExists: boolean; warnings: string[] }[]>;
+          GetKubeconfigInfo: () => Promise<{ files: string[]; currentContext: string; contexts: { name: string; cluster: string; user: string; namespace: string; current: boolean; file: string }[]; clusters: { name: string; server: string; insecureSkipTlsVerify: boolean; hasCertificateAuthority: boolean }[]; users: { name: string; authType: string; execCommand?: string }[] }>;
+          SetKubeContext: (name: string) => Promise<void>;
+          SetKubeNamespace: (contextName: string, namespace: string) => Promise<void>;
+          CheckKubeConnectivity: (contextName: string) => Promise<{ context: string; server: string; reachable: boolean; statusCode?: number; version?: string; latencyMs: number; error?: { kind: string; path?: string; context?: string; message: string } }>;
+          GetAWSSessionStatus: (profile: string) => Promise<{ profile: string; ssoProfile?: string; status: string; expiresAt?: string; expiresIn: number; tokenExpiresAt?: string; refreshable: boolean; message: string }>;
+          LoginAWSSSO: (profile: string) => Promise<void>;
+          CancelAWSSSOLogin: (profile: string) => Promise<void>;
           GetShellPATH: () => Promise<string>;
           GetShellEnvironment: () => Promise<Record<string, string>>;
           ImportShellEnvironment: () => Promise<void>;
+          GetShellImportSettings: () => Promise<{ allowlist: string[] }>;
+          SaveShellImportSettings: (settings: { allowlist: string[] }) => Promise<void>;
+          GetShellImportReport: () => Promise<{ shell: string; method?: string; startedAt: string; durationMs: number; attempts: { method: string; error?: string }[]; noiseLines: number; variables: { name: string; value: string; source: string; pattern: string }[]; notAllowed: string[]; error?: string } | null>;
           GetEnvironmentVariables: () => Promise<Record<string, string>>;
           SaveEnvironmentProfile: (name: string) => Promise<void>;
           GetEnvironmentProfiles: () => Promise<EnvironmentProfile[]>;
           LoadEnvironmentProfile: (name: string) => Promise<void>;
           DeleteEnvironmentProfile: (name: string) => Promise<void>;
+          SaveEnvironmentProfileVariables: (name: string, variables: EnvironmentVariable[]) => Promise<void>;
+          ExportEnvironmentProfiles: (names: string[], exportPath: string) => Promise<string>;
+          ImportEnvironmentProfiles: (path: string, conflict: 'skip' | 'overwrite' | 'rename') => Promise<{ name: string; importedAs?: string; action: string; warnings: string[] }[]>;
+          ImportTeamEnvironmentProfiles: (conflict: 'skip' | 'overwrite' | 'rename') => Promise<{ name: string; importedAs?: string; action: string; warnings: string[] }[]>;
           GetAppVersion: () => Promise<Record<string, string>>;
+          RunDiagnostics: () => Promise<{ startedAt: string; durationMs: number; status: 'pass' | 'warn' | 'fail'; passed: number; warnings: number; failures: number; checks: { name: string; status: 'pass' | 'warn' | 'fail'; message: string; hint?: string; version?: string; details: string[]; durationMs: number }[] }>;
           TestSimpleArray: () => Promise<string[]>;
           TestSimpleApps: () => Promise<ArgoApp[]>;
           LoginToArgoCD: (config: ArgoConfig) => Promise<void>;
+          GetArgoAppsAcrossProfiles: (profiles: string[], project: string) => Promise<{ profile: string; server: string; apps: ArgoApp[]; error?: string }[]>;
           // Rollouts functions
           GetRollouts: (config: any) => Promise<any[]>;
           GetRolloutStatus: (config: any, rolloutName: string) => Promise<any>;
@@ -119,6 +140,7 @@ declare global {
           AbortRollout: (config: any, rolloutName: string) => Promise<void>;
           RestartRollout: (config: any, rolloutName: string) => Promise<void>;
           SetRolloutImage: (config: any, rolloutName: string, image: string, container: string) => Promise<void>;
+          GetRolloutsAcrossProfiles: (profiles: string[], namespace: string) => Promise<{ profile: string; rollouts: any[]; error?: string }[]>;
           // Secret functions
           GetSecrets: (config: any, path: string) => Promise<any[]>;
           GetSecretData: (config: any, path: string, version: number) => Promise<any>;
@@ -146,8 +168,8 @@ declare global {
           GetTFEWorkspacesByTag: (config: any, tag: string, not: boolean) => Promise<any[]>;
           ExecuteTFEPlan: (config: any, execution: any) => Promise<any[]>;
           GetTFERuns: (config: any, workspaceID: string) => Promise<any[]>;
-          LockTFEWorkspace: (config: any, workspaceNames: string[], checkStatus: boolean) => Promise<void>;
-          UnlockTFEWorkspace: (config: any, workspaceNames: string[], force: boolean) => Promise<void>;
+          LockTFEWorkspace: (config: any, workspaceNames: string[], reason: string, checkStatus: boolean) => Promise<any[]>;
+          UnlockTFEWorkspace: (config: any, workspaceNames: string[], force: boolean) => Promise<any[]>;
           SetTFEWorkspaceVersion: (config: any, workspaceNames: string[], version: string) => Promise<void>;
           DiscardTFERuns: (config: any, ageHours: number, discardPending: boolean, dryRun: boolean, allWorkspaces: boolean) => Promise<void>;
           GetTFEVersions: (config: any) => Promise<any[]>;
@@ -312,7 +334,9 @@ const EnvironmentConfig: React.FC<{
         onAWSProfileChange();
       }
     } catch (error) {
+      // AWS_PROFILE is set even when the kubeconfig could not be configured
       setError(`Failed to set AWS Profile: ${error}`);
+      await loadEnvironmentVariables();
     } finally {
       setLoading(false);
     }
@@ -550,7 +574,7 @@ const EnvironmentConfig: React.FC<{
                   >
                     {profiles.map((profile) => (
                       <Option key={profile.name} value={profile.name}>
-                        {profile.name} (AWS: {profile.aws_profile || 'none'})
+                        {profile.name} (AWS: {profile.variables.find((v) => v.name === 'AWS_PROFILE')?.value || 'none'})
                       </Option>
                     ))}
                   </Select>
@@ -630,7 +654,7 @@ const EnvironmentConfig: React.FC<{
                 <Text type="secondary">💡 No AWS profiles found in ~/.aws/config</Text>
               )}
               {envVars.TFINFRA_REPOSITORY_PATH && (
-                <Text type="success">✨ Auto-configures KUBECONFIG and kubectl context when profile is selected</Text>
+                <Text type="success">✨ Auto-configures KUBECONFIG and the Kubernetes context when profile is selected</Text>
               )}
               {!envVars.TFINFRA_REPOSITORY_PATH && (
                 <Text type="warning">⚠️ TFINFRA_REPOSITORY_PATH not set - KUBECONFIG won't be auto-configured</Text>
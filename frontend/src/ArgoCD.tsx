@@ -47,6 +47,7 @@ interface ArgoConfig {
   project: string;
   username?: string;
   password?: string;
+  profile?: string;
 }
 
 interface ArgoAppDetail {
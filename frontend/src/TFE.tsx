@@ -79,6 +79,7 @@ interface TFERun {
   terraformVersion?: string;
   hasChanges?: boolean;
   isDestroy?: boolean;
+  refreshOnly?: boolean;
   isConfirmable?: boolean;
   actions?: {
     isConfirmable: boolean;
@@ -110,6 +111,9 @@ const TFE: React.FC = () => {
   const [autoRefresh, setAutoRefresh] = useState(false);
   const [activeTab, setActiveTab] = useState<'workspaces' | 'runs' | 'versions'>('workspaces');
   const [showConfigModal, setShowConfigModal] = useState(false);
+  const [lockWorkspaceId, setLockWorkspaceId] = useState<string | null>(null);
+  const [lockReason, setLockReason] = useState('');
+  const [lockWarning, setLockWarning] = useState<string | null>(null);
   const [nameFilter, setNameFilter] = useState('');
   const [environmentFilter, setEnvironmentFilter] = useState('');
   const [pageSize, setPageSize] = useState(10);
@@ -206,19 +210,26 @@ const TFE: React.FC = () => {
   };
 
   // Lock/unlock workspace
-  const toggleWorkspaceLock = async (workspaceId: string, lock: boolean) => {
+  const toggleWorkspaceLock = async (workspaceId: string, lock: boolean, reason = '') => {
     setLoading(true);
     setError(null);
+    setLockWarning(null);
     try {
       if (window.go?.main?.App?.LockTFEWorkspace && window.go?.main?.App?.UnlockTFEWorkspace) {
         // Find workspace name from workspaceId
         const workspace = workspaces.find(w => w.id === workspaceId);
         if (workspace) {
           const workspaceNames = [workspace.name];
-          if (lock) {
-            await window.go.main.App.LockTFEWorkspace(config, workspaceNames, true);
-          } else {
-            await window.go.main.App.UnlockTFEWorkspace(config, workspaceNames, false);
+          const results = lock
+            ? await window.go.main.App.LockTFEWorkspace(config, workspaceNames, reason, true)
+            : await window.go.main.App.UnlockTFEWorkspace(config, workspaceNames, false);
+          const failed = (results || []).find((r: any) => !r.success);
+          if (failed) {
+            throw new Error(`${failed.workspaceName}: ${failed.error}`);
+          }
+          const warned = (results || []).find((r: any) => r.warning);
+          if (warned) {
+            setLockWarning(`${warned.workspaceName}: ${warned.warning}`);
           }
         } else {
           throw new Error('Workspace not found');
@@ -406,7 +417,14 @@ const TFE: React.FC = () => {
             <Button
               icon={record.status === 'locked' ? <UnlockOutlined /> : <LockOutlined />}
               size="small"
-              onClick={() => toggleWorkspaceLock(record.id, record.status !== 'locked')}
+              onClick={() => {
+                if (record.status === 'locked') {
+                  toggleWorkspaceLock(record.id, false);
+                } else {
+                  setLockReason('');
+                  setLockWorkspaceId(record.id);
+                }
+              }}
               loading={loading}
             />
           </Tooltip>
@@ -665,6 +683,17 @@ const TFE: React.FC = () => {
         />
       )}
 
+      {lockWarning && (
+        <Alert
+          message={lockWarning}
+          type="warning"
+          showIcon
+          closable
+          onClose={() => setLockWarning(null)}
+          style={{ marginBottom: '16px' }}
+        />
+      )}
+
       <Card>
         <Space direction="vertical" style={{ width: '100%', marginBottom: '16px' }}>
           <Row justify="space-between">
@@ -684,6 +713,29 @@ const TFE: React.FC = () => {
         />
       </Card>
 
+      {/* Lock Reason Modal */}
+      <Modal
+        title="Lock Workspace"
+        open={lockWorkspaceId !== null}
+        onCancel={() => setLockWorkspaceId(null)}
+        onOk={() => {
+          const workspaceId = lockWorkspaceId;
+          setLockWorkspaceId(null);
+          if (workspaceId) {
+            toggleWorkspaceLock(workspaceId, true, lockReason.trim());
+          }
+        }}
+        okText="Lock"
+        okButtonProps={{ disabled: !lockReason.trim() }}
+      >
+        <Input
+          placeholder="Why is this workspace locked? e.g., incident INC-42"
+          value={lockReason}
+          onChange={(e) => setLockReason(e.target.value)}
+          prefix={<LockOutlined />}
+        />
+      </Modal>
+
       {/* Configuration Modal */}
       <Modal
         title="TFE Configuration"
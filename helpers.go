@@ -1,12 +1,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 // findYakExecutable searches for yak executable in common paths
 func findYakExecutable() string {
+	path, _ := searchYakExecutable()
+	return path
+}
+
+// searchYakExecutable returns the yak executable findYakExecutable uses and a line per location it tried
+func searchYakExecutable() (string, []string) {
 	// Common paths where yak might be installed
 	paths := []string{
 		"/opt/homebrew/bin/yak",    // Homebrew on Apple Silicon
@@ -15,12 +25,21 @@ func findYakExecutable() string {
 		"yak",                      // Try PATH first
 	}
 	
+	var details []string
 	for _, path := range paths {
-		if _, err := exec.LookPath(path); err == nil {
-			return path
+		found, err := exec.LookPath(path)
+		if err == nil {
+			details = append(details, fmt.Sprintf("%s: found at %s", path, found))
+			return path, details
+		}
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			details = append(details, fmt.Sprintf("%s: not found", path))
+		} else {
+			details = append(details, fmt.Sprintf("%s: %v", path, err))
 		}
 	}
-	return "yak" // fallback to PATH
+	details = append(details, "falling back to yak in the environment PATH")
+	return "yak", details // fallback to PATH
 }
 
 // Helper functions to safely extract values from map
@@ -156,3 +175,17 @@ func extractSyncLoop(data map[string]interface{}) string {
 	return ""
 }
 
+
+// yakGuiDir returns the ~/.yak-gui state directory, creating it if needed
+func yakGuiDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	dir := filepath.Join(homeDir, ".yak-gui")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create .yak-gui directory: %v", err)
+	}
+	return dir, nil
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"os/exec"
+	"context"
 )
 
 // JWT client/server configuration structures
@@ -47,9 +47,7 @@ func (a *App) CreateJWTClient(config JWTClientConfig) error {
 	}
 
 	// Execute yak secret jwt client
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	if _, err := a.runYakCombined(context.Background(), args...); err != nil {
 		return fmt.Errorf("failed to create JWT client secret: %w", err)
 	}
 
@@ -76,9 +74,7 @@ func (a *App) CreateJWTServer(config JWTServerConfig) error {
 	}
 
 	// Execute yak secret jwt server
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	if _, err := a.runYakCombined(context.Background(), args...); err != nil {
 		return fmt.Errorf("failed to create JWT server secret: %w", err)
 	}
 
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// kubeConnectivityTimeout bounds the /version request of a connectivity check
+const kubeConnectivityTimeout = 5 * time.Second
+
+// Kubeconfig error kinds
+const (
+	kubeconfigErrNotFound        = "not-found"
+	kubeconfigErrParse           = "parse"
+	kubeconfigErrWrite           = "write"
+	kubeconfigErrMissingContext  = "missing-context"
+	kubeconfigErrMissingCluster  = "missing-cluster"
+	kubeconfigErrInvalidTLS      = "invalid-tls"
+	kubeconfigErrConnectivity    = "connectivity"
+	kubeconfigErrInvalidResponse = "invalid-response"
+)
+
+// KubeconfigError is a structured kubeconfig failure, Kind is one of the kubeconfigErr constants
+type KubeconfigError struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Context string `json:"context,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements error with a "kubeconfig <kind>:" prefix the frontend can match on
+func (e *KubeconfigError) Error() string {
+	var where []string
+	if e.Context != "" {
+		where = append(where, "context "+e.Context)
+	}
+	if e.Path != "" {
+		where = append(where, e.Path)
+	}
+	if len(where) == 0 {
+		return fmt.Sprintf("kubeconfig %s: %s", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("kubeconfig %s: %s (%s)", e.Kind, e.Message, strings.Join(where, ", "))
+}
+
+// KubeContext is a context of a kubeconfig
+type KubeContext struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+	Current   bool   `json:"current"`
+	File      string `json:"file"`
+}
+
+// KubeCluster is a cluster of a kubeconfig
+type KubeCluster struct {
+	Name                  string `json:"name"`
+	Server                string `json:"server"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTlsVerify"`
+	HasCertificateAuth    bool   `json:"hasCertificateAuthority"`
+}
+
+// KubeUser is a user of a kubeconfig, credentials are never returned
+type KubeUser struct {
+	Name        string `json:"name"`
+	AuthType    string `json:"authType"` // exec, token, client-certificate, basic, auth-provider, none
+	ExecCommand string `json:"execCommand,omitempty"`
+}
+
+// KubeconfigInfo is the merged content of the KUBECONFIG files of the active environment
+type KubeconfigInfo struct {
+	Files          []string      `json:"files"`
+	CurrentContext string        `json:"currentContext"`
+	Contexts       []KubeContext `json:"contexts"`
+	Clusters       []KubeCluster `json:"clusters"`
+	Users          []KubeUser    `json:"users"`
+}
+
+// KubeConnectivityResult is the outcome of a /version request to the cluster of a context
+type KubeConnectivityResult struct {
+	Context    string           `json:"context"`
+	Server     string           `json:"server"`
+	Reachable  bool             `json:"reachable"`
+	StatusCode int              `json:"statusCode,omitempty"`
+	Version    string           `json:"version,omitempty"`
+	LatencyMs  int64            `json:"latencyMs"`
+	Error      *KubeconfigError `json:"error,omitempty"`
+}
+
+// kubeconfigFile is the subset of the kubeconfig format read by the GUI
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			Username              string `yaml:"username"`
+			Exec                  *struct {
+				Command string `yaml:"command"`
+			} `yaml:"exec"`
+			AuthProvider *struct {
+				Name string `yaml:"name"`
+			} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// kubeconfigSet is the merged view of several kubeconfig files, the first file defining a name wins like kubectl
+type kubeconfigSet struct {
+	info     KubeconfigInfo
+	clusters map[string]kubeClusterDetail
+}
+
+// kubeClusterDetail keeps the TLS settings of a cluster, resolved relative to its file
+type kubeClusterDetail struct {
+	KubeCluster
+	caFile string
+	caData string
+}
+
+// kubeconfigPaths returns the kubeconfig files of an environment, KUBECONFIG may list several
+func kubeconfigPaths(env *envContext) ([]string, error) {
+	var paths []string
+	for _, path := range filepath.SplitList(env.get("KUBECONFIG")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) > 0 {
+		return paths, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return []string{filepath.Join(homeDir, ".kube", "config")}, nil
+}
+
+// readKubeconfigFile parses a single kubeconfig file
+func readKubeconfigFile(path string) (*kubeconfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &KubeconfigError{Kind: kubeconfigErrNotFound, Path: path, Message: "kubeconfig file does not exist"}
+		}
+		return nil, &KubeconfigError{Kind: kubeconfigErrNotFound, Path: path, Message: err.Error()}
+	}
+	var file kubeconfigFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, &KubeconfigError{Kind: kubeconfigErrParse, Path: path, Message: err.Error()}
+	}
+	return &file, nil
+}
+
+// loadKubeconfig merges the kubeconfig files of an environment, missing files are skipped unless all are missing
+func loadKubeconfig(env *envContext) (*kubeconfigSet, error) {
+	paths, err := kubeconfigPaths(env)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &kubeconfigSet{
+		info: KubeconfigInfo{
+			Files:    []string{},
+			Contexts: []KubeContext{},
+			Clusters: []KubeCluster{},
+			Users:    []KubeUser{},
+		},
+		clusters: map[string]kubeClusterDetail{},
+	}
+	contexts := map[string]bool{}
+	users := map[string]bool{}
+
+	var firstErr error
+	for _, path := range paths {
+		file, err := readKubeconfigFile(path)
+		if err != nil {
+			if kerr, ok := err.(*KubeconfigError); ok && kerr.Kind == kubeconfigErrNotFound {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			return nil, err
+		}
+		set.info.Files = append(set.info.Files, path)
+
+		if set.info.CurrentContext == "" {
+			set.info.CurrentContext = file.CurrentContext
+		}
+		for _, c := range file.Contexts {
+			if contexts[c.Name] {
+				continue
+			}
+			contexts[c.Name] = true
+			set.info.Contexts = append(set.info.Contexts, KubeContext{
+				Name:      c.Name,
+				Cluster:   c.Context.Cluster,
+				User:      c.Context.User,
+				Namespace: c.Context.Namespace,
+				File:      path,
+			})
+		}
+		for _, c := range file.Clusters {
+			if _, ok := set.clusters[c.Name]; ok {
+				continue
+			}
+			detail := kubeClusterDetail{
+				KubeCluster: KubeCluster{
+					Name:                  c.Name,
+					Server:                c.Cluster.Server,
+					InsecureSkipTLSVerify: c.Cluster.InsecureSkipTLSVerify,
+					HasCertificateAuth:    c.Cluster.CertificateAuthority != "" || c.Cluster.CertificateAuthorityData != "",
+				},
+				caData: c.Cluster.CertificateAuthorityData,
+			}
+			if ca := c.Cluster.CertificateAuthority; ca != "" {
+				if !filepath.IsAbs(ca) {
+					ca = filepath.Join(filepath.Dir(path), ca)
+				}
+				detail.caFile = ca
+			}
+			set.clusters[c.Name] = detail
+			set.info.Clusters = append(set.info.Clusters, detail.KubeCluster)
+		}
+		for _, u := range file.Users {
+			if users[u.Name] {
+				continue
+			}
+			users[u.Name] = true
+			user := KubeUser{Name: u.Name, AuthType: "none"}
+			switch {
+			case u.User.Exec != nil:
+				user.AuthType = "exec"
+				user.ExecCommand = u.User.Exec.Command
+			case u.User.AuthProvider != nil:
+				user.AuthType = "auth-provider"
+			case u.User.Token != "" || u.User.TokenFile != "":
+				user.AuthType = "token"
+			case u.User.ClientCertificate != "" || u.User.ClientCertificateData != "":
+				user.AuthType = "client-certificate"
+			case u.User.Username != "":
+				user.AuthType = "basic"
+			}
+			set.info.Users = append(set.info.Users, user)
+		}
+	}
+
+	if len(set.info.Files) == 0 {
+		return nil, firstErr
+	}
+	// The context pinned for the environment overrides the current-context of the files
+	if env.kubeContext != "" {
+		set.info.CurrentContext = env.kubeContext
+	}
+	for i := range set.info.Contexts {
+		set.info.Contexts[i].Current = set.info.Contexts[i].Name == set.info.CurrentContext
+	}
+	return set, nil
+}
+
+// context returns a context of the merged kubeconfig
+func (s *kubeconfigSet) context(name string) (KubeContext, bool) {
+	for _, c := range s.info.Contexts {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return KubeContext{}, false
+}
+
+// GetKubeconfigInfo lists the contexts, clusters and users of the kubeconfig of the active environment
+func (a *App) GetKubeconfigInfo() (*KubeconfigInfo, error) {
+	set, err := loadKubeconfig(a.currentEnv())
+	if err != nil {
+		return nil, err
+	}
+	return &set.info, nil
+}
+
+// mapSliceValue returns the value of a key of a YAML mapping
+func mapSliceValue(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setMapSliceValue sets a key of a YAML mapping, keeping the order of the existing keys
+func setMapSliceValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+// lockKubeconfigFile takes the lock kubectl uses for a kubeconfig file: <file>.lock created exclusively and
+// removed on release, waiting up to stateLockTimeout for a kubectl or another instance holding it
+func lockKubeconfigFile(path string) (func(), error) {
+	lockPath := path + stateLockSuffix
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL, 0)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, &KubeconfigError{Kind: kubeconfigErrWrite, Path: path, Message: fmt.Sprintf("failed to lock: %v", err)}
+		}
+		if time.Now().After(deadline) {
+			return nil, &KubeconfigError{Kind: kubeconfigErrWrite, Path: path, Message: fmt.Sprintf("locked by another process, remove %s if none is running", lockPath)}
+		}
+		time.Sleep(stateLockRetry)
+	}
+}
+
+// updateKubeconfigFile rewrites a kubeconfig file through fn under its kubectl lock, replacing it atomically so
+// kubectl never reads a truncated file. Keys the GUI does not know are kept, comments are dropped like kubectl does.
+func updateKubeconfigFile(path string, fn func(doc yaml.MapSlice) (yaml.MapSlice, error)) error {
+	// k8senv kubeconfigs may be symlinks, the rename must replace their target
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return &KubeconfigError{Kind: kubeconfigErrNotFound, Path: path, Message: err.Error()}
+	}
+
+	unlock, err := lockKubeconfigFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &KubeconfigError{Kind: kubeconfigErrNotFound, Path: path, Message: err.Error()}
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return &KubeconfigError{Kind: kubeconfigErrParse, Path: path, Message: err.Error()}
+	}
+	doc, err = fn(doc)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return &KubeconfigError{Kind: kubeconfigErrWrite, Path: path, Message: err.Error()}
+	}
+	if err := writeFileAtomic(path, out, info.Mode().Perm()); err != nil {
+		return &KubeconfigError{Kind: kubeconfigErrWrite, Path: path, Message: err.Error()}
+	}
+	return nil
+}
+
+// pinKubeContext returns a copy of env pinned to a context of its kubeconfig. The kubeconfig files are shared
+// with kubectl and the other environments, so their current-context is left alone.
+func pinKubeContext(env *envContext, name string) (*envContext, error) {
+	set, err := loadKubeconfig(env)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := set.context(name); !ok {
+		return nil, &KubeconfigError{Kind: kubeconfigErrMissingContext, Path: strings.Join(set.info.Files, string(filepath.ListSeparator)), Context: name, Message: "context is not defined"}
+	}
+	return env.withKubeContext(name), nil
+}
+
+// withProfileKubeContext replaces the pinned context, which belongs to the previous kubeconfig, by the context
+// named after AWS_PROFILE when the kubeconfig defines one
+func withProfileKubeContext(env *envContext) *envContext {
+	env = env.withKubeContext("")
+	if pinned, err := pinKubeContext(env, env.get("AWS_PROFILE")); err == nil {
+		return pinned
+	}
+	return env
+}
+
+// SetKubeContext pins a context of the kubeconfig of the active environment
+func (a *App) SetKubeContext(name string) error {
+	if name == "" {
+		return fmt.Errorf("context name cannot be empty")
+	}
+	var pinErr error
+	a.updateEnv(func(env *envContext) *envContext {
+		pinned, err := pinKubeContext(env, name)
+		if pinErr = err; err != nil {
+			return env
+		}
+		return pinned
+	})
+	return pinErr
+}
+
+// SetKubeNamespace sets the default namespace of a context, in the file defining it.
+// An empty context name selects the current context.
+func (a *App) SetKubeNamespace(contextName, namespace string) error {
+	set, err := loadKubeconfig(a.currentEnv())
+	if err != nil {
+		return err
+	}
+	if contextName == "" {
+		contextName = set.info.CurrentContext
+	}
+	kubeContext, ok := set.context(contextName)
+	if !ok {
+		return &KubeconfigError{Kind: kubeconfigErrMissingContext, Context: contextName, Message: "context is not defined"}
+	}
+
+	return updateKubeconfigFile(kubeContext.File, func(doc yaml.MapSlice) (yaml.MapSlice, error) {
+		value, _ := mapSliceValue(doc, "contexts")
+		contexts, _ := value.([]interface{})
+		for _, entry := range contexts {
+			item, ok := entry.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			if name, _ := mapSliceValue(item, "name"); name != contextName {
+				continue
+			}
+			for i, field := range item {
+				if field.Key != "context" {
+					continue
+				}
+				body, _ := field.Value.(yaml.MapSlice)
+				if namespace == "" {
+					var kept yaml.MapSlice
+					for _, f := range body {
+						if f.Key != "namespace" {
+							kept = append(kept, f)
+						}
+					}
+					body = kept
+				} else {
+					body = setMapSliceValue(body, "namespace", namespace)
+				}
+				item[i].Value = body
+			}
+			return doc, nil
+		}
+		return nil, &KubeconfigError{Kind: kubeconfigErrMissingContext, Path: kubeContext.File, Context: contextName, Message: "context is not defined"}
+	})
+}
+
+// kubeHTTPClient builds a client trusting the certificate authority of a cluster
+func kubeHTTPClient(cluster kubeClusterDetail, contextName string) (*http.Client, *KubeconfigError) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cluster.InsecureSkipTLSVerify}
+
+	var caPEM []byte
+	switch {
+	case cluster.caData != "":
+		data, err := base64.StdEncoding.DecodeString(cluster.caData)
+		if err != nil {
+			return nil, &KubeconfigError{Kind: kubeconfigErrInvalidTLS, Context: contextName, Message: "certificate-authority-data is not valid base64"}
+		}
+		caPEM = data
+	case cluster.caFile != "":
+		data, err := os.ReadFile(cluster.caFile)
+		if err != nil {
+			return nil, &KubeconfigError{Kind: kubeconfigErrInvalidTLS, Path: cluster.caFile, Context: contextName, Message: err.Error()}
+		}
+		caPEM = data
+	}
+	if caPEM != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, &KubeconfigError{Kind: kubeconfigErrInvalidTLS, Context: contextName, Message: "certificate authority contains no PEM certificate"}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Timeout:   kubeConnectivityTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}, nil
+}
+
+// CheckKubeConnectivity requests /version from the cluster of a context of the active environment.
+// An empty context name checks the current context. Connection failures are reported in the result.
+func (a *App) CheckKubeConnectivity(contextName string) (*KubeConnectivityResult, error) {
+	set, err := loadKubeconfig(a.currentEnv())
+	if err != nil {
+		return nil, err
+	}
+	if contextName == "" {
+		contextName = set.info.CurrentContext
+	}
+	kubeContext, ok := set.context(contextName)
+	if !ok {
+		return nil, &KubeconfigError{Kind: kubeconfigErrMissingContext, Context: contextName, Message: "context is not defined"}
+	}
+	cluster, ok := set.clusters[kubeContext.Cluster]
+	if !ok || cluster.Server == "" {
+		return nil, &KubeconfigError{Kind: kubeconfigErrMissingCluster, Path: kubeContext.File, Context: contextName, Message: fmt.Sprintf("cluster %s is not defined or has no server", kubeContext.Cluster)}
+	}
+
+	result := &KubeConnectivityResult{Context: contextName, Server: cluster.Server}
+	client, kerr := kubeHTTPClient(cluster, contextName)
+	if kerr != nil {
+		result.Error = kerr
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kubeConnectivityTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cluster.Server, "/")+"/version", nil)
+	if err != nil {
+		result.Error = &KubeconfigError{Kind: kubeconfigErrMissingCluster, Context: contextName, Message: fmt.Sprintf("invalid server URL: %v", err)}
+		return result, nil
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = &KubeconfigError{Kind: kubeconfigErrConnectivity, Context: contextName, Message: err.Error()}
+		return result, nil
+	}
+	defer resp.Body.Close()
+
+	// The API server answered, even a 401/403 proves it is reachable
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		result.Error = &KubeconfigError{Kind: kubeconfigErrInvalidResponse, Context: contextName, Message: fmt.Sprintf("/version returned HTTP %d", resp.StatusCode)}
+		return result, nil
+	}
+
+	var version struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		result.Error = &KubeconfigError{Kind: kubeconfigErrInvalidResponse, Context: contextName, Message: fmt.Sprintf("failed to parse /version: %v", err)}
+		return result, nil
+	}
+	result.Version = version.GitVersion
+	return result, nil
+}
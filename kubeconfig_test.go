@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+preferences: {}
+current-context: staging
+clusters:
+- name: staging
+  cluster:
+    server: SERVER
+    insecure-skip-tls-verify: true
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: staging
+    namespace: default
+- name: prod
+  context:
+    cluster: prod
+    user: staging
+users:
+- name: staging
+  user:
+    exec:
+      command: aws
+`
+
+// writeTestKubeconfig writes a kubeconfig pointing the staging cluster at server
+func writeTestKubeconfig(t *testing.T, server string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(testKubeconfig, "SERVER", server, 1)), 0600))
+	return path
+}
+
+// TestKubeconfigContexts verifies contexts are listed and pinned without kubectl or rewriting current-context
+func TestKubeconfigContexts(t *testing.T) {
+	path := writeTestKubeconfig(t, "https://staging.example.com")
+	extra := filepath.Join(t.TempDir(), "extra")
+	require.NoError(t, os.WriteFile(extra, []byte("contexts:\n- name: prod\n  context:\n    cluster: shadowed\n- name: dev\n  context:\n    cluster: dev\n"), 0600))
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"KUBECONFIG": path + string(filepath.ListSeparator) + extra}))
+
+	info, err := app.GetKubeconfigInfo()
+	require.NoError(t, err)
+	require.Len(t, info.Contexts, 3)
+	assert.Equal(t, "staging", info.CurrentContext)
+	assert.True(t, info.Contexts[0].Current)
+	assert.Equal(t, "prod", info.Contexts[1].Cluster, "the first file defining a context wins")
+	assert.Equal(t, "exec", info.Users[0].AuthType)
+
+	require.NoError(t, app.SetKubeContext("dev"))
+	require.NoError(t, app.SetKubeNamespace("prod", "monitoring"))
+	info, err = app.GetKubeconfigInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "dev", info.CurrentContext)
+	assert.Equal(t, "monitoring", info.Contexts[1].Namespace)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "preferences: {}")
+	assert.Contains(t, string(data), "current-context: staging", "the shared file keeps its current-context")
+	assert.NoFileExists(t, path+".lock")
+
+	cmd := app.currentEnv().command(context.Background(), "kubectl", "get", "pods")
+	assert.Equal(t, []string{"--context", "dev", "get", "pods"}, cmd.Args[1:])
+
+	var kerr *KubeconfigError
+	require.True(t, errors.As(app.SetKubeContext("missing"), &kerr))
+	assert.Equal(t, kubeconfigErrMissingContext, kerr.Kind)
+	assert.Equal(t, "dev", app.currentEnv().kubeContext, "a failed selection keeps the pinned context")
+}
+
+// TestUpdateKubeconfigFileLock verifies a write waits for kubectl's <file>.lock and releases it
+func TestUpdateKubeconfigFileLock(t *testing.T) {
+	path := writeTestKubeconfig(t, "https://staging.example.com")
+	require.NoError(t, os.WriteFile(path+".lock", nil, 0600))
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove(path + ".lock")
+	}()
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"KUBECONFIG": path}))
+	require.NoError(t, app.SetKubeNamespace("staging", "monitoring"))
+	assert.NoFileExists(t, path+".lock")
+
+	info, err := app.GetKubeconfigInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "monitoring", info.Contexts[0].Namespace)
+}
+
+// TestSetAWSProfileKubeconfig verifies the k8senv kubeconfig is selected and a missing one is a structured error
+func TestSetAWSProfileKubeconfig(t *testing.T) {
+	tfinfra := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Dir(k8senvKubeconfigPath(tfinfra, "staging")), 0755))
+	require.NoError(t, os.WriteFile(k8senvKubeconfigPath(tfinfra, "staging"), []byte(strings.Replace(testKubeconfig, "current-context: staging", "current-context: prod", 1)), 0600))
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"TFINFRA_REPOSITORY_PATH": tfinfra}))
+
+	require.NoError(t, app.SetAWSProfile("staging"))
+	info, err := app.GetKubeconfigInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "staging", info.CurrentContext)
+	data, err := os.ReadFile(k8senvKubeconfigPath(tfinfra, "staging"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "current-context: prod")
+
+	var kerr *KubeconfigError
+	require.True(t, errors.As(app.SetAWSProfile("prod"), &kerr))
+	assert.Equal(t, kubeconfigErrNotFound, kerr.Kind)
+	assert.Equal(t, "prod", app.GetCurrentAWSProfile())
+	assert.Empty(t, app.GetKubeconfig(), "the staging kubeconfig must not stay selected for prod")
+	assert.Empty(t, app.currentEnv().kubeContext)
+
+	// Without terraform-infra the context pinned for the previous profile is dropped as well
+	require.NoError(t, app.SetAWSProfile("staging"))
+	assert.Equal(t, "staging", app.currentEnv().kubeContext)
+	app.env.Store(app.currentEnv().with(map[string]string{"TFINFRA_REPOSITORY_PATH": ""}))
+	require.NoError(t, app.SetAWSProfile("dev"))
+	assert.Equal(t, "dev", app.GetCurrentAWSProfile())
+	assert.Empty(t, app.currentEnv().kubeContext)
+}
+
+// TestCheckKubeConnectivity verifies /version is requested and failures are reported in the result
+func TestCheckKubeConnectivity(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"gitVersion": "v1.30.2-eks"}`))
+	}))
+	defer server.Close()
+
+	app := NewApp()
+	app.env.Store(newEnvContext().with(map[string]string{"KUBECONFIG": writeTestKubeconfig(t, server.URL)}))
+
+	result, err := app.CheckKubeConnectivity("")
+	require.NoError(t, err)
+	assert.True(t, result.Reachable)
+	assert.Equal(t, "v1.30.2-eks", result.Version)
+	assert.Nil(t, result.Error)
+
+	server.Close()
+	result, err = app.CheckKubeConnectivity("staging")
+	require.NoError(t, err)
+	assert.False(t, result.Reachable)
+	require.NotNil(t, result.Error)
+	assert.Equal(t, kubeconfigErrConnectivity, result.Error.Kind)
+
+	var kerr *KubeconfigError
+	_, err = app.CheckKubeConnectivity("prod")
+	require.True(t, errors.As(err, &kerr))
+	assert.Equal(t, kubeconfigErrMissingCluster, kerr.Kind)
+}
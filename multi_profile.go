@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxProfileFanOut is the number of environment profiles queried in parallel
+const maxProfileFanOut = 4
+
+// ProfileArgoApps are the ArgoCD applications of one environment profile
+type ProfileArgoApps struct {
+	Profile string    `json:"profile"`
+	Server  string    `json:"server"`
+	Apps    []ArgoApp `json:"apps"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ProfileRollouts are the Argo Rollouts of one environment profile
+type ProfileRollouts struct {
+	Profile  string            `json:"profile"`
+	Rollouts []RolloutListItem `json:"rollouts"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// validateProfileNames rejects empty and duplicate profile names of a fan-out
+func validateProfileNames(profiles []string) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("at least one environment profile is required")
+	}
+	seen := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		if profile == "" {
+			return fmt.Errorf("environment profile name cannot be empty")
+		}
+		if seen[profile] {
+			return fmt.Errorf("environment profile '%s' is listed twice", profile)
+		}
+		seen[profile] = true
+	}
+	return nil
+}
+
+// forEachProfile calls fn for every profile with bounded concurrency and waits for all calls to return
+func forEachProfile(profiles []string, fn func(i int, profile string)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxProfileFanOut)
+	for i, profile := range profiles {
+		wg.Add(1)
+		go func(i int, profile string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fn(i, profile)
+		}(i, profile)
+	}
+	wg.Wait()
+}
+
+// GetArgoAppsAcrossProfiles queries the ArgoCD applications of several environment profiles concurrently.
+// Each profile uses the ArgoCD server of its AWS profile, a failing profile is reported in its result.
+func (a *App) GetArgoAppsAcrossProfiles(profiles []string, project string) ([]ProfileArgoApps, error) {
+	if err := validateProfileNames(profiles); err != nil {
+		return nil, err
+	}
+
+	results := make([]ProfileArgoApps, len(profiles))
+	forEachProfile(profiles, func(i int, profile string) {
+		result := ProfileArgoApps{Profile: profile, Apps: []ArgoApp{}}
+		defer func() { results[i] = result }()
+
+		env, err := a.envForProfile(profile)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		result.Server, err = a.argoCDServerFor(env)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+
+		apps, err := a.GetArgoApps(ArgoConfig{Server: result.Server, Project: project, Profile: profile})
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		if apps != nil {
+			result.Apps = apps
+		}
+	})
+	return results, nil
+}
+
+// GetRolloutsAcrossProfiles queries the Argo Rollouts of several environment profiles concurrently.
+// An empty namespace lists the rollouts of all namespaces, a failing profile is reported in its result.
+func (a *App) GetRolloutsAcrossProfiles(profiles []string, namespace string) ([]ProfileRollouts, error) {
+	if err := validateProfileNames(profiles); err != nil {
+		return nil, err
+	}
+
+	results := make([]ProfileRollouts, len(profiles))
+	forEachProfile(profiles, func(i int, profile string) {
+		result := ProfileRollouts{Profile: profile, Rollouts: []RolloutListItem{}}
+		rollouts, err := a.GetRollouts(KubernetesConfig{Namespace: namespace, Profile: profile})
+		if err != nil {
+			result.Error = err.Error()
+		} else if rollouts != nil {
+			result.Rollouts = rollouts
+		}
+		results[i] = result
+	})
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeYakScript answers argocd status and rollouts list with names derived from the environment of the call
+const fakeYakScript = `#!/bin/sh
+case "$1" in
+argocd) echo "{\"app-$AWS_PROFILE\": {\"Health\": \"Healthy\", \"Sync\": \"Synced\"}}" ;;
+rollouts) echo "{\"items\": [{\"metadata\": {\"name\": \"$(basename $(dirname $KUBECONFIG))\", \"namespace\": \"web\"}}]}" ;;
+*) exit 1 ;;
+esac
+`
+
+// TestQueriesAcrossProfiles verifies each profile is queried with its own environment and tagged in the results
+func TestQueriesAcrossProfiles(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "yak"), []byte(fakeYakScript), 0755))
+	tfinfra := t.TempDir()
+	for _, profile := range []string{"staging-sso", "prod-sso"} {
+		dir := filepath.Join(tfinfra, "setup", "k8senv", profile)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config"), nil, 0644))
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installSiteConfig(t, home, doctolibSiteConfig)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TFINFRA_REPOSITORY_PATH", tfinfra)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("KUBECONFIG", "")
+
+	app := NewApp()
+	for name, awsProfile := range map[string]string{"staging": "staging-sso", "production": "prod-sso"} {
+		app.env.Store(newEnvContext().with(map[string]string{"AWS_PROFILE": awsProfile}))
+		require.NoError(t, app.SaveEnvironmentProfile(name))
+	}
+	app.env.Store(newEnvContext())
+
+	apps, err := app.GetArgoAppsAcrossProfiles([]string{"staging", "production", "missing"}, "")
+	require.NoError(t, err)
+	require.Len(t, apps, 3)
+	assert.Equal(t, "staging", apps[0].Profile)
+	assert.Equal(t, "argocd-staging.doctolib.net", apps[0].Server)
+	require.Len(t, apps[0].Apps, 1)
+	assert.Equal(t, "app-staging-sso", apps[0].Apps[0].AppName)
+	assert.Equal(t, "argocd-prod.doctolib.net", apps[1].Server)
+	require.Len(t, apps[1].Apps, 1)
+	assert.Equal(t, "app-prod-sso", apps[1].Apps[0].AppName)
+	assert.Contains(t, apps[2].Error, "not found")
+
+	rollouts, err := app.GetRolloutsAcrossProfiles([]string{"production", "staging"}, "web")
+	require.NoError(t, err)
+	require.Len(t, rollouts, 2)
+	require.Len(t, rollouts[0].Rollouts, 1)
+	assert.Equal(t, "prod-sso", rollouts[0].Rollouts[0].Name, "the kubeconfig of the profile is used")
+	assert.Equal(t, "staging-sso", rollouts[1].Rollouts[0].Name)
+
+	// Querying other profiles leaves the active environment alone
+	assert.Empty(t, app.GetActiveEnvironmentProfile())
+
+	_, err = app.GetRolloutsAcrossProfiles([]string{"staging", "staging"}, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	notificationSettingsFile = "notifications.json"
+	notificationHistoryFile  = "notification-history.json"
+	notificationTemplateName = "certificate-notification.tmpl"
+
+	// smtpPasswordSecretName is the secret store entry holding the SMTP password
+	smtpPasswordSecretName = "notifications:smtp-password"
+
+	// maxNotificationHistory bounds the history file so it does not grow forever
+	maxNotificationHistory = 500
+)
+
+// defaultCertificateNotificationTemplate is written to ~/.yak-gui/templates on first use.
+// The first line must be a "Subject:" header, the rest of the file is the message body.
+const defaultCertificateNotificationTemplate = `Subject: SSL Certificate {{.Operation}} - {{.Certificate}} Scheduled for {{.Date}}
+
+Dear Technical Services Team,
+
+This is to inform you that we will be performing an SSL certificate {{.Operation}} operation:
+
+Certificate: {{.Certificate}}
+Operation: {{.Operation}}
+Scheduled Date: {{.Date}}
+
+Please be aware that some customers may need to manually download our certificate and upload it to their trust store.
+
+Best regards
+`
+
+// NotificationSettings represents the configured notification transport and recipients
+type NotificationSettings struct {
+	Transport  string            `json:"transport"` // smtp, webhook, slack (empty disables sending)
+	Recipients []string          `json:"recipients"`
+	SMTP       SMTPSettings      `json:"smtp"`
+	WebhookURL string            `json:"webhookUrl,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// SMTPSettings represents the SMTP server used by the smtp transport
+type SMTPSettings struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"` // kept in the secret store, never returned to the frontend
+	From     string `json:"from"`
+}
+
+// NotificationTemplate represents the editable certificate notification template
+type NotificationTemplate struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// NotificationRecord represents a single recorded notification send
+type NotificationRecord struct {
+	SentAt      time.Time `json:"sentAt"`
+	Transport   string    `json:"transport"`
+	Recipients  []string  `json:"recipients"`
+	Subject     string    `json:"subject"`
+	Certificate string    `json:"certificate"`
+	Operation   string    `json:"operation"`
+	Date        string    `json:"date"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// notificationMessage is a rendered notification ready to be handed to a transport
+type notificationMessage struct {
+	Subject     string
+	Body        string
+	Recipients  []string
+	Certificate string
+	Operation   string
+	Date        string
+}
+
+// notificationTransport delivers a rendered notification
+type notificationTransport interface {
+	Send(ctx context.Context, msg notificationMessage) error
+}
+
+// defaultNotificationSettings returns the settings used when none have been saved
+func (a *App) defaultNotificationSettings() NotificationSettings {
+	site, err := a.siteConfig()
+	if err != nil {
+		fmt.Printf("Warning: using built-in notification recipients: %v\n", err)
+		site = builtinSiteConfig()
+	}
+
+	return NotificationSettings{
+		Recipients: site.Notifications.Recipients,
+		SMTP: SMTPSettings{
+			Port: 587,
+		},
+	}
+}
+
+// loadNotificationSettings returns the saved notification settings with the SMTP password from the secret store.
+// A password left in clear by a previous version is moved to the secret store.
+func (a *App) loadNotificationSettings() (NotificationSettings, error) {
+	settings := a.defaultNotificationSettings()
+	if _, err := loadStateFile(notificationSettingsFile, &settings); err != nil {
+		return settings, err
+	}
+
+	if settings.SMTP.Password != "" {
+		if err := setSecret(smtpPasswordSecretName, settings.SMTP.Password); err != nil {
+			return settings, err
+		}
+		stored := settings
+		stored.SMTP.Password = ""
+		if err := saveStateFile(notificationSettingsFile, stored); err != nil {
+			return settings, err
+		}
+		return settings, nil
+	}
+
+	password, _, err := getSecret(smtpPasswordSecretName)
+	if err != nil {
+		return settings, err
+	}
+	settings.SMTP.Password = password
+	return settings, nil
+}
+
+// GetNotificationSettings returns the saved notification settings, the SMTP password is left out
+func (a *App) GetNotificationSettings() (NotificationSettings, error) {
+	settings, err := a.loadNotificationSettings()
+	settings.SMTP.Password = ""
+	return settings, err
+}
+
+// SaveNotificationSettings validates and saves the notification settings.
+// A non-empty SMTP password is stored encrypted, an empty password keeps the stored one.
+func (a *App) SaveNotificationSettings(settings NotificationSettings) error {
+	if _, err := newNotificationTransport(settings); err != nil {
+		return err
+	}
+	if settings.SMTP.Password != "" {
+		if err := setSecret(smtpPasswordSecretName, settings.SMTP.Password); err != nil {
+			return err
+		}
+		settings.SMTP.Password = ""
+	}
+	return saveStateFile(notificationSettingsFile, settings)
+}
+
+// notificationTemplatePath returns the path of the certificate notification template
+func notificationTemplatePath() (string, error) {
+	dir, err := yakGuiDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "templates", notificationTemplateName), nil
+}
+
+// GetNotificationTemplate returns the certificate notification template, creating the default one if missing
+func (a *App) GetNotificationTemplate() (*NotificationTemplate, error) {
+	path, err := notificationTemplatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read notification template: %v", err)
+		}
+		if err := writeNotificationTemplate(path, defaultCertificateNotificationTemplate); err != nil {
+			return nil, err
+		}
+		data = []byte(defaultCertificateNotificationTemplate)
+	}
+
+	return &NotificationTemplate{Path: path, Content: string(data)}, nil
+}
+
+// SaveNotificationTemplate validates and saves the certificate notification template
+func (a *App) SaveNotificationTemplate(content string) error {
+	if _, err := renderNotification(content, "example-certificate", "2024-01-01", "renewal"); err != nil {
+		return err
+	}
+
+	path, err := notificationTemplatePath()
+	if err != nil {
+		return err
+	}
+	return writeNotificationTemplate(path, content)
+}
+
+// ResetNotificationTemplate restores the default certificate notification template
+func (a *App) ResetNotificationTemplate() error {
+	return a.SaveNotificationTemplate(defaultCertificateNotificationTemplate)
+}
+
+// writeNotificationTemplate atomically replaces the notification template, keeping the previous one as backup
+func writeNotificationTemplate(path, content string) error {
+	unlock, err := lockStateFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := writeStateData(path, []byte(content), 0644, nil); err != nil {
+		return fmt.Errorf("failed to write notification template: %v", err)
+	}
+	return nil
+}
+
+// renderNotification executes the template and splits it into subject and body
+func renderNotification(content, certificateName, operationDate, operation string) (notificationMessage, error) {
+	tmpl, err := template.New(notificationTemplateName).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return notificationMessage{}, fmt.Errorf("invalid notification template: %w", err)
+	}
+
+	data := map[string]string{
+		"Certificate": certificateName,
+		"Operation":   operation,
+		"Date":        operationDate,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return notificationMessage{}, fmt.Errorf("failed to render notification template: %w", err)
+	}
+
+	rendered := strings.TrimLeft(buf.String(), "\r\n")
+	firstLine, body, _ := strings.Cut(rendered, "\n")
+	if !strings.HasPrefix(firstLine, "Subject:") {
+		return notificationMessage{}, fmt.Errorf("invalid notification template: first line must be a Subject: header")
+	}
+
+	return notificationMessage{
+		Subject:     strings.TrimSpace(strings.TrimPrefix(firstLine, "Subject:")),
+		Body:        strings.TrimLeft(body, "\r\n"),
+		Certificate: certificateName,
+		Operation:   operation,
+		Date:        operationDate,
+	}, nil
+}
+
+// newNotificationTransport builds the transport selected in the settings
+func newNotificationTransport(settings NotificationSettings) (notificationTransport, error) {
+	switch settings.Transport {
+	case "":
+		return nil, nil
+	case "smtp":
+		if settings.SMTP.Host == "" || settings.SMTP.Port == 0 {
+			return nil, fmt.Errorf("SMTP host and port are required")
+		}
+		if settings.SMTP.From == "" {
+			return nil, fmt.Errorf("SMTP sender address is required")
+		}
+		if len(settings.Recipients) == 0 {
+			return nil, fmt.Errorf("at least one recipient is required for SMTP notifications: %w", &SiteNotConfiguredError{Setting: "notifications.recipients"})
+		}
+		return &smtpTransport{settings: settings.SMTP}, nil
+	case "webhook":
+		if settings.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook URL is required")
+		}
+		return &webhookTransport{url: settings.WebhookURL, headers: settings.Headers}, nil
+	case "slack":
+		if settings.WebhookURL == "" {
+			return nil, fmt.Errorf("Slack webhook URL is required")
+		}
+		return &slackTransport{url: settings.WebhookURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification transport %q", settings.Transport)
+	}
+}
+
+// smtpTransport sends notifications as plain-text email
+type smtpTransport struct {
+	settings SMTPSettings
+}
+
+func (t *smtpTransport) Send(ctx context.Context, msg notificationMessage) error {
+	addr := fmt.Sprintf("%s:%d", t.settings.Host, t.settings.Port)
+
+	var auth smtp.Auth
+	if t.settings.Username != "" {
+		auth = smtp.PlainAuth("", t.settings.Username, t.settings.Password, t.settings.Host)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", t.settings.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.Recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	// net/smtp has no context support, so run it in the background and honor the deadline
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, t.settings.From, msg.Recipients, buf.Bytes())
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email via %s: %w", addr, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sending email via %s timed out", addr)
+	}
+}
+
+// webhookTransport posts the notification as a generic JSON document
+type webhookTransport struct {
+	url     string
+	headers map[string]string
+}
+
+func (t *webhookTransport) Send(ctx context.Context, msg notificationMessage) error {
+	payload := map[string]interface{}{
+		"subject":     msg.Subject,
+		"body":        msg.Body,
+		"recipients":  msg.Recipients,
+		"certificate": msg.Certificate,
+		"operation":   msg.Operation,
+		"date":        msg.Date,
+	}
+	return postJSON(ctx, t.url, t.headers, payload)
+}
+
+// slackTransport posts the notification using the Slack incoming webhook format
+type slackTransport struct {
+	url string
+}
+
+func (t *slackTransport) Send(ctx context.Context, msg notificationMessage) error {
+	payload := map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Body),
+	}
+	return postJSON(ctx, t.url, nil, payload)
+}
+
+// postJSON sends payload as a JSON POST request and fails on non-2xx responses
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// GetNotificationHistory returns recorded notification sends, most recent first
+func (a *App) GetNotificationHistory() ([]NotificationRecord, error) {
+	var history []NotificationRecord
+	if _, err := loadStateFile(notificationHistoryFile, &history); err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []NotificationRecord{}
+	}
+	return history, nil
+}
+
+// recordNotification prepends a record to the notification history
+func recordNotification(record NotificationRecord) error {
+	var history []NotificationRecord
+	err := updateStateFile(notificationHistoryFile, &history, func() error {
+		history = append([]NotificationRecord{record}, history...)
+		if len(history) > maxNotificationHistory {
+			history = history[:maxNotificationHistory]
+		}
+		return nil
+	})
+
+	// A corrupt history file should not block notifications, start a new one
+	var corrupt *StateFileCorruptError
+	if errors.As(err, &corrupt) {
+		fmt.Printf("Warning: resetting notification history: %v\n", err)
+		return saveStateFile(notificationHistoryFile, []NotificationRecord{record})
+	}
+	return err
+}
+
+// SendCertificateNotification renders the notification template and sends it through the configured transport
+func (a *App) SendCertificateNotification(certificateName, operationDate, operation string) (*CertificateOperation, error) {
+	settings, err := a.loadNotificationSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := a.GetNotificationTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := renderNotification(tmpl.Content, certificateName, operationDate, operation)
+	if err != nil {
+		return nil, err
+	}
+	msg.Recipients = settings.Recipients
+
+	preview := fmt.Sprintf("To: %s\nSubject: %s\n\n%s", strings.Join(msg.Recipients, ", "), msg.Subject, msg.Body)
+
+	transport, err := newNotificationTransport(settings)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		return &CertificateOperation{
+			Success: false,
+			Message: "No notification transport configured, notification was not sent",
+			Output:  preview,
+		}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sendErr := transport.Send(ctx, msg)
+
+	record := NotificationRecord{
+		SentAt:      time.Now(),
+		Transport:   settings.Transport,
+		Recipients:  msg.Recipients,
+		Subject:     msg.Subject,
+		Certificate: certificateName,
+		Operation:   operation,
+		Date:        operationDate,
+		Success:     sendErr == nil,
+	}
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+	if err := recordNotification(record); err != nil {
+		fmt.Printf("Warning: failed to record notification: %v\n", err)
+	}
+
+	if sendErr != nil {
+		return &CertificateOperation{
+			Success: false,
+			Message: fmt.Sprintf("Failed to send %s notification for certificate %s", settings.Transport, certificateName),
+			Output:  sendErr.Error(),
+		}, nil
+	}
+
+	return &CertificateOperation{
+		Success: true,
+		Message: fmt.Sprintf("Notification for certificate %s sent via %s", certificateName, settings.Transport),
+		Output:  preview,
+	}, nil
+}
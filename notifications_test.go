@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSMTPServer is a minimal SMTP server that records delivered messages
+type testSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []testSMTPMessage
+}
+
+type testSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+func newTestSMTPServer(t *testing.T) *testSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &testSMTPServer{listener: listener}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *testSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var msg testSMTPMessage
+	reply("220 localhost test SMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = testSMTPMessage{}
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *testSMTPServer) received() []testSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testSMTPMessage(nil), s.messages...)
+}
+
+// TestSendCertificateNotificationSMTP verifies delivery through a local SMTP server
+func TestSendCertificateNotificationSMTP(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := newTestSMTPServer(t)
+	app := NewApp()
+
+	err := app.SaveNotificationSettings(NotificationSettings{
+		Transport:  "smtp",
+		Recipients: []string{"ops@example.com", "support@example.com"},
+		SMTP: SMTPSettings{
+			Host: "127.0.0.1",
+			Port: server.port(),
+			From: "yak-gui@example.com",
+		},
+	})
+	require.NoError(t, err)
+
+	result, err := app.SendCertificateNotification("wildcard-example.com", "2024-03-01", "renewal")
+	require.NoError(t, err)
+	assert.True(t, result.Success, result.Output)
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "yak-gui@example.com", messages[0].From)
+	assert.Equal(t, []string{"ops@example.com", "support@example.com"}, messages[0].To)
+	assert.Contains(t, messages[0].Data, "Subject: SSL Certificate renewal - wildcard-example.com Scheduled for 2024-03-01")
+	assert.Contains(t, messages[0].Data, "Certificate: wildcard-example.com")
+
+	history, err := app.GetNotificationHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Success)
+	assert.Equal(t, "smtp", history[0].Transport)
+}
+
+// TestSMTPPasswordInSecretStore verifies the SMTP password is stored encrypted and never returned to the frontend
+func TestSMTPPasswordInSecretStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	settings := NotificationSettings{
+		Transport:  "smtp",
+		Recipients: []string{"ops@example.com"},
+		SMTP:       SMTPSettings{Host: "smtp.example.com", Port: 587, Username: "yak", Password: "smtp-secret", From: "yak-gui@example.com"},
+	}
+	require.NoError(t, app.SaveNotificationSettings(settings))
+
+	path, err := statePath(notificationSettingsFile)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "smtp-secret")
+
+	returned, err := app.GetNotificationSettings()
+	require.NoError(t, err)
+	assert.Empty(t, returned.SMTP.Password)
+	assert.Equal(t, "yak", returned.SMTP.Username)
+
+	// Saving the settings returned to the frontend keeps the stored password
+	returned.SMTP.Host = "smtp2.example.com"
+	require.NoError(t, app.SaveNotificationSettings(returned))
+	loaded, err := app.loadNotificationSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "smtp2.example.com", loaded.SMTP.Host)
+	assert.Equal(t, "smtp-secret", loaded.SMTP.Password)
+
+	// A password saved in clear by a previous version moves to the secret store
+	settings.SMTP.Password = "legacy-secret"
+	require.NoError(t, saveStateFile(notificationSettingsFile, settings))
+	loaded, err = app.loadNotificationSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-secret", loaded.SMTP.Password)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "legacy-secret")
+	password, _, err := getSecret(smtpPasswordSecretName)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-secret", password)
+}
+
+// TestSendCertificateNotificationSlack verifies the Slack payload and custom templates
+func TestSendCertificateNotificationSlack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	require.NoError(t, app.SaveNotificationTemplate("Subject: {{.Certificate}} {{.Operation}}\n\nOn {{.Date}}\n"))
+	require.NoError(t, app.SaveNotificationSettings(NotificationSettings{Transport: "slack", WebhookURL: server.URL}))
+
+	result, err := app.SendCertificateNotification("api-example.net", "2024-05-02", "revocation")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "*api-example.net revocation*\nOn 2024-05-02\n", payload["text"])
+}
+
+// TestSendCertificateNotificationFailureIsRecorded verifies failed sends end up in the history
+func TestSendCertificateNotificationFailureIsRecorded(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	require.NoError(t, app.SaveNotificationSettings(NotificationSettings{Transport: "webhook", WebhookURL: server.URL}))
+
+	result, err := app.SendCertificateNotification("api-example.net", "2024-05-02", "renewal")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Output, strconv.Itoa(http.StatusInternalServerError))
+
+	history, err := app.GetNotificationHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.False(t, history[0].Success)
+	assert.NotEmpty(t, history[0].Error)
+}
+
+// TestNotificationTemplateValidation verifies that templates without a subject are rejected
+func TestNotificationTemplateValidation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	assert.Error(t, app.SaveNotificationTemplate("Hello {{.Certificate}}"))
+	assert.Error(t, app.SaveNotificationTemplate("Subject: {{.Unknown}}\n\nbody"))
+	assert.Error(t, app.SaveNotificationSettings(NotificationSettings{Transport: "pigeon"}))
+
+	tmpl, err := app.GetNotificationTemplate()
+	require.NoError(t, err)
+	assert.Equal(t, defaultCertificateNotificationTemplate, tmpl.Content)
+}
@@ -42,6 +42,7 @@ type RolloutListItem struct {
 type KubernetesConfig struct {
 	Server    string `json:"server"`
 	Namespace string `json:"namespace"`
+	Profile   string `json:"profile,omitempty"` // environment profile to query, the active one when empty
 }
 
 // GetRollouts gets all rollouts using the yak CLI
@@ -58,12 +59,14 @@ func (a *App) GetRollouts(config KubernetesConfig) ([]RolloutListItem, error) {
 	}
 
 	// Execute yak rollouts list --json with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak rollouts list failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -133,12 +136,14 @@ func (a *App) GetRolloutStatus(config KubernetesConfig, rolloutName string) (*Ro
 	}
 
 	// Execute yak rollouts get with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak rollouts get failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -203,9 +208,11 @@ func (a *App) PromoteRollout(config KubernetesConfig, rolloutName string, full b
 	}
 
 	// Execute yak rollouts promote
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to promote rollout %s: %w", rolloutName, err)
 	}
 
@@ -228,9 +235,11 @@ func (a *App) PauseRollout(config KubernetesConfig, rolloutName string) error {
 	}
 
 	// Execute yak rollouts pause
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to pause rollout %s: %w", rolloutName, err)
 	}
 
@@ -253,9 +262,11 @@ func (a *App) AbortRollout(config KubernetesConfig, rolloutName string) error {
 	}
 
 	// Execute yak rollouts abort
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to abort rollout %s: %w", rolloutName, err)
 	}
 
@@ -278,12 +289,14 @@ func (a *App) RestartRollout(config KubernetesConfig, rolloutName string) error
 	}
 
 	// Execute yak rollouts restart with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.CombinedOutput()
+	output, err := a.runYakCombined(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return fmt.Errorf("failed to restart rollout %s (exit code %d): %s", rolloutName, exitError.ExitCode(), string(output))
@@ -319,9 +332,11 @@ func (a *App) SetRolloutImage(config KubernetesConfig, rolloutName, image, conta
 	}
 
 	// Execute yak rollouts set-image
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to set image for rollout %s: %w", rolloutName, err)
 	}
 
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The secret store keeps tokens and passwords out of the plain JSON state files. It is obfuscation, not
+// protection: the AES key in secret.key sits next to secrets.enc with the same owner-only permissions, so
+// anyone able to read ~/.yak-gui can decrypt the store. It guards against secrets leaking through a copied
+// state file, a screen share or a grep of the home directory, not against another process of the user.
+const (
+	secretStoreKeyFile  = "secret.key"
+	secretStoreDataFile = "secrets.enc"
+)
+
+// secretStoreKey returns the AES-256 key of the local secret store, creating it on first use.
+// The caller must hold the state lock of the store, which serializes the creation of the key.
+func secretStoreKey() ([]byte, error) {
+	dir, err := yakGuiDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, secretStoreKeyFile)
+
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secret store key %s is corrupt", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secret store key: %v", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret store key: %v", err)
+	}
+	// Renamed into place, an instance reading the key never sees it partially written
+	if err := writeFileAtomic(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to create secret store key: %v", err)
+	}
+	return key, nil
+}
+
+// secretStoreCipher returns the AES-GCM cipher of the local secret store, the caller must hold its state lock
+func secretStoreCipher() (cipher.AEAD, error) {
+	key, err := secretStoreKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret store cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptSecretStore decrypts the content of the secret store file
+func decryptSecretStore(gcm cipher.AEAD, data []byte) (map[string]string, error) {
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secret store is corrupt")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret store: %v", err)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store: %v", err)
+	}
+	return secrets, nil
+}
+
+// validSecretStore returns the state file validator of the secret store, content that does not decrypt is invalid
+func validSecretStore(gcm cipher.AEAD) func([]byte) error {
+	return func(data []byte) error {
+		_, err := decryptSecretStore(gcm, data)
+		return err
+	}
+}
+
+// readSecretStore decrypts all secrets, the caller must hold the state lock of the store
+func readSecretStore(path string) (map[string]string, error) {
+	gcm, err := secretStoreCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readStateData(path, validSecretStore(gcm))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	return decryptSecretStore(gcm, data)
+}
+
+// writeSecretStore encrypts and writes all secrets, the caller must hold the state lock of the store
+func writeSecretStore(path string, secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret store: %v", err)
+	}
+
+	gcm, err := secretStoreCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	data := gcm.Seal(nonce, nonce, plaintext, nil)
+	return writeStateData(path, data, 0600, validSecretStore(gcm))
+}
+
+// getSecret returns a secret from the local secret store
+func getSecret(name string) (string, bool, error) {
+	var value string
+	var ok bool
+	err := withStateLock(secretStoreDataFile, func(path string) error {
+		secrets, err := readSecretStore(path)
+		if err != nil {
+			return err
+		}
+		value, ok = secrets[name]
+		return nil
+	})
+	return value, ok, err
+}
+
+// setSecret stores a secret in the local secret store, an empty value deletes it
+func setSecret(name, value string) error {
+	return withStateLock(secretStoreDataFile, func(path string) error {
+		secrets, err := readSecretStore(path)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			delete(secrets, name)
+		} else {
+			secrets[name] = value
+		}
+		return writeSecretStore(path, secrets)
+	})
+}
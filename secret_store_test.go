@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSecretStoreConcurrentFirstUse verifies concurrent first uses create a single key and keep every secret
+func TestSecretStoreConcurrentFirstUse(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- setSecret(fmt.Sprintf("token-%d", i), fmt.Sprintf("value-%d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, _, err := getSecret("token-0")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 8; i++ {
+		value, ok, err := getSecret(fmt.Sprintf("token-%d", i))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("value-%d", i), value)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(home, ".yak-gui"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".tmp-", "no temporary key file is left behind")
+	}
+}
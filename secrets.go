@@ -18,6 +18,7 @@ import (
 type SecretConfig struct {
 	Platform    string `json:"platform"`
 	Environment string `json:"environment"`
+	Profile     string `json:"profile,omitempty"` // environment profile to query, the active one when empty
 }
 
 // YakSecretConfig represents the structure of secret.yml
@@ -84,12 +85,14 @@ func (a *App) GetSecrets(config SecretConfig, path string) ([]SecretListItem, er
 	}
 
 	// Execute yak secret list --json with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak secret list failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -179,12 +182,14 @@ func (a *App) getSecretMetadata(config SecretConfig, secretPath string) (SecretL
 	}
 
 	// Execute yak secret metadata get with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return SecretListItem{}, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 15*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		return SecretListItem{}, fmt.Errorf("yak secret metadata get failed: %w", err)
 	}
@@ -282,12 +287,14 @@ func (a *App) GetSecretData(config SecretConfig, path string, version int) (*Sec
 
 
 	// Execute yak secret get with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	profileCtx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(profileCtx, 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	output, err := cmd.Output()
+	output, err := a.runYak(ctx, args...)
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			return nil, fmt.Errorf("yak secret get failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
@@ -401,9 +408,11 @@ func (a *App) CreateSecret(config SecretConfig, path, owner, usage, source strin
 	}
 
 	// Execute yak secret create
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to create secret %s: %w", path, err)
 	}
 
@@ -431,9 +440,11 @@ func (a *App) UpdateSecret(config SecretConfig, path string, data map[string]str
 	}
 
 	// Execute yak secret update
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to update secret %s: %w", path, err)
 	}
 
@@ -459,9 +470,11 @@ func (a *App) DeleteSecret(config SecretConfig, path string, version int) error
 	}
 
 	// Execute yak secret delete
-	cmd := exec.Command(findYakExecutable(), args...)
-	
-	if err := cmd.Run(); err != nil {
+	ctx, err := a.profileContext(config.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err := a.runYakCombined(ctx, args...); err != nil {
 		return fmt.Errorf("failed to delete secret %s: %w", path, err)
 	}
 
@@ -545,32 +558,45 @@ func parseSecretArray(secretsArray []interface{}) []SecretListItem {
 
 // LoadSecretConfig loads the secret.yml configuration file
 func (a *App) LoadSecretConfig() (*YakSecretConfig, error) {
-	// Try to find the secret.yml file
-	var configPath string
+	configPath, _ := a.findSecretConfig()
+	if configPath == "" {
+		return nil, fmt.Errorf("secret.yml not found in any expected location")
+	}
+	return readSecretConfig(configPath)
+}
+
+// findSecretConfig returns the secret.yml LoadSecretConfig reads, empty when there is none,
+// and a line per location it tried
+func (a *App) findSecretConfig() (string, []string) {
+	var candidates []string
 	
 	// First try TFINFRA_REPOSITORY_PATH/setup/yak_config/secret.yml
-	if tfinfraPath := os.Getenv("TFINFRA_REPOSITORY_PATH"); tfinfraPath != "" {
-		configPath = filepath.Join(tfinfraPath, "setup", "yak_config", "secret.yml")
-		if _, err := os.Stat(configPath); err == nil {
-			goto LoadConfig
-		}
+	if tfinfraPath := a.currentEnv().get("TFINFRA_REPOSITORY_PATH"); tfinfraPath != "" {
+		candidates = append(candidates, filepath.Join(tfinfraPath, "setup", "yak_config", "secret.yml"))
 	}
 	
 	// Then try ~/.yak/secret.yml
 	if homeDir, err := os.UserHomeDir(); err == nil {
-		configPath = filepath.Join(homeDir, ".yak", "secret.yml")
-		if _, err := os.Stat(configPath); err == nil {
-			goto LoadConfig
-		}
+		candidates = append(candidates, filepath.Join(homeDir, ".yak", "secret.yml"))
 	}
 	
 	// Finally try ./secret.yml
-	configPath = "secret.yml"
-	if _, err := os.Stat(configPath); err != nil {
-		return nil, fmt.Errorf("secret.yml not found in any expected location")
-	}
+	candidates = append(candidates, "secret.yml")
 	
-LoadConfig:
+	var details []string
+	for _, configPath := range candidates {
+		if _, err := os.Stat(configPath); err != nil {
+			details = append(details, fmt.Sprintf("%s: not found", configPath))
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s: found", configPath))
+		return configPath, details
+	}
+	return "", details
+}
+
+// readSecretConfig parses a secret.yml file
+func readSecretConfig(configPath string) (*YakSecretConfig, error) {
 	// Read the config file
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -641,7 +667,7 @@ func (a *App) GetSecretConfigPaths(platform, environment string) ([]string, erro
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	output, err := cmd.Output()
 	if err != nil {
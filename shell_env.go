@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	shellImportSettingsFile = "shell-import.json"
+
+	// shellEnvTimeout bounds each attempt at running the login shell, rc files may wait on slow tools
+	shellEnvTimeout = 10 * time.Second
+)
+
+// Sources of an imported variable in a ShellImportReport
+const (
+	shellEnvSourceShell   = "shell"   // imported from the login shell
+	shellEnvSourceGUI     = "gui"     // set in the GUI or by an environment profile, not exported by the shell
+	shellEnvSourceProcess = "process" // inherited by the app process only
+	shellEnvSourceUnset   = "unset"
+)
+
+// defaultShellImportAllowlist is imported when the operator has not configured an allowlist
+var defaultShellImportAllowlist = []string{
+	"AWS_PROFILE",
+	"KUBECONFIG",
+	"PATH",
+	"TFINFRA_REPOSITORY_PATH",
+	"HOME",
+	"GANDI_TOKEN",
+	"TFE_TOKEN",
+	"TFE_ENDPOINT",
+	"VAULT_ADDR",
+	"TF_TOKEN_*",
+}
+
+// ShellImportSettings holds the variable name patterns imported from the login shell, "*" and "?" are wildcards
+type ShellImportSettings struct {
+	Allowlist []string `json:"allowlist"`
+}
+
+// ShellImportAttempt is one way of running the login shell tried by an import
+type ShellImportAttempt struct {
+	Method string `json:"method"` // login-interactive, login, rc-file, plain
+	Error  string `json:"error,omitempty"`
+}
+
+// ShellImportVariable tells where an allowed variable of the active environment comes from
+type ShellImportVariable struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"` // masked for tokens, secrets, passwords and keys
+	Source  string `json:"source"`
+	Pattern string `json:"pattern"` // allowlist entry matching the name
+}
+
+// ShellImportReport describes the last shell environment import
+type ShellImportReport struct {
+	Shell      string                `json:"shell"`
+	Method     string                `json:"method,omitempty"`
+	StartedAt  time.Time             `json:"startedAt"`
+	DurationMs int64                 `json:"durationMs"`
+	Attempts   []ShellImportAttempt  `json:"attempts"`
+	NoiseLines int                   `json:"noiseLines"` // lines printed by rc files around the environment
+	Variables  []ShellImportVariable `json:"variables"`
+	NotAllowed []string              `json:"notAllowed"` // shell variables left out by the allowlist
+	Error      string                `json:"error,omitempty"`
+}
+
+// shellCapture is the environment captured from one run of the login shell
+type shellCapture struct {
+	vars       map[string]string
+	noiseLines int
+}
+
+// parseShellEnvCapture extracts the NUL-separated `env -0` output printed between the begin and end markers,
+// anything rc files printed around it is counted as noise
+func parseShellEnvCapture(output []byte, begin, end string) (*shellCapture, error) {
+	start := bytes.Index(output, []byte(begin+"\n"))
+	if start < 0 {
+		return nil, fmt.Errorf("environment marker not found in shell output")
+	}
+	stop := bytes.LastIndex(output, []byte(end))
+	if stop < start {
+		return nil, fmt.Errorf("environment end marker not found in shell output")
+	}
+
+	capture := &shellCapture{vars: map[string]string{}}
+	for _, noise := range [][]byte{output[:start], output[stop+len(end):]} {
+		if trimmed := bytes.TrimSpace(noise); len(trimmed) > 0 {
+			capture.noiseLines += bytes.Count(trimmed, []byte("\n")) + 1
+		}
+	}
+
+	for _, entry := range bytes.Split(output[start+len(begin)+1:stop], []byte{0}) {
+		name, value, ok := strings.Cut(string(entry), "=")
+		if !ok || name == "" {
+			continue
+		}
+		capture.vars[name] = value
+	}
+	return capture, nil
+}
+
+// captureShellEnvironment runs the login shell of the operator and captures its environment.
+// Interactive login shells are tried first as they load the most rc files.
+func captureShellEnvironment(report *ShellImportReport) (*shellCapture, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate environment marker: %v", err)
+	}
+	marker := "__YAK_GUI_ENV_" + hex.EncodeToString(nonce)
+	begin, end := marker+"_BEGIN__", marker+"_END__"
+	script := fmt.Sprintf("printf '%%s\\n' '%s'; env -0; printf '%%s' '%s'", begin, end)
+
+	type shellAttempt struct {
+		method string
+		args   []string
+	}
+	attempts := []shellAttempt{
+		{"login-interactive", []string{"-l", "-i", "-c", script}},
+		{"login", []string{"-l", "-c", script}},
+	}
+	if strings.Contains(report.Shell, "zsh") {
+		attempts = append(attempts, shellAttempt{"rc-file", []string{"-c", "source ~/.zshrc; " + script}})
+	} else {
+		attempts = append(attempts, shellAttempt{"plain", []string{"-c", script}})
+	}
+
+	for _, attempt := range attempts {
+		ctx, cancel := context.WithTimeout(context.Background(), shellEnvTimeout)
+		cmd := exec.CommandContext(ctx, report.Shell, attempt.args...)
+		cmd.Dir = homeDir
+		// rc files may start background programs holding the pipes open
+		cmd.WaitDelay = time.Second
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		output, runErr := cmd.Output()
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		cancel()
+
+		// A failing command at the end of an rc file does not matter once the environment was printed
+		capture, err := parseShellEnvCapture(output, begin, end)
+		if err == nil {
+			report.Method = attempt.method
+			report.Attempts = append(report.Attempts, ShellImportAttempt{Method: attempt.method})
+			return capture, nil
+		}
+
+		switch {
+		case timedOut:
+			err = fmt.Errorf("timed out after %s", shellEnvTimeout)
+		case runErr != nil:
+			err = fmt.Errorf("%v: %s", runErr, firstLine(stderr.String()))
+		}
+		report.Attempts = append(report.Attempts, ShellImportAttempt{Method: attempt.method, Error: err.Error()})
+	}
+	return nil, fmt.Errorf("failed to get shell environment with all methods: %s", report.Attempts[len(report.Attempts)-1].Error)
+}
+
+// firstLine returns the first non-empty line of a command output
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// validateShellImportPattern checks an allowlist entry is a variable name with optional wildcards
+func validateShellImportPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("allowlist entries cannot be empty")
+	}
+	for _, r := range pattern {
+		if !(r == '_' || r == '*' || r == '?' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return fmt.Errorf("invalid allowlist entry %q: only letters, digits, _ and the wildcards * and ? are allowed", pattern)
+		}
+	}
+	return nil
+}
+
+// matchShellImportAllowlist returns the allowlist entry matching a variable name, empty when none does
+func matchShellImportAllowlist(allowlist []string, name string) string {
+	for _, pattern := range allowlist {
+		if ok, _ := path.Match(pattern, name); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// isSensitiveVariable reports whether a variable value must be masked in reports and stored as a secret
+func isSensitiveVariable(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range []string{"TOKEN", "SECRET", "PASSWORD", "KEY"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetShellImportSettings returns the saved shell import allowlist, or the default one
+func (a *App) GetShellImportSettings() (ShellImportSettings, error) {
+	settings := ShellImportSettings{Allowlist: append([]string(nil), defaultShellImportAllowlist...)}
+	if _, err := loadStateFile(shellImportSettingsFile, &settings); err != nil {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// SaveShellImportSettings validates and saves the shell import allowlist
+func (a *App) SaveShellImportSettings(settings ShellImportSettings) error {
+	if len(settings.Allowlist) == 0 {
+		return fmt.Errorf("allowlist cannot be empty")
+	}
+	for _, pattern := range settings.Allowlist {
+		if err := validateShellImportPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return saveStateFile(shellImportSettingsFile, settings)
+}
+
+// GetShellEnvironment runs the login shell of the operator and returns its full environment
+func (a *App) GetShellEnvironment() (map[string]string, error) {
+	report := &ShellImportReport{Shell: a.loginShell()}
+	capture, err := captureShellEnvironment(report)
+	if err != nil {
+		return nil, err
+	}
+	return capture.vars, nil
+}
+
+// loginShell returns the shell of the operator
+func (a *App) loginShell() string {
+	if shell := a.currentEnv().get("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/zsh" // Default to zsh on macOS
+}
+
+// ImportShellEnvironment imports the allowed variables of the login shell into the active environment.
+// The diagnostics of the import are available from GetShellImportReport.
+func (a *App) ImportShellEnvironment() error {
+	_, err := a.importShellEnvironment()
+	return err
+}
+
+// importShellEnvironment imports the allowed variables of the login shell and records the report
+func (a *App) importShellEnvironment() (*ShellImportReport, error) {
+	report := &ShellImportReport{
+		Shell:      a.loginShell(),
+		StartedAt:  time.Now(),
+		Attempts:   []ShellImportAttempt{},
+		Variables:  []ShellImportVariable{},
+		NotAllowed: []string{},
+	}
+	defer func() {
+		report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+		a.shellImportMu.Lock()
+		a.shellImport = report
+		a.shellImportMu.Unlock()
+	}()
+
+	settings, err := a.GetShellImportSettings()
+	if err != nil {
+		report.Error = err.Error()
+		return report, err
+	}
+	capture, err := captureShellEnvironment(report)
+	if err != nil {
+		report.Error = err.Error()
+		return report, fmt.Errorf("failed to get shell environment: %v", err)
+	}
+	report.NoiseLines = capture.noiseLines
+
+	imported := make(map[string]string)
+	for name, value := range capture.vars {
+		if matchShellImportAllowlist(settings.Allowlist, name) == "" {
+			report.NotAllowed = append(report.NotAllowed, name)
+		} else if value != "" {
+			imported[name] = value
+		}
+	}
+	env := a.updateEnv(func(env *envContext) *envContext {
+		return env.with(imported)
+	})
+
+	// Report the imported variables and, for plain allowlist entries, where the value comes from instead
+	names := make(map[string]bool)
+	for name := range imported {
+		names[name] = true
+	}
+	for _, pattern := range settings.Allowlist {
+		if !strings.ContainsAny(pattern, "*?") {
+			names[pattern] = true
+		}
+	}
+	for name := range names {
+		variable := ShellImportVariable{Name: name, Pattern: matchShellImportAllowlist(settings.Allowlist, name)}
+		_, overridden := env.vars[name]
+		_, inherited := os.LookupEnv(name)
+		switch {
+		case imported[name] != "":
+			variable.Source = shellEnvSourceShell
+		case overridden:
+			variable.Source = shellEnvSourceGUI
+		case inherited:
+			variable.Source = shellEnvSourceProcess
+		default:
+			variable.Source = shellEnvSourceUnset
+		}
+		variable.Value = env.get(name)
+		if isSensitiveVariable(name) {
+			variable.Value = maskSensitiveValue(variable.Value)
+		}
+		report.Variables = append(report.Variables, variable)
+	}
+	sort.Slice(report.Variables, func(i, j int) bool { return report.Variables[i].Name < report.Variables[j].Name })
+	sort.Strings(report.NotAllowed)
+	return report, nil
+}
+
+// GetShellImportReport returns the diagnostics of the last shell environment import, nil before the first one
+func (a *App) GetShellImportReport() *ShellImportReport {
+	a.shellImportMu.Lock()
+	defer a.shellImportMu.Unlock()
+	return a.shellImport
+}
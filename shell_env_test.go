@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseShellEnvCapture verifies multi-line values survive and rc file output is ignored
+func TestParseShellEnvCapture(t *testing.T) {
+	output := []byte("Welcome!\nLast login: today\nBEGIN\nA=1\x00MULTI=line one\nline two=x\x00\x00END\nlogout\n")
+	capture, err := parseShellEnvCapture(output, "BEGIN", "END")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "MULTI": "line one\nline two=x"}, capture.vars)
+	assert.Equal(t, 3, capture.noiseLines)
+
+	_, err = parseShellEnvCapture([]byte("A=1\n"), "BEGIN", "END")
+	assert.Error(t, err)
+}
+
+// TestImportShellEnvironment verifies only allowed variables are imported and the report tells where they come from
+func TestImportShellEnvironment(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+	shell := filepath.Join(t.TempDir(), "fake-shell")
+	require.NoError(t, os.WriteFile(shell, []byte(`#!/bin/sh
+echo "Welcome to the fake shell"
+export TF_TOKEN_app_terraform_io="tf-token-value"
+export VAULT_ADDR="https://vault.example.com"
+export UNRELATED="ignored"
+export GANDI_TOKEN="gandi
+multi-line"
+exec /bin/sh "$@"
+`), 0755))
+	t.Setenv("SHELL", shell)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", "")
+
+	app := NewApp()
+	app.setEnvVar("TFINFRA_REPOSITORY_PATH", "/gui/terraform-infra")
+	require.NoError(t, app.SaveShellImportSettings(ShellImportSettings{
+		Allowlist: []string{"TF_TOKEN_*", "VAULT_ADDR", "GANDI_TOKEN", "TFINFRA_REPOSITORY_PATH", "YAK_GUI_TEST_UNSET"},
+	}))
+	require.NoError(t, app.ImportShellEnvironment())
+
+	env := app.currentEnv()
+	assert.Equal(t, "tf-token-value", env.get("TF_TOKEN_app_terraform_io"))
+	assert.Equal(t, "gandi\nmulti-line", env.get("GANDI_TOKEN"))
+	_, imported := env.vars["UNRELATED"]
+	assert.False(t, imported)
+
+	report := app.GetShellImportReport()
+	require.NotNil(t, report)
+	assert.Equal(t, "login-interactive", report.Method)
+	assert.GreaterOrEqual(t, report.NoiseLines, 1)
+	assert.Contains(t, report.NotAllowed, "UNRELATED")
+
+	sources := map[string]ShellImportVariable{}
+	for _, variable := range report.Variables {
+		sources[variable.Name] = variable
+	}
+	assert.Equal(t, shellEnvSourceShell, sources["TF_TOKEN_app_terraform_io"].Source)
+	assert.Equal(t, "TF_TOKEN_*", sources["TF_TOKEN_app_terraform_io"].Pattern)
+	assert.Equal(t, "tf-t...alue", sources["TF_TOKEN_app_terraform_io"].Value)
+	assert.Equal(t, shellEnvSourceGUI, sources["TFINFRA_REPOSITORY_PATH"].Source)
+	assert.Equal(t, shellEnvSourceUnset, sources["YAK_GUI_TEST_UNSET"].Source)
+}
+
+// TestSaveShellImportSettings verifies allowlist entries are validated
+func TestSaveShellImportSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	settings, err := app.GetShellImportSettings()
+	require.NoError(t, err)
+	assert.Contains(t, settings.Allowlist, "TF_TOKEN_*")
+
+	assert.Error(t, app.SaveShellImportSettings(ShellImportSettings{}))
+	assert.Error(t, app.SaveShellImportSettings(ShellImportSettings{Allowlist: []string{"TF TOKEN"}}))
+	assert.Error(t, app.SaveShellImportSettings(ShellImportSettings{Allowlist: []string{"[A-Z]*"}}))
+}
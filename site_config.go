@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// siteConfigFile is read from ~/.yak-gui, it overrides the terraform-infra site config
+	siteConfigFile = "site.yaml"
+
+	// tfinfraSiteConfigPath is the site config shipped with terraform-infra, relative to TFINFRA_REPOSITORY_PATH
+	tfinfraSiteConfigPath = "setup/yak_config/yak-gui-site.yaml"
+
+	// siteProfilePlaceholder is replaced by the AWS profile in URL templates
+	siteProfilePlaceholder = "{profile}"
+)
+
+// SiteArgoCDConfig defines how the ArgoCD server of an AWS profile is found
+type SiteArgoCDConfig struct {
+	ServerTemplate  string            `yaml:"serverTemplate" json:"serverTemplate"`   // e.g. "argocd-{profile}.example.com"
+	ProfileSuffixes []string          `yaml:"profileSuffixes" json:"profileSuffixes"` // stripped from the profile before templating
+	Servers         map[string]string `yaml:"servers" json:"servers,omitempty"`       // explicit server per AWS profile
+}
+
+// SiteTFEProfile overrides the TFE endpoint of an environment profile
+type SiteTFEProfile struct {
+	Endpoint     string `yaml:"endpoint" json:"endpoint,omitempty"`
+	Organization string `yaml:"organization" json:"organization,omitempty"`
+}
+
+// SiteTFEConfig defines the TFE endpoints and workspace naming rules
+type SiteTFEConfig struct {
+	Endpoint     string                    `yaml:"endpoint" json:"endpoint"`
+	Organization string                    `yaml:"organization" json:"organization"`
+	Profiles     map[string]SiteTFEProfile `yaml:"profiles" json:"profiles,omitempty"` // keyed by environment profile
+	Resolver     TFEResolverConfig         `yaml:"resolver" json:"resolver"`
+}
+
+// SiteNotificationsConfig defines notification defaults
+type SiteNotificationsConfig struct {
+	Recipients []string `yaml:"recipients" json:"recipients"`
+}
+
+// SiteCertificatesConfig lists the certificates managed with yak
+type SiteCertificatesConfig struct {
+	Names []string `yaml:"names" json:"names"`
+}
+
+// SiteConfig holds the organization-specific settings of yak-gui
+type SiteConfig struct {
+	ArgoCD        SiteArgoCDConfig        `yaml:"argocd" json:"argocd"`
+	TFE           SiteTFEConfig           `yaml:"tfe" json:"tfe"`
+	Notifications SiteNotificationsConfig `yaml:"notifications" json:"notifications"`
+	Certificates  SiteCertificatesConfig  `yaml:"certificates" json:"certificates"`
+	Sources       []string                `yaml:"-" json:"sources"` // files merged over the built-in defaults, in order
+}
+
+// SiteNotConfiguredError is returned by features that need an organization-specific setting no site file provides
+type SiteNotConfiguredError struct {
+	Setting string // key in the site file, e.g. "tfe.endpoint"
+}
+
+// Error implements error
+func (e *SiteNotConfiguredError) Error() string {
+	return fmt.Sprintf("%s is not configured, set it in %s of terraform-infra or in ~/.yak-gui/%s",
+		e.Setting, tfinfraSiteConfigPath, siteConfigFile)
+}
+
+// builtinSiteConfig returns the organization-neutral defaults, organization settings such as the TFE endpoint,
+// the ArgoCD servers, the notification recipients and the certificates come from the site files
+func builtinSiteConfig() SiteConfig {
+	return SiteConfig{
+		ArgoCD: SiteArgoCDConfig{
+			ProfileSuffixes: []string{awsSSOProfileSuffix},
+		},
+		TFE: SiteTFEConfig{
+			Resolver: TFEResolverConfig{
+				OwnerTagPrefix:       "owner:",
+				EnvironmentTagPrefix: "env:",
+				EnvironmentRules: []TFEResolverRule{
+					{Pattern: "preprod", Value: "preprod"},
+					{Pattern: "shared", Value: "shared"},
+					{Pattern: "prd|prod", Value: "production"},
+					{Pattern: "staging", Value: "staging"},
+					{Pattern: "dev", Value: "development"},
+					{Pattern: "test", Value: "testing"},
+				},
+			},
+		},
+		Notifications: SiteNotificationsConfig{
+			Recipients: []string{},
+		},
+		Certificates: SiteCertificatesConfig{
+			Names: []string{},
+		},
+	}
+}
+
+// siteConfigPaths returns the site files to merge, lowest precedence first
+func siteConfigPaths(tfinfraPath string) ([]string, error) {
+	var paths []string
+	if tfinfraPath != "" {
+		paths = append(paths, filepath.Join(tfinfraPath, filepath.FromSlash(tfinfraSiteConfigPath)))
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return append(paths, filepath.Join(homeDir, ".yak-gui", siteConfigFile)), nil
+}
+
+// loadSiteConfig merges the terraform-infra and user site files over the built-in defaults.
+// Keys present in a file replace the defaults, lists are replaced rather than appended.
+func loadSiteConfig(tfinfraPath string) (SiteConfig, error) {
+	config := builtinSiteConfig()
+
+	paths, err := siteConfigPaths(tfinfraPath)
+	if err != nil {
+		return config, err
+	}
+
+	config.Sources = []string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return config, fmt.Errorf("failed to read site config %s: %v", path, err)
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("failed to parse site config %s: %v", path, err)
+		}
+		config.Sources = append(config.Sources, path)
+	}
+
+	if _, err := newRuleWorkspaceResolver(config.TFE.Resolver); err != nil {
+		return config, fmt.Errorf("invalid TFE resolver in site config: %w", err)
+	}
+	return config, nil
+}
+
+// siteConfig loads the site config of the terraform-infra checkout of the active environment
+func (a *App) siteConfig() (SiteConfig, error) {
+	return loadSiteConfig(a.currentEnv().get("TFINFRA_REPOSITORY_PATH"))
+}
+
+// GetSiteConfig returns the effective site configuration and the files it was read from
+func (a *App) GetSiteConfig() (SiteConfig, error) {
+	return a.siteConfig()
+}
+
+// argoCDServer returns the ArgoCD server of an AWS profile
+func (c SiteArgoCDConfig) argoCDServer(profile string) (string, error) {
+	if server, ok := c.Servers[profile]; ok && server != "" {
+		return server, nil
+	}
+
+	cleanProfile := profile
+	for _, suffix := range c.ProfileSuffixes {
+		cleanProfile = strings.TrimSuffix(cleanProfile, suffix)
+	}
+	if server, ok := c.Servers[cleanProfile]; ok && server != "" {
+		return server, nil
+	}
+
+	if c.ServerTemplate == "" {
+		return "", &SiteNotConfiguredError{Setting: fmt.Sprintf("argocd.serverTemplate (or argocd.servers.%s)", cleanProfile)}
+	}
+	return strings.ReplaceAll(c.ServerTemplate, siteProfilePlaceholder, cleanProfile), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doctolibSiteConfig is the site file terraform-infra ships, tests of organization-specific behavior use it
+const doctolibSiteConfig = "docs/yak-gui-site.doctolib.yaml"
+
+// installSiteConfig copies a site file to the user site config of home
+func installSiteConfig(t *testing.T, home, source string) {
+	t.Helper()
+	data, err := os.ReadFile(source)
+	require.NoError(t, err)
+	path := filepath.Join(home, ".yak-gui", siteConfigFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+// TestSiteConfigOverrides verifies the user site file overrides terraform-infra, which overrides the built-in defaults
+func TestSiteConfigOverrides(t *testing.T) {
+	home := t.TempDir()
+	tfinfra := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFINFRA_REPOSITORY_PATH", tfinfra)
+	t.Setenv("TFE_ENDPOINT", "")
+	t.Setenv("TFE_ORGANIZATION", "")
+	t.Setenv("AWS_PROFILE", "staging-sso")
+
+	app := NewApp()
+
+	// Without site files the built-in defaults apply, they have no organization settings
+	site, err := app.GetSiteConfig()
+	require.NoError(t, err)
+	assert.Empty(t, site.Sources)
+	var notConfigured *SiteNotConfiguredError
+	_, err = app.GetArgoCDServerFromProfile()
+	require.True(t, errors.As(err, &notConfigured))
+	assert.Contains(t, notConfigured.Setting, "argocd.serverTemplate")
+	_, err = app.ListCertificates()
+	require.True(t, errors.As(err, &notConfigured))
+	assert.Equal(t, "certificates.names", notConfigured.Setting)
+	assert.Empty(t, app.defaultNotificationSettings().Recipients)
+	config, err := app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.Endpoint)
+	config.Token = "tfe-token"
+	_, err = app.GetTFEWorkspaces(config)
+	require.True(t, errors.As(err, &notConfigured))
+	assert.Equal(t, "tfe.endpoint", notConfigured.Setting)
+
+	tfinfraSite := filepath.Join(tfinfra, filepath.FromSlash(tfinfraSiteConfigPath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(tfinfraSite), 0755))
+	require.NoError(t, os.WriteFile(tfinfraSite, []byte(`
+argocd:
+  serverTemplate: "argocd.{profile}.example.org"
+tfe:
+  endpoint: tfe.example.org
+  organization: example
+  profiles:
+    production:
+      organization: example-prod
+  resolver:
+    environmentRules:
+      - pattern: "-(eu|us)$"
+        value: "region-$1"
+certificates:
+  names: [shop.example.org]
+`), 0644))
+
+	userSite := filepath.Join(home, ".yak-gui", siteConfigFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(userSite), 0755))
+	require.NoError(t, os.WriteFile(userSite, []byte(`
+argocd:
+  servers:
+    prod: argocd-prod.internal
+`), 0644))
+
+	site, err = app.GetSiteConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{tfinfraSite, userSite}, site.Sources)
+
+	server, err := app.GetArgoCDServerFromProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "argocd.staging.example.org", server)
+	t.Setenv("AWS_PROFILE", "prod-sso")
+	server, err = app.GetArgoCDServerFromProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "argocd-prod.internal", server)
+
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "tfe.example.org", config.Endpoint)
+	assert.Equal(t, "example", config.Organization)
+	app.env.Store(app.currentEnv().withProfile("production"))
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "example-prod", config.Organization)
+
+	resolver, err := app.tfeResolverFor("example")
+	require.NoError(t, err)
+	workspace := TFEWorkspace{Name: "payments-eu"}
+	resolver.Resolve(&workspace)
+	assert.Equal(t, "region-eu", workspace.Environment)
+	// Keys omitted in the site file keep their defaults
+	assert.Equal(t, "owner:", site.TFE.Resolver.OwnerTagPrefix)
+
+	certificates, err := app.ListCertificates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop.example.org"}, certificates)
+	assert.Equal(t, builtinSiteConfig().Notifications.Recipients, app.defaultNotificationSettings().Recipients)
+}
+
+// TestDoctolibSiteConfig verifies the site file shipped in terraform-infra parses and sets every organization setting
+func TestDoctolibSiteConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installSiteConfig(t, home, doctolibSiteConfig)
+
+	site, err := loadSiteConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "tfe.doctolib.net", site.TFE.Endpoint)
+	assert.Equal(t, "doctolib", site.TFE.Organization)
+	assert.NotEmpty(t, site.Notifications.Recipients)
+	assert.NotEmpty(t, site.Certificates.Names)
+	server, err := site.ArgoCD.argoCDServer("staging-sso")
+	require.NoError(t, err)
+	assert.Equal(t, "argocd-staging.doctolib.net", server)
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// stateLocks stands in for file locks on platforms without flock, it only serializes this instance
+var stateLocks sync.Map
+
+// tryLockFile takes the in-process lock of the file f was opened from
+func tryLockFile(f *os.File) (bool, error) {
+	_, held := stateLocks.LoadOrStore(f.Name(), true)
+	return !held, nil
+}
+
+// unlockFile releases the lock taken by tryLockFile
+func unlockFile(f *os.File) {
+	stateLocks.Delete(f.Name())
+}
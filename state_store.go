@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State files under ~/.yak-gui are replaced atomically: the new content is written to a temporary file
+// in the same directory and renamed over the old one, whose last valid version is kept as <name>.bak.
+// A <name>.lock file serializes writers across the app instances of the operator, and a file that no
+// longer parses is restored from its backup, the damaged copy being kept as <name>.corrupt.
+const (
+	stateBackupSuffix  = ".bak"
+	stateCorruptSuffix = ".corrupt"
+	stateLockSuffix    = ".lock"
+
+	// stateLockTimeout bounds the wait for another instance holding the lock of a state file
+	stateLockTimeout = 10 * time.Second
+	stateLockRetry   = 50 * time.Millisecond
+)
+
+// StateFileCorruptError is returned for a state file that does not parse and has no valid backup
+type StateFileCorruptError struct {
+	Path   string
+	Detail string
+}
+
+// Error implements error
+func (e *StateFileCorruptError) Error() string {
+	return fmt.Sprintf("state file %s is corrupt and has no valid backup: %s", e.Path, e.Detail)
+}
+
+// validStateJSON rejects content that is not a JSON document
+func validStateJSON(data []byte) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("not a valid JSON document")
+	}
+	return nil
+}
+
+// statePath returns the path of a state file, name is relative to ~/.yak-gui
+func statePath(name string) (string, error) {
+	dir, err := yakGuiDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// withStateLock runs fn with the path of a state file while holding its lock
+func withStateLock(name string, fn func(path string) error) error {
+	path, err := statePath(name)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockStateFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(path)
+}
+
+// lockStateFile takes the exclusive lock of a state file, waiting up to stateLockTimeout
+func lockStateFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %v", err)
+	}
+	f, err := os.OpenFile(path+stateLockSuffix, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock of %s: %v", filepath.Base(path), err)
+	}
+
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %v", filepath.Base(path), err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s is locked by another yak-gui instance", filepath.Base(path))
+		}
+		time.Sleep(stateLockRetry)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// writeFileAtomic replaces a file with data through a temporary file renamed over it
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %v", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions of %s: %v", filepath.Base(path), err)
+	}
+	// The content must be on disk before the rename makes it visible
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %v", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// readStateData reads a state file, the caller must hold its lock. Content rejected by valid is replaced
+// by the backup. A missing file returns an error satisfying os.IsNotExist.
+func readStateData(path string, valid func([]byte) error) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+	if valid == nil {
+		return data, nil
+	}
+	invalid := valid(data)
+	if invalid == nil {
+		return data, nil
+	}
+
+	backup, err := os.ReadFile(path + stateBackupSuffix)
+	if err != nil || valid(backup) != nil {
+		return nil, &StateFileCorruptError{Path: path, Detail: invalid.Error()}
+	}
+	if err := writeFileAtomic(path+stateCorruptSuffix, data, 0600); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, backup, 0600); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Warning: %s was corrupt (%v), restored the last good version, the damaged file is kept as %s\n",
+		path, invalid, filepath.Base(path+stateCorruptSuffix))
+	return backup, nil
+}
+
+// writeStateData atomically replaces a state file, the caller must hold its lock. The current content
+// becomes the backup when valid accepts it, otherwise it is kept as the corrupt copy.
+func writeStateData(path string, data []byte, perm os.FileMode, valid func([]byte) error) error {
+	if current, err := os.ReadFile(path); err == nil {
+		suffix := stateBackupSuffix
+		if valid != nil && valid(current) != nil {
+			suffix = stateCorruptSuffix
+		}
+		if err := writeFileAtomic(path+suffix, current, perm); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+	return writeFileAtomic(path, data, perm)
+}
+
+// readStateJSON decodes a state file into v, the caller must hold its lock.
+// It returns false without error when the file does not exist yet.
+func readStateJSON(path string, v interface{}) (bool, error) {
+	data, err := readStateData(path, validStateJSON)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %v", filepath.Base(path), err)
+	}
+	return true, nil
+}
+
+// writeStateJSON encodes v as indented JSON into a state file, the caller must hold its lock
+func writeStateJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", filepath.Base(path), err)
+	}
+	return writeStateData(path, data, 0600, validStateJSON)
+}
+
+// loadStateFile reads a JSON state file from ~/.yak-gui into v.
+// It returns false without error when the file does not exist yet.
+func loadStateFile(name string, v interface{}) (bool, error) {
+	var found bool
+	err := withStateLock(name, func(path string) error {
+		var err error
+		found, err = readStateJSON(path, v)
+		return err
+	})
+	return found, err
+}
+
+// saveStateFile writes v as indented JSON to a state file in ~/.yak-gui
+func saveStateFile(name string, v interface{}) error {
+	return withStateLock(name, func(path string) error {
+		return writeStateJSON(path, v)
+	})
+}
+
+// updateStateFile loads a JSON state file into v, applies fn and saves v, holding the lock of the file
+// so that concurrent instances do not lose each other's changes. Nothing is saved when fn fails.
+func updateStateFile(name string, v interface{}, fn func() error) error {
+	return withStateLock(name, func(path string) error {
+		if _, err := readStateJSON(path, v); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		return writeStateJSON(path, v)
+	})
+}
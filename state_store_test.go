@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStateFileBackupAndRecovery verifies the previous version is kept and restored when the file gets corrupted
+func TestStateFileBackupAndRecovery(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".yak-gui", "state.json")
+
+	require.NoError(t, saveStateFile("state.json", map[string]int{"version": 1}))
+	require.NoError(t, saveStateFile("state.json", map[string]int{"version": 2}))
+	backup, err := os.ReadFile(path + stateBackupSuffix)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": 1}`, string(backup))
+
+	temps, err := filepath.Glob(filepath.Join(home, ".yak-gui", ".state.json.tmp-*"))
+	require.NoError(t, err)
+	assert.Empty(t, temps)
+
+	// A crash of another tool truncated the file
+	require.NoError(t, os.WriteFile(path, []byte(`{"vers`), 0600))
+	var state map[string]int
+	found, err := loadStateFile("state.json", &state)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, state["version"])
+	corrupt, err := os.ReadFile(path + stateCorruptSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, `{"vers`, string(corrupt))
+
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	require.NoError(t, os.Remove(path+stateBackupSuffix))
+	_, err = loadStateFile("state.json", &state)
+	var corruptErr *StateFileCorruptError
+	assert.True(t, errors.As(err, &corruptErr))
+
+	// Saving over a corrupt file does not turn it into the backup
+	require.NoError(t, saveStateFile("state.json", map[string]int{"version": 3}))
+	_, err = os.Stat(path + stateBackupSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestUpdateStateFileConcurrent verifies read-modify-write cycles do not lose updates
+func TestUpdateStateFileConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var counter map[string]int
+			assert.NoError(t, updateStateFile("counter.json", &counter, func() error {
+				if counter == nil {
+					counter = map[string]int{}
+				}
+				counter["count"]++
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	var counter map[string]int
+	_, err := loadStateFile("counter.json", &counter)
+	require.NoError(t, err)
+	assert.Equal(t, 20, counter["count"])
+}
@@ -4,8 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -15,6 +14,7 @@ type TFEConfig struct {
 	Endpoint     string `json:"endpoint"`
 	Organization string `json:"organization"`
 	Token        string `json:"token,omitempty"`
+	TokenSource  string `json:"tokenSource,omitempty"` // where the token was found, e.g. "secret store" or "TFE_TOKEN"
 }
 
 // TFEWorkspace represents a TFE workspace
@@ -56,6 +56,7 @@ type TFERun struct {
 	TerraformVersion string `json:"terraformVersion,omitempty"`
 	HasChanges       bool   `json:"hasChanges"`
 	IsDestroy        bool   `json:"isDestroy"`
+	RefreshOnly      bool   `json:"refreshOnly,omitempty"`
 	IsConfirmable    bool   `json:"isConfirmable"`
 	Actions          struct {
 		IsConfirmable bool `json:"isConfirmable"`
@@ -73,19 +74,23 @@ type TFEPlanExecution struct {
 	TerraformVersion string   `json:"terraformVersion"`
 	Message          string   `json:"message,omitempty"`
 	Wait             bool     `json:"wait"`
+	RefreshOnly      bool     `json:"refreshOnly,omitempty"` // speculative refresh-only plan through the TFE API, used by drift scans
+
+	runTag string // unique tag added to the run message, identifies the run of an interrupted plan
 }
 
 // TFEPlanResult represents the result of a plan execution
 type TFEPlanResult struct {
-	WorkspaceName string    `json:"workspaceName"`
-	RunID         string    `json:"runId"`
-	Status        string    `json:"status"`
-	HasChanges    bool      `json:"hasChanges"`
-	Message       string    `json:"message,omitempty"`
-	Error         string    `json:"error,omitempty"`
-	URL           string    `json:"url,omitempty"`
-	Duration      string    `json:"duration,omitempty"`
-	CreatedAt     time.Time `json:"createdAt"`
+	WorkspaceName string          `json:"workspaceName"`
+	RunID         string          `json:"runId"`
+	Status        string          `json:"status"`
+	HasChanges    bool            `json:"hasChanges"`
+	Message       string          `json:"message,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	URL           string          `json:"url,omitempty"`
+	Duration      string          `json:"duration,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Summary       *TFEPlanSummary `json:"summary,omitempty"`
 }
 
 // TFEVersionInfo represents Terraform version information
@@ -98,8 +103,72 @@ type TFEVersionInfo struct {
 	Usage      int    `json:"usage"` // Number of workspaces using this version
 }
 
-// GetTFEWorkspaces retrieves all TFE workspaces
+// GetTFEWorkspaces retrieves all TFE workspaces with their details from the TFE API.
+// Without an API token it falls back to the workspace names listed by yak.
 func (a *App) GetTFEWorkspaces(config TFEConfig) ([]TFEWorkspace, error) {
+	if config.Token == "" {
+		return a.listTFEWorkspacesWithYak(config)
+	}
+	return a.listTFEWorkspacesWithAPI(config, nil)
+}
+
+// GetTFEWorkspacesByTag retrieves TFE workspaces filtered by tag
+func (a *App) GetTFEWorkspacesByTag(config TFEConfig, tag string, not bool) ([]TFEWorkspace, error) {
+	if config.Token == "" {
+		var args []string
+		if tag != "" {
+			args = append(args, "--tag", tag)
+			if not {
+				args = append(args, "--not")
+			}
+		}
+		return a.listTFEWorkspacesWithYak(config, args...)
+	}
+
+	query := url.Values{}
+	if tag != "" {
+		if not {
+			query.Set("search[exclude-tags]", tag)
+		} else {
+			query.Set("search[tags]", tag)
+		}
+	}
+	return a.listTFEWorkspacesWithAPI(config, query)
+}
+
+// listTFEWorkspacesWithAPI fetches workspace details from the TFE API and resolves owner and environment
+func (a *App) listTFEWorkspacesWithAPI(config TFEConfig, query url.Values) ([]TFEWorkspace, error) {
+	resolver, err := a.tfeResolverFor(config.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	workspaces, err := client.listWorkspaces(ctx, config.Organization, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TFE workspaces: %w", err)
+	}
+
+	for i := range workspaces {
+		resolver.Resolve(&workspaces[i])
+	}
+	return workspaces, nil
+}
+
+// listTFEWorkspacesWithYak lists workspace names with yak, only name-based details are available this way
+func (a *App) listTFEWorkspacesWithYak(config TFEConfig, extraArgs ...string) ([]TFEWorkspace, error) {
+	resolver, err := a.tfeResolverFor(config.Organization)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build yak command
 	args := []string{"tfe", "workspace", "list", "--json"}
 	
@@ -107,15 +176,16 @@ func (a *App) GetTFEWorkspaces(config TFEConfig) ([]TFEWorkspace, error) {
 	if config.Organization != "" {
 		args = append(args, "--organization", config.Organization)
 	}
+	args = append(args, extraArgs...)
 	
 	// Execute command
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
 		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
 	)
@@ -135,127 +205,19 @@ func (a *App) GetTFEWorkspaces(config TFEConfig) ([]TFEWorkspace, error) {
 	var workspaces []TFEWorkspace
 	for _, name := range workspaceNames {
 		workspace := TFEWorkspace{
-			ID:           name, // Use name as ID for now
+			ID:           name, // yak only returns names, use them as IDs
 			Name:         name,
 			Organization: config.Organization,
-			Status:       "active", // Default status
-			// Don't extract fake tags - TFE has real tags but we can't get them from the list command
-			Environment: extractEnvironmentFromName(name),
-			Owner:       extractOwnerFromName(name),
-			Tags:        []string{}, // Empty tags since we can't get real ones from the list command
+			Status:       "active", // Lock status is not available from the list command
+			Tags:         []string{},
 		}
+		resolver.Resolve(&workspace)
 		workspaces = append(workspaces, workspace)
 	}
 	
 	return workspaces, nil
 }
 
-// Helper function to extract environment from workspace name
-func extractEnvironmentFromName(name string) string {
-	// Check for regional patterns first (more specific)
-	if strings.Contains(name, "dev-aws-fr-par-1") {
-		return "dev-aws-fr-par-1"
-	} else if strings.Contains(name, "dev-aws-de-fra-1") {
-		return "dev-aws-de-fra-1"
-	} else if strings.Contains(name, "dev-aws-global") {
-		return "dev-aws-global"
-	} else if strings.Contains(name, "staging-aws-fr-par-1") {
-		return "staging-aws-fr-par-1"
-	} else if strings.Contains(name, "staging-aws-de-fra-1") {
-		return "staging-aws-de-fra-1"
-	} else if strings.Contains(name, "staging-aws-global") {
-		return "staging-aws-global"
-	} else if strings.Contains(name, "prod-aws-fr-par-1") || strings.Contains(name, "prd-aws-fr-par-1") {
-		return "prod-aws-fr-par-1"
-	} else if strings.Contains(name, "prod-aws-de-fra-1") || strings.Contains(name, "prd-aws-de-fra-1") {
-		return "prod-aws-de-fra-1"
-	} else if strings.Contains(name, "prod-aws-global") || strings.Contains(name, "prd-aws-global") {
-		return "prod-aws-global"
-	} else if strings.Contains(name, "preprod-aws-fr-par-1") {
-		return "preprod-aws-fr-par-1"
-	} else if strings.Contains(name, "preprod-aws-de-fra-1") {
-		return "preprod-aws-de-fra-1"
-	} else if strings.Contains(name, "preprod-aws-global") {
-		return "preprod-aws-global"
-	}
-	
-	// Check for general environment patterns
-	if strings.Contains(name, "preprod") {
-		return "preprod"
-	} else if strings.Contains(name, "shared") {
-		return "shared"
-	} else if strings.Contains(name, "prd") || strings.Contains(name, "prod") {
-		return "production"
-	} else if strings.Contains(name, "staging") {
-		return "staging"
-	} else if strings.Contains(name, "dev") {
-		return "development"
-	} else if strings.Contains(name, "test") {
-		return "testing"
-	}
-	
-	return "unknown"
-}
-
-// Helper function to extract owner from workspace name
-func extractOwnerFromName(name string) string {
-	// Try to extract team/owner from workspace name patterns
-	if strings.Contains(name, "tooling") {
-		return "tooling-team"
-	} else if strings.Contains(name, "security") {
-		return "security-team"
-	} else if strings.Contains(name, "logging") {
-		return "logging-team"
-	} else if strings.Contains(name, "cicd") {
-		return "cicd-team"
-	}
-	return "unknown"
-}
-
-
-// GetTFEWorkspacesByTag retrieves TFE workspaces filtered by tag
-func (a *App) GetTFEWorkspacesByTag(config TFEConfig, tag string, not bool) ([]TFEWorkspace, error) {
-	// Build yak command
-	args := []string{"tfe", "workspace", "list", "--json"}
-	
-	// Add organization if specified
-	if config.Organization != "" {
-		args = append(args, "--organization", config.Organization)
-	}
-	
-	// Add tag filter
-	if tag != "" {
-		args = append(args, "--tag", tag)
-		if not {
-			args = append(args, "--not")
-		}
-	}
-	
-	// Execute command
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
-		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
-	)
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list TFE workspaces by tag: %w - %s", err, string(output))
-	}
-	
-	var workspaces []TFEWorkspace
-	if err := json.Unmarshal(output, &workspaces); err != nil {
-		return nil, fmt.Errorf("failed to parse TFE workspaces response: %w", err)
-	}
-	
-	return workspaces, nil
-}
-
 // ExecuteTFEPlan executes a plan on TFE workspaces
 func (a *App) ExecuteTFEPlan(config TFEConfig, execution TFEPlanExecution) ([]TFEPlanResult, error) {
 	// Build yak command
@@ -288,10 +250,10 @@ func (a *App) ExecuteTFEPlan(config TFEConfig, execution TFEPlanExecution) ([]TF
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second) // 5 minutes timeout for plan execution
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
 		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
 	)
@@ -309,95 +271,133 @@ func (a *App) ExecuteTFEPlan(config TFEConfig, execution TFEPlanExecution) ([]TF
 	return results, nil
 }
 
-// GetTFERuns retrieves TFE runs (currently not supported by yak CLI)
+// GetTFERuns retrieves the tfeRunsPageSize most recent runs of a TFE workspace, identified by ID or name
 func (a *App) GetTFERuns(config TFEConfig, workspaceID string) ([]TFERun, error) {
-	// Note: The yak tfe run command doesn't have a list subcommand
-	// For now, return empty array until the yak CLI supports this
-	return []TFERun{}, nil
+	if workspaceID == "" {
+		return nil, fmt.Errorf("workspace is required")
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	workspace, err := client.getWorkspace(ctx, config.Organization, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TFE workspace %s: %w", workspaceID, err)
+	}
+
+	runs, err := client.listRuns(ctx, config.Organization, workspace, tfeRunsPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TFE runs for %s: %w", workspace.Name, err)
+	}
+	return runs, nil
 }
 
-// LockTFEWorkspace locks a TFE workspace
-func (a *App) LockTFEWorkspace(config TFEConfig, workspaceNames []string, checkStatus bool) error {
-	// Build yak command
-	args := []string{"tfe", "workspace", "lock"}
-	
-	// Add organization if specified
-	if config.Organization != "" {
-		args = append(args, "--organization", config.Organization)
+// GetTFERun retrieves a single TFE run
+func (a *App) GetTFERun(config TFEConfig, runID string) (*TFERun, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("run ID is required")
 	}
-	
-	// Add workspaces
-	if len(workspaceNames) > 0 {
-		args = append(args, "--workspaces", strings.Join(workspaceNames, ","))
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Add check status flag
-	if checkStatus {
-		args = append(args, "--check-status")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := client.getRun(ctx, config.Organization, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TFE run %s: %w", runID, err)
 	}
-	
-	// Execute command
+	return &run, nil
+}
+
+// GetTFEPlanLog retrieves the plan log of a TFE run with ANSI colors stripped
+func (a *App) GetTFEPlanLog(config TFEConfig, runID string) (string, error) {
+	return a.getTFERunLog(config, runID, "plan")
+}
+
+// GetTFEApplyLog retrieves the apply log of a TFE run with ANSI colors stripped
+func (a *App) GetTFEApplyLog(config TFEConfig, runID string) (string, error) {
+	return a.getTFERunLog(config, runID, "apply")
+}
+
+func (a *App) getTFERunLog(config TFEConfig, runID, phase string) (string, error) {
+	if runID == "" {
+		return "", fmt.Errorf("run ID is required")
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return "", err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
-	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
-		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
-	)
-	
-	output, err := cmd.CombinedOutput()
+
+	log, err := client.getRunLog(ctx, runID, phase)
 	if err != nil {
-		return fmt.Errorf("failed to lock TFE workspace: %w - %s", err, string(output))
+		return "", fmt.Errorf("failed to get %s log for TFE run %s: %w", phase, runID, err)
 	}
-	
-	return nil
+	return log, nil
 }
 
-// UnlockTFEWorkspace unlocks a TFE workspace
-func (a *App) UnlockTFEWorkspace(config TFEConfig, workspaceNames []string, force bool) error {
-	// Build yak command
-	args := []string{"tfe", "workspace", "unlock"}
-	
-	// Add organization if specified
-	if config.Organization != "" {
-		args = append(args, "--organization", config.Organization)
-	}
-	
-	// Add workspaces
-	if len(workspaceNames) > 0 {
-		args = append(args, "--workspaces", strings.Join(workspaceNames, ","))
+// ApplyTFERun confirms a planned TFE run
+func (a *App) ApplyTFERun(config TFEConfig, runID string, comment string) error {
+	return a.tfeRunAction(config, runID, "apply", comment, func(run TFERun) bool { return run.Actions.IsConfirmable })
+}
+
+// CancelTFERun cancels a pending or running TFE run
+func (a *App) CancelTFERun(config TFEConfig, runID string, comment string) error {
+	return a.tfeRunAction(config, runID, "cancel", comment, func(run TFERun) bool { return run.Actions.IsCancelable })
+}
+
+// DiscardTFERun discards a planned TFE run
+func (a *App) DiscardTFERun(config TFEConfig, runID string, comment string) error {
+	return a.tfeRunAction(config, runID, "discard", comment, func(run TFERun) bool { return run.Actions.IsDiscardable })
+}
+
+// tfeRunAction checks that the run currently allows the action before triggering it
+func (a *App) tfeRunAction(config TFEConfig, runID, action, comment string, allowed func(TFERun) bool) error {
+	if runID == "" {
+		return fmt.Errorf("run ID is required")
 	}
-	
-	// Add force flag
-	if force {
-		args = append(args, "--force")
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return err
 	}
-	
-	// Execute command
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
-		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
-	)
-	
-	output, err := cmd.CombinedOutput()
+
+	run, err := client.getRun(ctx, config.Organization, runID)
 	if err != nil {
-		return fmt.Errorf("failed to unlock TFE workspace: %w - %s", err, string(output))
+		return fmt.Errorf("failed to get TFE run %s: %w", runID, err)
+	}
+	if !allowed(run) {
+		return fmt.Errorf("TFE run %s does not allow %s in status %s", runID, action, run.Status)
+	}
+
+	if err := client.runAction(ctx, runID, action, comment); err != nil {
+		return fmt.Errorf("failed to %s TFE run %s: %w", action, runID, err)
 	}
-	
 	return nil
 }
 
 // SetTFEWorkspaceVersion sets the Terraform version for TFE workspaces
 func (a *App) SetTFEWorkspaceVersion(config TFEConfig, workspaceNames []string, version string) error {
+	return a.setTFEWorkspaceVersion(context.Background(), config, workspaceNames, version)
+}
+
+// setTFEWorkspaceVersion runs SetTFEWorkspaceVersion with the environment of ctx
+func (a *App) setTFEWorkspaceVersion(ctx context.Context, config TFEConfig, workspaceNames []string, version string) error {
 	// Build yak command
 	args := []string{"tfe", "workspace", "set-version"}
 	
@@ -415,13 +415,13 @@ func (a *App) SetTFEWorkspaceVersion(config TFEConfig, workspaceNames []string,
 	args = append(args, "--version", version)
 	
 	// Execute command
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
 		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
 	)
@@ -464,10 +464,10 @@ func (a *App) DiscardTFERuns(config TFEConfig, ageHours int, discardPending bool
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second) // 5 minutes timeout
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
 		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
 	)
@@ -494,10 +494,10 @@ func (a *App) GetTFEVersions(config TFEConfig) ([]TFEVersionInfo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
+	cmd := a.yakCommand(ctx, args...)
 	
 	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
 		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
 	)
@@ -514,96 +514,3 @@ func (a *App) GetTFEVersions(config TFEConfig) ([]TFEVersionInfo, error) {
 	
 	return versions, nil
 }
-
-// CheckTFEDeprecatedVersions checks for workspaces using deprecated Terraform versions
-func (a *App) CheckTFEDeprecatedVersions(config TFEConfig, versionFile string, teamsFile string, sendEmail bool) (map[string]interface{}, error) {
-	// Build yak command
-	args := []string{"tfe", "check-versions"}
-	
-	// Add organization if specified
-	if config.Organization != "" {
-		args = append(args, "--organization", config.Organization)
-	}
-	
-	// Add required files
-	args = append(args, "--file", versionFile)
-	args = append(args, "--teams", teamsFile)
-	
-	// Add send email flag
-	if sendEmail {
-		args = append(args, "--send-email")
-	}
-	
-	// Add JSON output
-	args = append(args, "--json")
-	
-	// Execute command
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
-	
-	cmd := exec.CommandContext(ctx, findYakExecutable(), args...)
-	
-	// Set environment variables for TFE authentication and ensure proper environment
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
-		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
-	)
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to check TFE deprecated versions: %w - %s", err, string(output))
-	}
-	
-	var result map[string]interface{}
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse TFE deprecated versions response: %w", err)
-	}
-	
-	return result, nil
-}
-
-// GetTFEConfig retrieves TFE configuration from environment variables
-func (a *App) GetTFEConfig() (TFEConfig, error) {
-	config := TFEConfig{
-		Endpoint:     "tfe.doctolib.net", // Default TFE endpoint for Doctolib
-		Organization: "doctolib",         // Default organization
-	}
-	
-	// Get environment variables
-	env := a.GetEnvironmentVariables()
-	
-	// Override with environment variables if present
-	if endpoint := env["TFE_ENDPOINT"]; endpoint != "" {
-		config.Endpoint = endpoint
-	}
-	
-	if org := env["TFE_ORGANIZATION"]; org != "" {
-		config.Organization = org
-	}
-	
-	if token := env["TFE_TOKEN"]; token != "" {
-		config.Token = token
-	}
-	
-	// Also check for TF_TOKEN_<hostname> format
-	if config.Token == "" {
-		// Extract hostname from endpoint for token lookup
-		hostname := config.Endpoint
-		if hostname != "" {
-			// Try TF_TOKEN_<hostname> format (replace dots with underscores)
-			tokenKey := "TF_TOKEN_" + strings.ReplaceAll(hostname, ".", "_")
-			if token := env[tokenKey]; token != "" {
-				config.Token = token
-			}
-		}
-	}
-	
-	return config, nil
-}
-
-// SetTFEConfig sets TFE configuration in environment variables
-func (a *App) SetTFEConfig(config TFEConfig) error {
-	// Note: In a real application, you might want to store this more securely
-	// For now, we'll just return nil as the config is passed from the frontend
-	return nil
-}
\ No newline at end of file
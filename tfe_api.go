@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tfeRunsPageSize is the number of recent runs listed for a workspace
+const tfeRunsPageSize = 20
+
+// tfeClient is a minimal client for the TFE v2 (JSON:API) REST API
+type tfeClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// tfeResource is a JSON:API resource object, attributes are decoded by the caller
+type tfeResource struct {
+	ID            string                     `json:"id"`
+	Type          string                     `json:"type"`
+	Attributes    json.RawMessage            `json:"attributes,omitempty"`
+	Relationships map[string]tfeRelationship `json:"relationships,omitempty"`
+}
+
+// tfeRelationship is a JSON:API to-one relationship
+type tfeRelationship struct {
+	Data *tfeResourceRef `json:"data"`
+}
+
+// tfeResourceRef identifies a related JSON:API resource
+type tfeResourceRef struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// tfeListResponse is a paginated JSON:API collection
+type tfeListResponse struct {
+	Data     []tfeResource `json:"data"`
+	Included []tfeResource `json:"included,omitempty"`
+	Meta     struct {
+		Pagination struct {
+			CurrentPage int `json:"current-page"`
+			NextPage    int `json:"next-page"`
+			TotalPages  int `json:"total-pages"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// tfeSingleResponse is a JSON:API document holding a single resource
+type tfeSingleResponse struct {
+	Data     tfeResource   `json:"data"`
+	Included []tfeResource `json:"included,omitempty"`
+}
+
+// tfeAPIError is returned when the TFE API answers with a non-2xx status
+type tfeAPIError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func (e *tfeAPIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("TFE API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("TFE API returned status %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+// newTFEClient creates an API client from the TFE configuration
+func newTFEClient(config TFEConfig) (*tfeClient, error) {
+	if config.Endpoint == "" {
+		return nil, &SiteNotConfiguredError{Setting: "tfe.endpoint"}
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("TFE token is required")
+	}
+
+	baseURL := strings.TrimSuffix(config.Endpoint, "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "https://" + baseURL
+	}
+
+	return &tfeClient{
+		baseURL:    baseURL,
+		token:      config.Token,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// do performs an API request against a path below /api/v2 and decodes the JSON response into out
+func (c *tfeClient) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	endpoint := c.baseURL + "/api/v2" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal TFE request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create TFE request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.api+json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("TFE request %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read TFE response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &tfeAPIError{StatusCode: resp.StatusCode}
+		var errorDoc struct {
+			Errors []struct {
+				Title  string `json:"title"`
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		if json.Unmarshal(data, &errorDoc) == nil {
+			for _, e := range errorDoc.Errors {
+				if e.Detail != "" {
+					apiErr.Messages = append(apiErr.Messages, e.Detail)
+				} else if e.Title != "" {
+					apiErr.Messages = append(apiErr.Messages, e.Title)
+				}
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse TFE response: %w", err)
+	}
+	return nil
+}
+
+// list fetches every page of a JSON:API collection
+func (c *tfeClient) list(ctx context.Context, path string, query url.Values) ([]tfeResource, []tfeResource, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("page[size]", "100")
+
+	var data, included []tfeResource
+	page := 1
+	for {
+		query.Set("page[number]", fmt.Sprintf("%d", page))
+
+		var resp tfeListResponse
+		if err := c.do(ctx, http.MethodGet, path, query, nil, &resp); err != nil {
+			return nil, nil, err
+		}
+		data = append(data, resp.Data...)
+		included = append(included, resp.Included...)
+
+		if resp.Meta.Pagination.NextPage == 0 || resp.Meta.Pagination.NextPage <= page {
+			break
+		}
+		page = resp.Meta.Pagination.NextPage
+	}
+
+	return data, included, nil
+}
+
+// tfeWorkspaceAttributes are the workspace attributes used by the GUI
+type tfeWorkspaceAttributes struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	TerraformVersion string   `json:"terraform-version"`
+	AutoApply        bool     `json:"auto-apply"`
+	Locked           bool     `json:"locked"`
+	TagNames         []string `json:"tag-names"`
+	CreatedAt        string   `json:"created-at"`
+	UpdatedAt        string   `json:"updated-at"`
+	VCSRepo          *struct {
+		Identifier        string `json:"identifier"`
+		Branch            string `json:"branch"`
+		IngressSubmodules bool   `json:"ingress-submodules"`
+	} `json:"vcs-repo"`
+}
+
+// toTFEWorkspace maps a workspace resource into the frontend representation
+func (r tfeResource) toTFEWorkspace(organization string) (TFEWorkspace, error) {
+	var attrs tfeWorkspaceAttributes
+	if err := json.Unmarshal(r.Attributes, &attrs); err != nil {
+		return TFEWorkspace{}, fmt.Errorf("failed to parse workspace %s: %w", r.ID, err)
+	}
+
+	workspace := TFEWorkspace{
+		ID:               r.ID,
+		Name:             attrs.Name,
+		Description:      attrs.Description,
+		TerraformVersion: attrs.TerraformVersion,
+		Status:           "active",
+		Tags:             attrs.TagNames,
+		Organization:     organization,
+		CreatedAt:        attrs.CreatedAt,
+		UpdatedAt:        attrs.UpdatedAt,
+		AutoApply:        attrs.AutoApply,
+	}
+	if workspace.Tags == nil {
+		workspace.Tags = []string{}
+	}
+	if attrs.Locked {
+		workspace.Status = "locked"
+	}
+	if run, ok := r.Relationships["current-run"]; ok && run.Data != nil {
+		workspace.LastRun = run.Data.ID
+	}
+	if attrs.VCSRepo != nil {
+		workspace.VCSRepo = &TFEVCSRepo{
+			Identifier:        attrs.VCSRepo.Identifier,
+			Branch:            attrs.VCSRepo.Branch,
+			IngressSubmodules: attrs.VCSRepo.IngressSubmodules,
+		}
+	}
+
+	return workspace, nil
+}
+
+// listWorkspaces returns all workspaces of an organization, optionally filtered with API search parameters
+func (c *tfeClient) listWorkspaces(ctx context.Context, organization string, query url.Values) ([]TFEWorkspace, error) {
+	if organization == "" {
+		return nil, &SiteNotConfiguredError{Setting: "tfe.organization"}
+	}
+
+	resources, _, err := c.list(ctx, "/organizations/"+url.PathEscape(organization)+"/workspaces", query)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]TFEWorkspace, 0, len(resources))
+	for _, resource := range resources {
+		workspace, err := resource.toTFEWorkspace(organization)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, nil
+}
+
+// tfeRunAttributes are the run attributes used by the GUI
+type tfeRunAttributes struct {
+	Status           string `json:"status"`
+	CreatedAt        string `json:"created-at"`
+	Message          string `json:"message"`
+	Source           string `json:"source"`
+	TerraformVersion string `json:"terraform-version"`
+	HasChanges       bool   `json:"has-changes"`
+	IsDestroy        bool   `json:"is-destroy"`
+	RefreshOnly      bool   `json:"refresh-only"`
+	Actions          struct {
+		IsConfirmable bool `json:"is-confirmable"`
+		IsCancelable  bool `json:"is-cancelable"`
+		IsDiscardable bool `json:"is-discardable"`
+	} `json:"actions"`
+}
+
+// toTFERun maps a run resource into the frontend representation
+func (r tfeResource) toTFERun(c *tfeClient, organization, workspaceName string, included []tfeResource) (TFERun, error) {
+	var attrs tfeRunAttributes
+	if err := json.Unmarshal(r.Attributes, &attrs); err != nil {
+		return TFERun{}, fmt.Errorf("failed to parse run %s: %w", r.ID, err)
+	}
+
+	run := TFERun{
+		ID:               r.ID,
+		WorkspaceName:    workspaceName,
+		Status:           attrs.Status,
+		CreatedAt:        attrs.CreatedAt,
+		Message:          attrs.Message,
+		Source:           attrs.Source,
+		TerraformVersion: attrs.TerraformVersion,
+		HasChanges:       attrs.HasChanges,
+		IsDestroy:        attrs.IsDestroy,
+		RefreshOnly:      attrs.RefreshOnly,
+		IsConfirmable:    attrs.Actions.IsConfirmable,
+	}
+	run.Actions.IsConfirmable = attrs.Actions.IsConfirmable
+	run.Actions.IsCancelable = attrs.Actions.IsCancelable
+	run.Actions.IsDiscardable = attrs.Actions.IsDiscardable
+
+	if ws, ok := r.Relationships["workspace"]; ok && ws.Data != nil {
+		run.WorkspaceID = ws.Data.ID
+	}
+	if user, ok := r.Relationships["created-by"]; ok && user.Data != nil {
+		run.CreatedBy = user.Data.ID
+		for _, inc := range included {
+			if inc.Type == "users" && inc.ID == user.Data.ID {
+				var userAttrs struct {
+					Username string `json:"username"`
+				}
+				if json.Unmarshal(inc.Attributes, &userAttrs) == nil && userAttrs.Username != "" {
+					run.CreatedBy = userAttrs.Username
+				}
+			}
+		}
+	}
+	if organization != "" && workspaceName != "" {
+		run.URL = fmt.Sprintf("%s/app/%s/workspaces/%s/runs/%s", c.baseURL, organization, workspaceName, r.ID)
+	}
+
+	return run, nil
+}
+
+// getWorkspace fetches a workspace by ID ("ws-...") or by name within the organization
+func (c *tfeClient) getWorkspace(ctx context.Context, organization, workspace string) (TFEWorkspace, error) {
+	path := "/workspaces/" + url.PathEscape(workspace)
+	if !strings.HasPrefix(workspace, "ws-") {
+		if organization == "" {
+			return TFEWorkspace{}, fmt.Errorf("cannot look up workspace %s: %w", workspace, &SiteNotConfiguredError{Setting: "tfe.organization"})
+		}
+		path = "/organizations/" + url.PathEscape(organization) + "/workspaces/" + url.PathEscape(workspace)
+	}
+
+	var resp tfeSingleResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &resp); err != nil {
+		return TFEWorkspace{}, err
+	}
+	return resp.Data.toTFEWorkspace(organization)
+}
+
+// listRuns returns the limit most recent runs of a workspace, most recent first. Only the first page is
+// fetched, the run history of a busy workspace has thousands of entries.
+func (c *tfeClient) listRuns(ctx context.Context, organization string, workspace TFEWorkspace, limit int) ([]TFERun, error) {
+	query := url.Values{}
+	query.Set("include", "created_by")
+	query.Set("page[size]", fmt.Sprintf("%d", limit))
+	query.Set("page[number]", "1")
+
+	var resp tfeListResponse
+	if err := c.do(ctx, http.MethodGet, "/workspaces/"+url.PathEscape(workspace.ID)+"/runs", query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	runs := make([]TFERun, 0, len(resp.Data))
+	for _, resource := range resp.Data {
+		run, err := resource.toTFERun(c, organization, workspace.Name, resp.Included)
+		if err != nil {
+			return nil, err
+		}
+		if run.WorkspaceID == "" {
+			run.WorkspaceID = workspace.ID
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// getRun fetches a single run
+func (c *tfeClient) getRun(ctx context.Context, organization, runID string) (TFERun, error) {
+	query := url.Values{}
+	query.Set("include", "created_by,workspace")
+
+	var resp tfeSingleResponse
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(runID), query, nil, &resp); err != nil {
+		return TFERun{}, err
+	}
+
+	workspaceName := ""
+	if ws, ok := resp.Data.Relationships["workspace"]; ok && ws.Data != nil {
+		for _, inc := range resp.Included {
+			if inc.Type == "workspaces" && inc.ID == ws.Data.ID {
+				var wsAttrs struct {
+					Name string `json:"name"`
+				}
+				if json.Unmarshal(inc.Attributes, &wsAttrs) == nil {
+					workspaceName = wsAttrs.Name
+				}
+			}
+		}
+	}
+
+	return resp.Data.toTFERun(c, organization, workspaceName, resp.Included)
+}
+
+// runAction triggers apply, cancel or discard on a run
+func (c *tfeClient) runAction(ctx context.Context, runID, action, comment string) error {
+	var body interface{}
+	if comment != "" {
+		body = map[string]string{"comment": comment}
+	}
+	return c.do(ctx, http.MethodPost, "/runs/"+url.PathEscape(runID)+"/actions/"+action, nil, body, nil)
+}
+
+// getRunLog downloads the plan or apply log of a run, phase is "plan" or "apply"
+func (c *tfeClient) getRunLog(ctx context.Context, runID, phase string) (string, error) {
+	var resp tfeSingleResponse
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(runID)+"/"+phase, nil, nil, &resp); err != nil {
+		return "", err
+	}
+
+	var attrs struct {
+		LogReadURL string `json:"log-read-url"`
+	}
+	if err := json.Unmarshal(resp.Data.Attributes, &attrs); err != nil {
+		return "", fmt.Errorf("failed to parse %s of run %s: %w", phase, runID, err)
+	}
+	if attrs.LogReadURL == "" {
+		return "", fmt.Errorf("no %s log available for run %s", phase, runID)
+	}
+
+	// Log URLs are pre-signed archivist links and must not receive the API token
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attrs.LogReadURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create log request: %w", err)
+	}
+	logResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s log: %w", phase, err)
+	}
+	defer logResp.Body.Close()
+
+	if logResp.StatusCode < 200 || logResp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to download %s log: status %s", phase, logResp.Status)
+	}
+
+	data, err := io.ReadAll(logResp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s log: %w", phase, err)
+	}
+	return stripANSI(string(data)), nil
+}
+
+// ansiEscapePattern matches ANSI escape sequences (colors, cursor movement)
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b[@-Z\\-_]`)
+
+// stripANSI removes ANSI escape sequences and the STX/ETX markers TFE wraps logs in
+func stripANSI(s string) string {
+	s = ansiEscapePattern.ReplaceAllString(s, "")
+	return strings.NewReplacer("\x02", "", "\x03", "").Replace(s)
+}
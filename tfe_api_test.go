@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTFEServer starts an httptest stand-in for the TFE API and returns a config pointing at it
+func newTestTFEServer(t *testing.T, mux *http.ServeMux) TFEConfig {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"errors": []map[string]string{{"title": "unauthorized"}},
+			})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return TFEConfig{
+		Endpoint:     server.URL,
+		Organization: "acme",
+		Token:        "test-token",
+	}
+}
+
+// writeJSONAPI writes a JSON:API document
+func writeJSONAPI(w http.ResponseWriter, doc interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// TestGetTFEWorkspacesFromAPI verifies workspace details are fetched and mapped across pages
+func TestGetTFEWorkspacesFromAPI(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installSiteConfig(t, home, doctolibSiteConfig)
+	app := NewApp()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page[number]") {
+		case "1":
+			writeJSONAPI(w, map[string]interface{}{
+				"data": []map[string]interface{}{{
+					"id":   "ws-1",
+					"type": "workspaces",
+					"attributes": map[string]interface{}{
+						"name":              "network-prod-aws-fr-par-1",
+						"terraform-version": "1.5.7",
+						"auto-apply":        true,
+						"locked":            true,
+						"tag-names":         []string{"owner:platform", "network"},
+						"vcs-repo": map[string]interface{}{
+							"identifier": "acme/terraform-infra",
+							"branch":     "main",
+						},
+					},
+					"relationships": map[string]interface{}{
+						"current-run": map[string]interface{}{"data": map[string]string{"id": "run-1", "type": "runs"}},
+					},
+				}},
+				"meta": map[string]interface{}{"pagination": map[string]int{"current-page": 1, "next-page": 2, "total-pages": 2}},
+			})
+		default:
+			writeJSONAPI(w, map[string]interface{}{
+				"data": []map[string]interface{}{{
+					"id":         "ws-2",
+					"type":       "workspaces",
+					"attributes": map[string]interface{}{"name": "tooling-staging", "terraform-version": "1.3.0"},
+				}},
+				"meta": map[string]interface{}{"pagination": map[string]int{"current-page": 2, "total-pages": 2}},
+			})
+		}
+	})
+	config := newTestTFEServer(t, mux)
+
+	workspaces, err := app.GetTFEWorkspaces(config)
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+
+	assert.Equal(t, "ws-1", workspaces[0].ID)
+	assert.Equal(t, "1.5.7", workspaces[0].TerraformVersion)
+	assert.Equal(t, "locked", workspaces[0].Status)
+	assert.True(t, workspaces[0].AutoApply)
+	assert.Equal(t, "run-1", workspaces[0].LastRun)
+	assert.Equal(t, "platform", workspaces[0].Owner)
+	assert.Equal(t, "prod-aws-fr-par-1", workspaces[0].Environment)
+	require.NotNil(t, workspaces[0].VCSRepo)
+	assert.Equal(t, "acme/terraform-infra", workspaces[0].VCSRepo.Identifier)
+
+	assert.Equal(t, "active", workspaces[1].Status)
+	assert.Equal(t, "tooling-team", workspaces[1].Owner)
+	assert.Equal(t, "staging", workspaces[1].Environment)
+}
+
+// TestTFEResolverConfigPerOrganization verifies that saved resolver rules replace the defaults
+func TestTFEResolverConfigPerOrganization(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installSiteConfig(t, home, doctolibSiteConfig)
+	app := NewApp()
+
+	require.NoError(t, app.SaveTFEResolverConfig("acme", TFEResolverConfig{
+		EnvironmentRules: []TFEResolverRule{{Pattern: `^(\w+)-`, Value: "env-$1"}},
+		OwnerRules:       []TFEResolverRule{{Pattern: "billing", Value: "payments"}},
+	}))
+	assert.Error(t, app.SaveTFEResolverConfig("acme", TFEResolverConfig{
+		OwnerRules: []TFEResolverRule{{Pattern: "(", Value: "broken"}},
+	}))
+
+	resolver, err := app.tfeResolverFor("acme")
+	require.NoError(t, err)
+	workspace := TFEWorkspace{Name: "eu-billing-api"}
+	resolver.Resolve(&workspace)
+	assert.Equal(t, "env-eu", workspace.Environment)
+	assert.Equal(t, "payments", workspace.Owner)
+
+	resolver, err = app.tfeResolverFor("other")
+	require.NoError(t, err)
+	workspace = TFEWorkspace{Name: "app-preprod-aws-de-fra-1"}
+	resolver.Resolve(&workspace)
+	assert.Equal(t, "preprod-aws-de-fra-1", workspace.Environment)
+	assert.Equal(t, "unknown", workspace.Owner)
+}
+
+// TestTFEAPIErrors verifies API errors are surfaced with their details
+func TestTFEAPIErrors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	config := newTestTFEServer(t, http.NewServeMux())
+	config.Token = "wrong-token"
+
+	_, err := app.GetTFEWorkspaces(config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Contains(t, err.Error(), "unauthorized")
+}
+
+// testRunResource returns a JSON:API run document for the TFE stand-in
+func testRunResource(id, status string, confirmable, cancelable, discardable bool) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   id,
+		"type": "runs",
+		"attributes": map[string]interface{}{
+			"status":      status,
+			"created-at":  "2024-01-01T10:00:00Z",
+			"message":     "Triggered via UI",
+			"source":      "tfe-ui",
+			"has-changes": true,
+			"actions": map[string]bool{
+				"is-confirmable": confirmable,
+				"is-cancelable":  cancelable,
+				"is-discardable": discardable,
+			},
+		},
+		"relationships": map[string]interface{}{
+			"workspace":  map[string]interface{}{"data": map[string]string{"id": "ws-1", "type": "workspaces"}},
+			"created-by": map[string]interface{}{"data": map[string]string{"id": "user-1", "type": "users"}},
+		},
+	}
+}
+
+// TestTFERunsLogsAndActions verifies run listing, log retrieval and action guards against the TFE stand-in
+func TestTFERunsLogsAndActions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	var actions []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/network", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{"id": "ws-1", "type": "workspaces", "attributes": map[string]string{"name": "network"}},
+		})
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1/runs", func(w http.ResponseWriter, r *http.Request) {
+		// Only the most recent page is requested, the next one must not be followed
+		assert.Equal(t, "20", r.URL.Query().Get("page[size]"))
+		assert.Equal(t, "1", r.URL.Query().Get("page[number]"))
+		writeJSONAPI(w, map[string]interface{}{
+			"meta": map[string]interface{}{"pagination": map[string]int{"current-page": 1, "next-page": 2, "total-pages": 40}},
+			"data": []interface{}{
+				testRunResource("run-planned", "planned", true, false, true),
+				testRunResource("run-applied", "applied", false, false, false),
+			},
+			"included": []interface{}{
+				map[string]interface{}{"id": "user-1", "type": "users", "attributes": map[string]string{"username": "jdoe"}},
+			},
+		})
+	})
+	runs := map[string]map[string]interface{}{
+		"run-planned": testRunResource("run-planned", "planned", true, false, true),
+		"run-applied": testRunResource("run-applied", "applied", false, false, false),
+	}
+	for id, run := range runs {
+		run := run
+		mux.HandleFunc("/api/v2/runs/"+id, func(w http.ResponseWriter, r *http.Request) {
+			writeJSONAPI(w, map[string]interface{}{"data": run})
+		})
+		mux.HandleFunc("/api/v2/runs/"+id+"/actions/", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			actions = append(actions, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		})
+	}
+	config := newTestTFEServer(t, mux)
+
+	// Logs are served by a separate archivist stand-in with pre-signed URLs and no API token
+	archivist := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte("\x02\x1b[1m\x1b[32mPlan:\x1b[0m 1 to add, 0 to change, 0 to destroy.\x03"))
+	}))
+	defer archivist.Close()
+	mux.HandleFunc("/api/v2/runs/run-planned/plan", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"id":         "plan-1",
+				"type":       "plans",
+				"attributes": map[string]string{"log-read-url": archivist.URL + "/v1/object/plan-1"},
+			},
+		})
+	})
+
+	result, err := app.GetTFERuns(config, "network")
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "run-planned", result[0].ID)
+	assert.Equal(t, "ws-1", result[0].WorkspaceID)
+	assert.Equal(t, "network", result[0].WorkspaceName)
+	assert.Equal(t, "jdoe", result[0].CreatedBy)
+	assert.True(t, result[0].Actions.IsConfirmable)
+	assert.True(t, result[0].IsConfirmable)
+	assert.Equal(t, config.Endpoint+"/app/acme/workspaces/network/runs/run-planned", result[0].URL)
+
+	log, err := app.GetTFEPlanLog(config, "run-planned")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 1 to add, 0 to change, 0 to destroy.", log)
+
+	require.NoError(t, app.ApplyTFERun(config, "run-planned", "LGTM"))
+	require.NoError(t, app.DiscardTFERun(config, "run-planned", ""))
+	assert.Error(t, app.CancelTFERun(config, "run-planned", ""))
+	assert.Error(t, app.ApplyTFERun(config, "run-applied", ""))
+
+	assert.Equal(t, []string{
+		"/api/v2/runs/run-planned/actions/apply",
+		"/api/v2/runs/run-planned/actions/discard",
+	}, actions)
+}
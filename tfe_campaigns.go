@@ -0,0 +1,593 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+const tfeCampaignsFile = "tfe-campaigns.json"
+
+// tfeCampaignUpgradeTimeout bounds the version changes that finish a paused wave, so that pausing or quitting
+// cannot wait on a hung TFE. Workspaces left over are marked upgrade-failed and can be retried.
+var tfeCampaignUpgradeTimeout = 2 * time.Minute
+
+// Campaign workspace statuses
+const (
+	campaignWorkspacePending       = "pending"
+	campaignWorkspacePlanning      = "planning"
+	campaignWorkspaceNeedsReview   = "needs-review" // plan has changes, version is not changed automatically
+	campaignWorkspacePlanFailed    = "plan-failed"
+	campaignWorkspaceUpgrading     = "upgrading"
+	campaignWorkspaceUpgraded      = "upgraded"
+	campaignWorkspaceUpgradeFailed = "upgrade-failed"
+	campaignWorkspaceSkipped       = "skipped"
+)
+
+// TFEWorkspaceSelector selects the workspaces of a campaign, all set criteria must match
+type TFEWorkspaceSelector struct {
+	Tag          string   `json:"tag,omitempty"`
+	Owner        string   `json:"owner,omitempty"`
+	NamePattern  string   `json:"namePattern,omitempty"` // shell glob, e.g. "*-prod-*"
+	FromVersions []string `json:"fromVersions,omitempty"`
+	Workspaces   []string `json:"workspaces,omitempty"`
+}
+
+// TFECampaignRequest describes a Terraform version upgrade campaign to create
+type TFECampaignRequest struct {
+	Name          string               `json:"name"`
+	TargetVersion string               `json:"targetVersion"`
+	Selector      TFEWorkspaceSelector `json:"selector"`
+	WaveSize      int                  `json:"waveSize"`
+}
+
+// TFECampaignWorkspace tracks a workspace through a campaign
+type TFECampaignWorkspace struct {
+	Name            string          `json:"name"`
+	ID              string          `json:"id"`
+	PreviousVersion string          `json:"previousVersion,omitempty"`
+	Wave            int             `json:"wave"`
+	Status          string          `json:"status"`
+	RunID           string          `json:"runId,omitempty"`
+	RunURL          string          `json:"runUrl,omitempty"`
+	Summary         *TFEPlanSummary `json:"summary,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+// TFECampaign represents a persisted Terraform version upgrade campaign
+type TFECampaign struct {
+	ID            string                 `json:"id"`
+	Name          string                 `json:"name"`
+	Organization  string                 `json:"organization"`
+	Profile       string                 `json:"profile,omitempty"` // environment profile the running or last wave was started under
+	TargetVersion string                 `json:"targetVersion"`
+	Selector      TFEWorkspaceSelector   `json:"selector"`
+	WaveSize      int                    `json:"waveSize"`
+	Waves         int                    `json:"waves"`
+	Status        string                 `json:"status"` // idle, running, completed
+	Workspaces    []TFECampaignWorkspace `json:"workspaces"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	UpdatedAt     time.Time              `json:"updatedAt"`
+}
+
+// tfeCampaignManager runs campaign waves in the background and persists every workspace transition
+type tfeCampaignManager struct {
+	app     *App
+	mu      sync.Mutex
+	running map[string]*runningTFECampaign
+
+	// setVersion changes the Terraform version of a workspace, it is replaceable for tests
+	setVersion func(ctx context.Context, config TFEConfig, workspace, version string) error
+}
+
+type runningTFECampaign struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newTFECampaignManager creates a campaign manager that upgrades workspaces through SetTFEWorkspaceVersion
+func newTFECampaignManager(app *App) *tfeCampaignManager {
+	return &tfeCampaignManager{
+		app:     app,
+		running: make(map[string]*runningTFECampaign),
+		setVersion: func(ctx context.Context, config TFEConfig, workspace, version string) error {
+			return app.setTFEWorkspaceVersion(ctx, config, []string{workspace}, version)
+		},
+	}
+}
+
+// isTerminal reports whether the workspace needs no further automatic action
+func (w TFECampaignWorkspace) isTerminal() bool {
+	return w.Status != campaignWorkspacePending && w.Status != campaignWorkspacePlanning && w.Status != campaignWorkspaceUpgrading
+}
+
+// matches reports whether a workspace satisfies every criterion of the selector
+func (s TFEWorkspaceSelector) matches(workspace TFEWorkspace) bool {
+	if len(s.Workspaces) > 0 && !containsString(s.Workspaces, workspace.Name) {
+		return false
+	}
+	if s.Tag != "" && !containsString(workspace.Tags, s.Tag) {
+		return false
+	}
+	if s.Owner != "" && workspace.Owner != s.Owner {
+		return false
+	}
+	if s.NamePattern != "" {
+		if ok, _ := path.Match(s.NamePattern, workspace.Name); !ok {
+			return false
+		}
+	}
+	if len(s.FromVersions) > 0 && !containsString(s.FromVersions, workspace.TerraformVersion) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads all campaigns from disk, the caller must hold m.mu
+func (m *tfeCampaignManager) load() ([]TFECampaign, error) {
+	var campaigns []TFECampaign
+	if _, err := loadStateFile(tfeCampaignsFile, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// update applies fn to a campaign and persists the result
+func (m *tfeCampaignManager) update(id string, fn func(c *TFECampaign) error) (*TFECampaign, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var campaigns []TFECampaign
+	var campaign TFECampaign
+	err := updateStateFile(tfeCampaignsFile, &campaigns, func() error {
+		for i := range campaigns {
+			if campaigns[i].ID != id {
+				continue
+			}
+			if err := fn(&campaigns[i]); err != nil {
+				return err
+			}
+			campaigns[i].UpdatedAt = time.Now()
+			campaign = campaigns[i]
+			return nil
+		}
+		return fmt.Errorf("TFE campaign '%s' not found", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.app.emitEvent("tfe-campaign:update", campaign)
+	return &campaign, nil
+}
+
+// updateWorkspace applies fn to a single workspace of a campaign
+func (m *tfeCampaignManager) updateWorkspace(id, workspace string, fn func(w *TFECampaignWorkspace)) error {
+	_, err := m.update(id, func(c *TFECampaign) error {
+		for i := range c.Workspaces {
+			if c.Workspaces[i].Name == workspace {
+				fn(&c.Workspaces[i])
+				c.Workspaces[i].UpdatedAt = time.Now()
+				return nil
+			}
+		}
+		return fmt.Errorf("workspace %s is not part of campaign %s", workspace, id)
+	})
+	return err
+}
+
+// recover resets workspaces that were in flight when the application last stopped
+func (m *tfeCampaignManager) recover() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaigns, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range campaigns {
+		if campaigns[i].Status != "running" || m.running[campaigns[i].ID] != nil {
+			continue
+		}
+		campaigns[i].Status = "idle"
+		for j := range campaigns[i].Workspaces {
+			ws := &campaigns[i].Workspaces[j]
+			switch ws.Status {
+			case campaignWorkspacePlanning:
+				ws.Status = campaignWorkspacePending
+			case campaignWorkspaceUpgrading:
+				// The version change may or may not have been applied, let the operator check
+				ws.Status = campaignWorkspaceUpgradeFailed
+				ws.Error = "interrupted by application restart, verify the workspace version"
+			}
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return saveStateFile(tfeCampaignsFile, campaigns)
+}
+
+// stopAll cancels running waves and waits for them to persist their state
+func (m *tfeCampaignManager) stopAll() {
+	m.mu.Lock()
+	var running []*runningTFECampaign
+	for _, r := range m.running {
+		running = append(running, r)
+	}
+	m.mu.Unlock()
+
+	for _, r := range running {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// CreateTFECampaign selects workspaces for a Terraform version upgrade and splits them into waves
+func (a *App) CreateTFECampaign(config TFEConfig, request TFECampaignRequest) (*TFECampaign, error) {
+	if request.TargetVersion == "" {
+		return nil, fmt.Errorf("target version is required")
+	}
+	if request.WaveSize <= 0 {
+		request.WaveSize = 10
+	}
+	if request.Name == "" {
+		request.Name = fmt.Sprintf("Upgrade to Terraform %s", request.TargetVersion)
+	}
+
+	versions, err := a.GetTFEVersions(config)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, v := range versions {
+		if v.Version == request.TargetVersion {
+			if v.Status == "disabled" || v.Status == "deprecated" {
+				return nil, fmt.Errorf("terraform version %s is %s", v.Version, v.Status)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("terraform version %s is not available in TFE", request.TargetVersion)
+	}
+
+	workspaces, err := a.GetTFEWorkspaces(config)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	campaign := TFECampaign{
+		ID:            fmt.Sprintf("campaign-%d", now.UnixNano()),
+		Name:          request.Name,
+		Organization:  config.Organization,
+		TargetVersion: request.TargetVersion,
+		Selector:      request.Selector,
+		WaveSize:      request.WaveSize,
+		Status:        "idle",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	for _, ws := range workspaces {
+		if !request.Selector.matches(ws) {
+			continue
+		}
+		entry := TFECampaignWorkspace{
+			Name:            ws.Name,
+			ID:              ws.ID,
+			PreviousVersion: ws.TerraformVersion,
+			Status:          campaignWorkspacePending,
+			UpdatedAt:       now,
+		}
+		if ws.TerraformVersion == request.TargetVersion {
+			entry.Status = campaignWorkspaceSkipped
+			entry.Error = "already on target version"
+		}
+		campaign.Workspaces = append(campaign.Workspaces, entry)
+	}
+	if len(campaign.Workspaces) == 0 {
+		return nil, fmt.Errorf("no workspaces match the campaign selector")
+	}
+
+	// Only workspaces that still need an upgrade are spread over waves
+	index := 0
+	for i := range campaign.Workspaces {
+		if campaign.Workspaces[i].Status == campaignWorkspaceSkipped {
+			continue
+		}
+		campaign.Workspaces[i].Wave = index/campaign.WaveSize + 1
+		index++
+	}
+	campaign.Waves = (index + campaign.WaveSize - 1) / campaign.WaveSize
+
+	m := a.tfeCampaigns
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaigns, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	campaigns = append(campaigns, campaign)
+	if err := saveStateFile(tfeCampaignsFile, campaigns); err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// GetTFECampaigns returns all campaigns, most recent first
+func (a *App) GetTFECampaigns() ([]TFECampaign, error) {
+	m := a.tfeCampaigns
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campaigns, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if campaigns == nil {
+		campaigns = []TFECampaign{}
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].CreatedAt.After(campaigns[j].CreatedAt)
+	})
+	return campaigns, nil
+}
+
+// GetTFECampaign returns a single campaign
+func (a *App) GetTFECampaign(id string) (*TFECampaign, error) {
+	campaigns, err := a.GetTFECampaigns()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range campaigns {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("TFE campaign '%s' not found", id)
+}
+
+// nextWave returns the lowest wave that still has pending workspaces, or 0 when none is left
+func (c *TFECampaign) nextWave() int {
+	wave := 0
+	for _, ws := range c.Workspaces {
+		if ws.Status == campaignWorkspacePending && ws.Wave > 0 && (wave == 0 || ws.Wave < wave) {
+			wave = ws.Wave
+		}
+	}
+	return wave
+}
+
+// RunTFECampaignWave plans the next wave in the background and upgrades the workspaces whose plans are clean.
+// Progress is emitted as "tfe-campaign:update" events.
+func (a *App) RunTFECampaignWave(config TFEConfig, id string) (*TFECampaign, error) {
+	m := a.tfeCampaigns
+
+	m.mu.Lock()
+	if m.running[id] != nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("TFE campaign '%s' is already running", id)
+	}
+	env := a.currentEnv()
+	ctx, cancel := context.WithCancel(withEnvContext(context.Background(), env))
+	running := &runningTFECampaign{cancel: cancel, done: make(chan struct{})}
+	m.running[id] = running
+	m.mu.Unlock()
+
+	var wave int
+	var workspaces []string
+	campaign, err := m.update(id, func(c *TFECampaign) error {
+		wave = c.nextWave()
+		if wave == 0 {
+			return fmt.Errorf("TFE campaign '%s' has no pending workspaces", id)
+		}
+		for _, ws := range c.Workspaces {
+			if ws.Wave == wave && ws.Status == campaignWorkspacePending {
+				workspaces = append(workspaces, ws.Name)
+			}
+		}
+		c.Status = "running"
+		c.Profile = env.profile
+		return nil
+	})
+	if err != nil {
+		cancel()
+		m.mu.Lock()
+		delete(m.running, id)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	go m.runWave(ctx, config, *campaign, workspaces, running)
+	return campaign, nil
+}
+
+// runWave plans the wave workspaces, then sets the target version on the clean ones
+func (m *tfeCampaignManager) runWave(ctx context.Context, config TFEConfig, campaign TFECampaign, workspaces []string, running *runningTFECampaign) {
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, campaign.ID)
+		m.mu.Unlock()
+		running.cancel()
+		close(running.done)
+	}()
+
+	for _, name := range workspaces {
+		if err := m.updateWorkspace(campaign.ID, name, func(w *TFECampaignWorkspace) {
+			w.Status = campaignWorkspacePlanning
+			w.Error = ""
+		}); err != nil {
+			fmt.Printf("Warning: TFE campaign %s: %v\n", campaign.ID, err)
+		}
+	}
+
+	execution := TFEPlanExecution{
+		TerraformVersion: campaign.TargetVersion,
+		Message:          fmt.Sprintf("%s (wave plan)", campaign.Name),
+		Wait:             true,
+	}
+
+	var clean []string
+	m.app.tfePlanJobs.fanOutTFEPlans(ctx, config, workspaces, execution, defaultTFEPlanConcurrency, func(result TFEPlanResult) {
+		err := m.updateWorkspace(campaign.ID, result.WorkspaceName, func(w *TFECampaignWorkspace) {
+			w.RunID = result.RunID
+			w.RunURL = result.URL
+			w.Summary = result.Summary
+			switch {
+			case result.Status == "canceled":
+				w.Status = campaignWorkspacePending
+			case result.isErrored():
+				w.Status = campaignWorkspacePlanFailed
+				w.Error = result.Error
+			case result.HasChanges:
+				w.Status = campaignWorkspaceNeedsReview
+			default:
+				w.Status = campaignWorkspaceUpgrading
+				clean = append(clean, result.WorkspaceName)
+			}
+		})
+		if err != nil {
+			fmt.Printf("Warning: TFE campaign %s: %v\n", campaign.ID, err)
+		}
+	})
+
+	// Workspaces never started because the wave was paused go back to pending
+	for _, name := range workspaces {
+		m.updateWorkspace(campaign.ID, name, func(w *TFECampaignWorkspace) {
+			if w.Status == campaignWorkspacePlanning {
+				w.Status = campaignWorkspacePending
+			}
+		})
+	}
+
+	// Clean plans are upgraded even when the wave was paused meanwhile, with the environment of the wave
+	upgradeCtx, cancelUpgrade := context.WithTimeout(context.WithoutCancel(ctx), tfeCampaignUpgradeTimeout)
+	defer cancelUpgrade()
+	for _, name := range clean {
+		err := m.setVersion(upgradeCtx, config, name, campaign.TargetVersion)
+		m.updateWorkspace(campaign.ID, name, func(w *TFECampaignWorkspace) {
+			if err != nil {
+				w.Status = campaignWorkspaceUpgradeFailed
+				w.Error = err.Error()
+			} else {
+				w.Status = campaignWorkspaceUpgraded
+			}
+		})
+	}
+
+	_, err := m.update(campaign.ID, func(c *TFECampaign) error {
+		c.Status = "idle"
+		done := true
+		for _, ws := range c.Workspaces {
+			if !ws.isTerminal() {
+				done = false
+				break
+			}
+		}
+		if done {
+			c.Status = "completed"
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Warning: TFE campaign %s: %v\n", campaign.ID, err)
+	}
+}
+
+// PauseTFECampaign stops the running wave, workspaces still planning return to pending
+func (a *App) PauseTFECampaign(id string) error {
+	m := a.tfeCampaigns
+	m.mu.Lock()
+	running := m.running[id]
+	m.mu.Unlock()
+
+	if running == nil {
+		return fmt.Errorf("TFE campaign '%s' is not running", id)
+	}
+	running.cancel()
+	<-running.done
+	return nil
+}
+
+// RetryTFECampaignWorkspace puts a failed or reviewed workspace back to pending so the next wave run picks it up
+func (a *App) RetryTFECampaignWorkspace(id, workspace string) (*TFECampaign, error) {
+	m := a.tfeCampaigns
+	m.mu.Lock()
+	running := m.running[id] != nil
+	m.mu.Unlock()
+	if running {
+		return nil, fmt.Errorf("TFE campaign '%s' is running, pause it first", id)
+	}
+
+	return m.update(id, func(c *TFECampaign) error {
+		for i := range c.Workspaces {
+			ws := &c.Workspaces[i]
+			if ws.Name != workspace {
+				continue
+			}
+			switch ws.Status {
+			case campaignWorkspacePlanFailed, campaignWorkspaceUpgradeFailed, campaignWorkspaceNeedsReview:
+				ws.Status = campaignWorkspacePending
+				ws.Error = ""
+				ws.UpdatedAt = time.Now()
+				if c.Status == "completed" {
+					c.Status = "idle"
+				}
+				return nil
+			default:
+				return fmt.Errorf("workspace %s cannot be retried in status %s", workspace, ws.Status)
+			}
+		}
+		return fmt.Errorf("workspace %s is not part of campaign %s", workspace, id)
+	})
+}
+
+// DeleteTFECampaign removes a campaign that is not running
+func (a *App) DeleteTFECampaign(id string) error {
+	m := a.tfeCampaigns
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running[id] != nil {
+		return fmt.Errorf("TFE campaign '%s' is running, pause it first", id)
+	}
+
+	campaigns, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	var remaining []TFECampaign
+	found := false
+	for _, c := range campaigns {
+		if c.ID == id {
+			found = true
+			continue
+		}
+		remaining = append(remaining, c)
+	}
+	if !found {
+		return fmt.Errorf("TFE campaign '%s' not found", id)
+	}
+	return saveStateFile(tfeCampaignsFile, remaining)
+}
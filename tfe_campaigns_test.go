@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedTFECampaign stores a campaign with the given workspace waves, all pending
+func seedTFECampaign(t *testing.T, waves map[string]int) TFECampaign {
+	t.Helper()
+	campaign := TFECampaign{
+		ID:            "campaign-1",
+		Name:          "Upgrade to Terraform 1.9.0",
+		Organization:  "acme",
+		TargetVersion: "1.9.0",
+		WaveSize:      3,
+		Status:        "idle",
+		CreatedAt:     time.Now(),
+	}
+	for name, wave := range waves {
+		campaign.Workspaces = append(campaign.Workspaces, TFECampaignWorkspace{Name: name, Wave: wave, Status: campaignWorkspacePending})
+		if wave > campaign.Waves {
+			campaign.Waves = wave
+		}
+	}
+	sort.Slice(campaign.Workspaces, func(i, j int) bool { return campaign.Workspaces[i].Name < campaign.Workspaces[j].Name })
+	require.NoError(t, saveStateFile(tfeCampaignsFile, []TFECampaign{campaign}))
+	return campaign
+}
+
+// waitTFECampaign waits for the running wave of a campaign to finish
+func waitTFECampaign(t *testing.T, app *App, id string) {
+	t.Helper()
+	app.tfeCampaigns.mu.Lock()
+	running := app.tfeCampaigns.running[id]
+	app.tfeCampaigns.mu.Unlock()
+	if running == nil {
+		return
+	}
+	select {
+	case <-running.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("campaign %s wave did not finish", id)
+	}
+}
+
+// campaignStatuses returns the status of every workspace of a campaign
+func campaignStatuses(t *testing.T, app *App, id string) map[string]string {
+	t.Helper()
+	campaign, err := app.GetTFECampaign(id)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, ws := range campaign.Workspaces {
+		statuses[ws.Name] = ws.Status
+	}
+	return statuses
+}
+
+// TestTFECampaignWaveGating verifies only clean plans are upgraded and waves run one at a time
+func TestTFECampaignWaveGating(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+	seedTFECampaign(t, map[string]int{"api": 1, "db": 1, "cache": 1, "search": 2})
+
+	plans := map[string]TFEPlanResult{
+		"api":    {Status: "planned_and_finished"},
+		"db":     {Status: "planned", HasChanges: true},
+		"cache":  {Status: "errored", Error: "exit status 1"},
+		"search": {Status: "planned_and_finished"},
+	}
+	var mu sync.Mutex
+	var planned, upgraded []string
+	app.tfePlanJobs.planWorkspace = func(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+		assert.Equal(t, "1.9.0", execution.TerraformVersion)
+		mu.Lock()
+		planned = append(planned, workspace)
+		mu.Unlock()
+		result := plans[workspace]
+		result.WorkspaceName = workspace
+		return result
+	}
+	app.tfeCampaigns.setVersion = func(ctx context.Context, config TFEConfig, workspace, version string) error {
+		upgraded = append(upgraded, workspace)
+		return nil
+	}
+
+	tests := []struct {
+		wave     int
+		planned  []string
+		upgraded []string
+		statuses map[string]string
+		status   string
+	}{
+		{
+			wave:     1,
+			planned:  []string{"api", "cache", "db"},
+			upgraded: []string{"api"},
+			statuses: map[string]string{
+				"api":    campaignWorkspaceUpgraded,
+				"db":     campaignWorkspaceNeedsReview,
+				"cache":  campaignWorkspacePlanFailed,
+				"search": campaignWorkspacePending,
+			},
+			status: "idle",
+		},
+		{
+			wave:     2,
+			planned:  []string{"search"},
+			upgraded: []string{"search"},
+			statuses: map[string]string{
+				"api":    campaignWorkspaceUpgraded,
+				"db":     campaignWorkspaceNeedsReview,
+				"cache":  campaignWorkspacePlanFailed,
+				"search": campaignWorkspaceUpgraded,
+			},
+			status: "completed",
+		},
+	}
+	for _, tt := range tests {
+		planned, upgraded = nil, nil
+		campaign, err := app.RunTFECampaignWave(TFEConfig{Organization: "acme"}, "campaign-1")
+		require.NoError(t, err)
+		assert.Equal(t, "running", campaign.Status)
+		waitTFECampaign(t, app, "campaign-1")
+
+		sort.Strings(planned)
+		assert.Equal(t, tt.planned, planned, "wave %d", tt.wave)
+		assert.Equal(t, tt.upgraded, upgraded, "wave %d", tt.wave)
+		assert.Equal(t, tt.statuses, campaignStatuses(t, app, "campaign-1"), "wave %d", tt.wave)
+		campaign, err = app.GetTFECampaign("campaign-1")
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, campaign.Status, "wave %d", tt.wave)
+	}
+
+	_, err := app.RunTFECampaignWave(TFEConfig{Organization: "acme"}, "campaign-1")
+	assert.Error(t, err, "no wave is left")
+
+	// A failed plan is retried by the next wave run
+	campaign, err := app.RetryTFECampaignWorkspace("campaign-1", "cache")
+	require.NoError(t, err)
+	assert.Equal(t, "idle", campaign.Status)
+	assert.Equal(t, 1, campaign.nextWave())
+	_, err = app.RetryTFECampaignWorkspace("campaign-1", "api")
+	assert.Error(t, err, "an upgraded workspace cannot be retried")
+}
+
+// TestPauseTFECampaign verifies pausing returns unstarted workspaces to pending and bounds the upgrades of the wave
+func TestPauseTFECampaign(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	previous := tfeCampaignUpgradeTimeout
+	tfeCampaignUpgradeTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { tfeCampaignUpgradeTimeout = previous })
+
+	app := NewApp()
+	seedTFECampaign(t, map[string]int{"api": 1, "db": 1})
+
+	release := make(chan struct{})
+	planned := make(chan struct{}, 2)
+	app.tfePlanJobs.planWorkspace = func(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+		planned <- struct{}{}
+		if workspace == "api" {
+			return TFEPlanResult{WorkspaceName: workspace, Status: "planned_and_finished"}
+		}
+		<-ctx.Done()
+		return TFEPlanResult{WorkspaceName: workspace, Status: "errored", Error: "signal: killed"}
+	}
+	// TFE hangs on the version change, the upgrade timeout must release the pause
+	app.tfeCampaigns.setVersion = func(ctx context.Context, config TFEConfig, workspace, version string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-release:
+			return nil
+		}
+	}
+	defer close(release)
+
+	_, err := app.RunTFECampaignWave(TFEConfig{Organization: "acme"}, "campaign-1")
+	require.NoError(t, err)
+	<-planned
+	<-planned
+
+	_, err = app.RetryTFECampaignWorkspace("campaign-1", "db")
+	assert.Error(t, err, "a running campaign cannot be changed")
+
+	paused := make(chan error)
+	go func() { paused <- app.PauseTFECampaign("campaign-1") }()
+	select {
+	case err := <-paused:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pausing waited on the hung version change")
+	}
+
+	assert.Equal(t, map[string]string{
+		"api": campaignWorkspaceUpgradeFailed,
+		"db":  campaignWorkspacePending,
+	}, campaignStatuses(t, app, "campaign-1"))
+	assert.Error(t, app.PauseTFECampaign("campaign-1"), "the campaign is no longer running")
+}
+
+// TestRecoverTFECampaigns verifies workspaces in flight at the last stop are reset
+func TestRecoverTFECampaigns(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+	campaign := seedTFECampaign(t, map[string]int{"api": 1, "db": 1, "web": 1})
+	campaign.Status = "running"
+	campaign.Workspaces[0].Status = campaignWorkspaceUpgrading
+	campaign.Workspaces[1].Status = campaignWorkspacePlanning
+	campaign.Workspaces[2].Status = campaignWorkspaceUpgraded
+	require.NoError(t, saveStateFile(tfeCampaignsFile, []TFECampaign{campaign}))
+
+	require.NoError(t, app.tfeCampaigns.recover())
+	recovered, err := app.GetTFECampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "idle", recovered.Status)
+	assert.Equal(t, map[string]string{
+		"api": campaignWorkspaceUpgradeFailed,
+		"db":  campaignWorkspacePending,
+		"web": campaignWorkspaceUpgraded,
+	}, campaignStatuses(t, app, campaign.ID))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	tfeSettingsFile = "tfe-settings.json"
+
+	// defaultTFEProfile holds the settings used when no environment profile is loaded
+	defaultTFEProfile = "default"
+)
+
+// TFEProfileSettings are the persisted TFE settings of an environment profile, the token lives in the secret store
+type TFEProfileSettings struct {
+	Endpoint     string `json:"endpoint,omitempty"`
+	Organization string `json:"organization,omitempty"`
+}
+
+// terraformCredentialsFile is the layout of ~/.terraform.d/credentials.tfrc.json written by `terraform login`
+type terraformCredentialsFile struct {
+	Credentials map[string]struct {
+		Token string `json:"token"`
+	} `json:"credentials"`
+}
+
+// tfeProfileName returns the profile whose TFE settings apply
+func (a *App) tfeProfileName() string {
+	if profile := a.GetActiveEnvironmentProfile(); profile != "" {
+		return profile
+	}
+	return defaultTFEProfile
+}
+
+// tfeTokenSecretName is the secret store entry holding the TFE token of a profile
+func tfeTokenSecretName(profile string) string {
+	return "tfe-token:" + profile
+}
+
+// tfeHostname extracts the hostname from an endpoint that may include a scheme or path
+func tfeHostname(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// terraformTokenEnvVar returns the TF_TOKEN_<host> variable name, Terraform encodes dots as "_" and hyphens as "__"
+func terraformTokenEnvVar(hostname string) string {
+	encoded := strings.ReplaceAll(hostname, "-", "__")
+	return "TF_TOKEN_" + strings.ReplaceAll(encoded, ".", "_")
+}
+
+// terraformCredentialsToken reads the token of a host from ~/.terraform.d/credentials.tfrc.json
+func terraformCredentialsToken(hostname string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(homeDir, ".terraform.d", "credentials.tfrc.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read Terraform credentials: %v", err)
+	}
+
+	var credentials terraformCredentialsFile
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return "", fmt.Errorf("failed to parse Terraform credentials: %v", err)
+	}
+	return credentials.Credentials[hostname].Token, nil
+}
+
+// loadTFEProfileSettings returns the persisted settings of every profile
+func loadTFEProfileSettings() (map[string]TFEProfileSettings, error) {
+	settings := map[string]TFEProfileSettings{}
+	if _, err := loadStateFile(tfeSettingsFile, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetTFEConfig returns the TFE configuration of the active environment profile.
+// Settings saved in the GUI take precedence over TFE_ENDPOINT and TFE_ORGANIZATION, which take precedence
+// over the site config. The token is looked up in the encrypted secret store, then TFE_TOKEN,
+// TF_TOKEN_<host> and ~/.terraform.d/credentials.tfrc.json.
+func (a *App) GetTFEConfig() (TFEConfig, error) {
+	return a.tfeConfig(a.tfeProfileName(), a.currentEnv())
+}
+
+// tfeConfigForProfile returns the TFE configuration of an environment profile whether or not it is active
+func (a *App) tfeConfigForProfile(profile string) (TFEConfig, error) {
+	if profile == "" || profile == a.tfeProfileName() {
+		return a.GetTFEConfig()
+	}
+	if profile == defaultTFEProfile {
+		return a.tfeConfig(profile, newEnvContext())
+	}
+	env, err := a.envForProfile(profile)
+	if err != nil {
+		return TFEConfig{}, err
+	}
+	return a.tfeConfig(profile, env)
+}
+
+// tfeConfig resolves the TFE configuration of a profile in its environment, the site file included
+func (a *App) tfeConfig(profile string, env *envContext) (TFEConfig, error) {
+	site, err := loadSiteConfig(env.get("TFINFRA_REPOSITORY_PATH"))
+	if err != nil {
+		return TFEConfig{}, err
+	}
+
+	config := TFEConfig{
+		Endpoint:     site.TFE.Endpoint,
+		Organization: site.TFE.Organization,
+	}
+	if override, ok := site.TFE.Profiles[profile]; ok {
+		if override.Endpoint != "" {
+			config.Endpoint = override.Endpoint
+		}
+		if override.Organization != "" {
+			config.Organization = override.Organization
+		}
+	}
+
+	if endpoint := env.get("TFE_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+	}
+	if org := env.get("TFE_ORGANIZATION"); org != "" {
+		config.Organization = org
+	}
+
+	settings, err := loadTFEProfileSettings()
+	if err != nil {
+		return config, err
+	}
+	if saved, ok := settings[profile]; ok {
+		if saved.Endpoint != "" {
+			config.Endpoint = saved.Endpoint
+		}
+		if saved.Organization != "" {
+			config.Organization = saved.Organization
+		}
+	}
+
+	token, ok, err := getSecret(tfeTokenSecretName(profile))
+	if err != nil {
+		return config, err
+	}
+	if ok {
+		config.Token = token
+		config.TokenSource = "secret store"
+		return config, nil
+	}
+
+	if token := env.get("TFE_TOKEN"); token != "" {
+		config.Token = token
+		config.TokenSource = "TFE_TOKEN"
+		return config, nil
+	}
+
+	hostname := tfeHostname(config.Endpoint)
+	if hostname == "" {
+		return config, nil
+	}
+	tokenVar := terraformTokenEnvVar(hostname)
+	if token := env.get(tokenVar); token != "" {
+		config.Token = token
+		config.TokenSource = tokenVar
+		return config, nil
+	}
+
+	token, err = terraformCredentialsToken(hostname)
+	if err != nil {
+		return config, err
+	}
+	if token != "" {
+		config.Token = token
+		config.TokenSource = "credentials.tfrc.json"
+	}
+	return config, nil
+}
+
+// SetTFEConfig persists the TFE settings of the active environment profile.
+// A non-empty token is stored encrypted, an empty token keeps the stored one.
+func (a *App) SetTFEConfig(config TFEConfig) error {
+	profile := a.tfeProfileName()
+
+	settings := map[string]TFEProfileSettings{}
+	err := updateStateFile(tfeSettingsFile, &settings, func() error {
+		settings[profile] = TFEProfileSettings{
+			Endpoint:     strings.TrimSpace(config.Endpoint),
+			Organization: strings.TrimSpace(config.Organization),
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tokens found in the environment or Terraform credentials are not copied into the store
+	if config.Token != "" && (config.TokenSource == "" || config.TokenSource == "secret store") {
+		return setSecret(tfeTokenSecretName(profile), config.Token)
+	}
+	return nil
+}
+
+// ClearTFEToken removes the stored TFE token of the active environment profile
+func (a *App) ClearTFEToken() error {
+	return setSecret(tfeTokenSecretName(a.tfeProfileName()), "")
+}
+
+// deleteTFEProfileSettings removes the TFE settings and token of a deleted environment profile
+func deleteTFEProfileSettings(profile string) error {
+	settings := map[string]TFEProfileSettings{}
+	err := updateStateFile(tfeSettingsFile, &settings, func() error {
+		delete(settings, profile)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return setSecret(tfeTokenSecretName(profile), "")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTFEConfigPerProfileWithEncryptedToken verifies settings are stored per profile and tokens never in plain text
+func TestTFEConfigPerProfileWithEncryptedToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFE_ENDPOINT", "")
+	t.Setenv("TFE_ORGANIZATION", "")
+	t.Setenv("TFE_TOKEN", "")
+
+	app := NewApp()
+
+	config, err := app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, builtinSiteConfig().TFE.Endpoint, config.Endpoint)
+	assert.Empty(t, config.Token)
+
+	require.NoError(t, app.SetTFEConfig(TFEConfig{Endpoint: "tfe.staging.example.com", Organization: "acme", Token: "staging-secret-token"}))
+
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "tfe.staging.example.com", config.Endpoint)
+	assert.Equal(t, "acme", config.Organization)
+	assert.Equal(t, "staging-secret-token", config.Token)
+	assert.Equal(t, "secret store", config.TokenSource)
+
+	// The token is only stored encrypted and the key is private
+	for _, name := range []string{tfeSettingsFile, secretStoreDataFile} {
+		data, err := os.ReadFile(filepath.Join(home, ".yak-gui", name))
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "staging-secret-token")
+	}
+	info, err := os.Stat(filepath.Join(home, ".yak-gui", secretStoreKeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Another profile has its own settings
+	app.env.Store(app.currentEnv().withProfile("production"))
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, builtinSiteConfig().TFE.Endpoint, config.Endpoint)
+	assert.Empty(t, config.Token)
+
+	app.env.Store(app.currentEnv().withProfile(""))
+	require.NoError(t, app.ClearTFEToken())
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.Token)
+	assert.Equal(t, "acme", config.Organization)
+}
+
+// TestTFETokenFallbacks verifies the environment and Terraform credentials fallbacks
+func TestTFETokenFallbacks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFE_ENDPOINT", "https://tfe-eu.example.com/")
+	t.Setenv("TFE_ORGANIZATION", "")
+	t.Setenv("TFE_TOKEN", "")
+
+	assert.Equal(t, "TF_TOKEN_tfe__eu_example_com", terraformTokenEnvVar("tfe-eu.example.com"))
+
+	credentialsDir := filepath.Join(home, ".terraform.d")
+	require.NoError(t, os.MkdirAll(credentialsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(credentialsDir, "credentials.tfrc.json"), []byte(
+		`{"credentials": {"tfe-eu.example.com": {"token": "from-credentials"}}}`), 0600))
+
+	app := NewApp()
+
+	config, err := app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "from-credentials", config.Token)
+	assert.Equal(t, "credentials.tfrc.json", config.TokenSource)
+
+	t.Setenv("TF_TOKEN_tfe__eu_example_com", "from-tf-token")
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "from-tf-token", config.Token)
+
+	t.Setenv("TFE_TOKEN", "from-tfe-token")
+	config, err = app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "from-tfe-token", config.Token)
+
+	// Saving the settings does not copy an environment token into the store
+	require.NoError(t, app.SetTFEConfig(config))
+	data, err := os.ReadFile(filepath.Join(home, ".yak-gui", tfeSettingsFile))
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(data), "from-tfe-token"))
+	_, stored, err := getSecret(tfeTokenSecretName(defaultTFEProfile))
+	require.NoError(t, err)
+	assert.False(t, stored)
+}
+
+// TestTFEConfigForOtherProfileUsesItsSiteFile verifies another profile's settings come from its own terraform-infra
+func TestTFEConfigForOtherProfileUsesItsSiteFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFE_ENDPOINT", "")
+	t.Setenv("TFE_ORGANIZATION", "")
+	t.Setenv("TFE_TOKEN", "")
+
+	writeSite := func(endpoint, organization string) string {
+		tfinfra := t.TempDir()
+		site := filepath.Join(tfinfra, filepath.FromSlash(tfinfraSiteConfigPath))
+		require.NoError(t, os.MkdirAll(filepath.Dir(site), 0755))
+		require.NoError(t, os.WriteFile(site, []byte("tfe:\n  endpoint: "+endpoint+"\n  organization: "+organization+"\n"), 0644))
+		return tfinfra
+	}
+	t.Setenv("TFINFRA_REPOSITORY_PATH", writeSite("tfe.staging.example.com", "staging"))
+	productionInfra := writeSite("tfe.production.example.com", "production")
+
+	app := NewApp()
+	require.NoError(t, app.SaveEnvironmentProfileVariables("production", []EnvironmentVariable{
+		{Name: "AWS_PROFILE", Value: "production-sso"},
+		{Name: "TFINFRA_REPOSITORY_PATH", Value: productionInfra},
+	}))
+
+	config, err := app.GetTFEConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "tfe.staging.example.com", config.Endpoint)
+
+	config, err = app.tfeConfigForProfile("production")
+	require.NoError(t, err)
+	assert.Equal(t, "tfe.production.example.com", config.Endpoint)
+	assert.Equal(t, "production", config.Organization)
+}
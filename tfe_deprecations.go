@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TFETeamContact represents the team owning workspaces and how to reach it
+type TFETeamContact struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Slack string `json:"slack,omitempty"`
+}
+
+// TFEDeprecatedWorkspace is a workspace running a deprecated version
+type TFEDeprecatedWorkspace struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Owner string          `json:"owner,omitempty"`
+	Team  *TFETeamContact `json:"team,omitempty"` // nil when the owner has no entry in the teams mapping
+}
+
+// TFEDeprecatedVersion groups the workspaces running a deprecated version
+type TFEDeprecatedVersion struct {
+	Version    string                   `json:"version"`
+	Reason     string                   `json:"reason"`
+	Workspaces []TFEDeprecatedWorkspace `json:"workspaces"`
+}
+
+// TFEDeprecationReport lists deprecated Terraform versions with their workspaces and owning teams
+type TFEDeprecationReport struct {
+	Organization       string                 `json:"organization"`
+	GeneratedAt        time.Time              `json:"generatedAt"`
+	RecommendedVersion string                 `json:"recommendedVersion,omitempty"`
+	TotalWorkspaces    int                    `json:"totalWorkspaces"`
+	AffectedWorkspaces int                    `json:"affectedWorkspaces"`
+	Versions           []TFEDeprecatedVersion `json:"versions"`
+	EmailSent          bool                   `json:"emailSent"`
+}
+
+// yakCheckVersionsOutput is the JSON printed by `yak tfe check-versions --json`,
+// yak owns the version policy and teams mapping formats
+// (see testdata/yak-check-versions.json)
+type yakCheckVersionsOutput struct {
+	Organization       string `json:"organization"`
+	RecommendedVersion string `json:"recommended_version"`
+	TotalWorkspaces    int    `json:"total_workspaces"`
+	DeprecatedVersions []struct {
+		Version    string `json:"version"`
+		Reason     string `json:"reason"`
+		Workspaces []struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Owner string `json:"owner"`
+			Team  *struct {
+				Email string `json:"email"`
+				Slack string `json:"slack"`
+			} `json:"team"`
+		} `json:"workspaces"`
+	} `json:"deprecated_versions"`
+	EmailSent bool `json:"email_sent"`
+}
+
+// yakCheckVersionsRequiredKeys are the keys yak always prints, deprecated_versions is null when none are found
+var yakCheckVersionsRequiredKeys = []string{"organization", "total_workspaces", "deprecated_versions"}
+
+// defaultTFEDeprecationFiles returns the version policy and teams files from terraform-infra
+func defaultTFEDeprecationFiles(tfinfraPath string) (string, string, error) {
+	if tfinfraPath == "" {
+		return "", "", fmt.Errorf("TFINFRA_REPOSITORY_PATH is not set, cannot locate the Terraform version policy")
+	}
+	configDir := filepath.Join(tfinfraPath, "setup", "yak_config")
+	return filepath.Join(configDir, "tfe_versions.yml"), filepath.Join(configDir, "teams.yml"), nil
+}
+
+// compareTerraformVersions compares two versions numerically, a pre-release sorts before its release
+func compareTerraformVersions(a, b string) int {
+	splitVersion := func(v string) ([]int, string) {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+		pre := ""
+		if i := strings.IndexAny(v, "-+"); i >= 0 {
+			v, pre = v[:i], v[i:]
+		}
+		var parts []int
+		for _, p := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(p)
+			parts = append(parts, n)
+		}
+		return parts, pre
+	}
+
+	pa, preA := splitVersion(a)
+	pb, preB := splitVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	default:
+		return strings.Compare(preA, preB)
+	}
+}
+
+// parseTFEDeprecationReport converts the check-versions output of yak into a report, oldest versions first
+func parseTFEDeprecationReport(data []byte) (*TFEDeprecationReport, error) {
+	// A renamed or missing key would otherwise parse as a report without deprecations
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse yak check-versions output: %v", err)
+	}
+	for _, key := range yakCheckVersionsRequiredKeys {
+		if _, ok := keys[key]; !ok {
+			return nil, fmt.Errorf("unexpected yak check-versions output: missing %q, check the yak version", key)
+		}
+	}
+
+	var output yakCheckVersionsOutput
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&output); err != nil {
+		return nil, fmt.Errorf("unexpected yak check-versions output, check the yak version: %v", err)
+	}
+
+	report := &TFEDeprecationReport{
+		Organization:       output.Organization,
+		GeneratedAt:        time.Now(),
+		RecommendedVersion: output.RecommendedVersion,
+		TotalWorkspaces:    output.TotalWorkspaces,
+		Versions:           []TFEDeprecatedVersion{},
+		EmailSent:          output.EmailSent,
+	}
+
+	for _, version := range output.DeprecatedVersions {
+		entry := TFEDeprecatedVersion{Version: version.Version, Reason: version.Reason}
+		for _, ws := range version.Workspaces {
+			deprecated := TFEDeprecatedWorkspace{ID: ws.ID, Name: ws.Name, Owner: ws.Owner}
+			if ws.Team != nil {
+				deprecated.Team = &TFETeamContact{Name: ws.Owner, Email: ws.Team.Email, Slack: ws.Team.Slack}
+			}
+			entry.Workspaces = append(entry.Workspaces, deprecated)
+		}
+		sort.Slice(entry.Workspaces, func(i, j int) bool { return entry.Workspaces[i].Name < entry.Workspaces[j].Name })
+		report.AffectedWorkspaces += len(entry.Workspaces)
+		report.Versions = append(report.Versions, entry)
+	}
+	// Oldest versions first, they are the most urgent
+	sort.Slice(report.Versions, func(i, j int) bool {
+		return compareTerraformVersions(report.Versions[i].Version, report.Versions[j].Version) < 0
+	})
+
+	return report, nil
+}
+
+// CheckTFEDeprecatedVersions reports workspaces using deprecated Terraform versions with their owning teams.
+// Empty file paths default to the version policy and teams mapping of terraform-infra.
+func (a *App) CheckTFEDeprecatedVersions(config TFEConfig, versionFile string, teamsFile string, sendEmail bool) (*TFEDeprecationReport, error) {
+	if versionFile == "" || teamsFile == "" {
+		defaultVersionFile, defaultTeamsFile, err := defaultTFEDeprecationFiles(a.currentEnv().get("TFINFRA_REPOSITORY_PATH"))
+		if err != nil {
+			return nil, err
+		}
+		if versionFile == "" {
+			versionFile = defaultVersionFile
+		}
+		if teamsFile == "" {
+			teamsFile = defaultTeamsFile
+		}
+	}
+
+	args := []string{"tfe", "check-versions", "--file", versionFile, "--teams", teamsFile}
+	if config.Organization != "" {
+		args = append(args, "--organization", config.Organization)
+	}
+	// yak owns the email templates sent to teams
+	if sendEmail {
+		args = append(args, "--send-email")
+	}
+	args = append(args, "--json")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	cmd := a.yakCommand(ctx, args...)
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
+		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("yak tfe check-versions failed with exit code %d: %s", exitError.ExitCode(), string(exitError.Stderr))
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("yak tfe check-versions timed out after 120 seconds")
+		}
+		return nil, fmt.Errorf("failed to execute yak tfe check-versions: %w", err)
+	}
+
+	report, err := parseTFEDeprecationReport(output)
+	if err != nil {
+		return nil, err
+	}
+	if report.Organization == "" {
+		report.Organization = config.Organization
+	}
+	return report, nil
+}
+
+// renderTFEDeprecationCSV renders one row per deprecated workspace
+func renderTFEDeprecationCSV(report *TFEDeprecationReport) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{{"version", "reason", "workspace", "workspace_id", "owner", "team_email", "team_slack"}}
+	for _, version := range report.Versions {
+		for _, ws := range version.Workspaces {
+			email, slack := "", ""
+			if ws.Team != nil {
+				email, slack = ws.Team.Email, ws.Team.Slack
+			}
+			rows = append(rows, []string{version.Version, version.Reason, ws.Name, ws.ID, ws.Owner, email, slack})
+		}
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// renderTFEDeprecationMarkdown renders a section per deprecated version with a workspace table
+func renderTFEDeprecationMarkdown(report *TFEDeprecationReport) string {
+	escape := func(s string) string { return strings.ReplaceAll(s, "|", "\\|") }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Deprecated Terraform versions - %s\n\n", report.Organization)
+	fmt.Fprintf(&b, "Generated on %s: %d of %d workspaces use a deprecated version.\n", report.GeneratedAt.Format("2006-01-02"), report.AffectedWorkspaces, report.TotalWorkspaces)
+	if report.RecommendedVersion != "" {
+		fmt.Fprintf(&b, "Please upgrade to Terraform %s.\n", report.RecommendedVersion)
+	}
+
+	for _, version := range report.Versions {
+		fmt.Fprintf(&b, "\n## %s (%d workspaces)\n\n%s\n\n", version.Version, len(version.Workspaces), version.Reason)
+		b.WriteString("| Workspace | Owner | Email | Slack |\n|---|---|---|---|\n")
+		for _, ws := range version.Workspaces {
+			email, slack := "", ""
+			if ws.Team != nil {
+				email, slack = ws.Team.Email, ws.Team.Slack
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", escape(ws.Name), escape(ws.Owner), escape(email), escape(slack))
+		}
+	}
+	return b.String()
+}
+
+// ExportTFEDeprecationReport writes the deprecated versions report as CSV or Markdown and returns the written path
+func (a *App) ExportTFEDeprecationReport(config TFEConfig, versionFile, teamsFile, format, exportPath string) (string, error) {
+	if exportPath == "" {
+		return "", fmt.Errorf("export path is required")
+	}
+
+	report, err := a.CheckTFEDeprecatedVersions(config, versionFile, teamsFile, false)
+	if err != nil {
+		return "", err
+	}
+
+	var content string
+	switch strings.ToLower(format) {
+	case "csv":
+		if content, err = renderTFEDeprecationCSV(report); err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(strings.ToLower(exportPath), ".csv") {
+			exportPath += ".csv"
+		}
+	case "markdown", "md":
+		content = renderTFEDeprecationMarkdown(report)
+		if !strings.HasSuffix(strings.ToLower(exportPath), ".md") {
+			exportPath += ".md"
+		}
+	default:
+		return "", fmt.Errorf("unsupported export format '%s', use csv or markdown", format)
+	}
+
+	if err := writeFileAtomic(exportPath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %v", err)
+	}
+	return exportPath, nil
+}
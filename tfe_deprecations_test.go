@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompareTerraformVersions verifies numeric and pre-release ordering
+func TestCompareTerraformVersions(t *testing.T) {
+	assert.Equal(t, -1, compareTerraformVersions("1.5.7", "1.10.0"))
+	assert.Equal(t, 0, compareTerraformVersions("v1.6.0", "1.6.0"))
+	assert.Equal(t, -1, compareTerraformVersions("1.6.0-beta1", "1.6.0"))
+	assert.Equal(t, 1, compareTerraformVersions("1.6", "1.5.9"))
+}
+
+// fakeCheckVersionsScript prints the check-versions JSON of yak copied next to it and records its arguments
+const fakeCheckVersionsScript = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+cat "$(dirname "$0")/output.json"
+`
+
+// TestTFEDeprecationReportFromYak verifies the check-versions output of yak is typed and the terraform-infra files are passed
+func TestTFEDeprecationReportFromYak(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "yak"), []byte(fakeCheckVersionsScript), 0755))
+	fixture, err := os.ReadFile(filepath.Join("testdata", "yak-check-versions.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "output.json"), fixture, 0644))
+	tfinfra := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TFINFRA_REPOSITORY_PATH", tfinfra)
+
+	app := NewApp()
+	report, err := app.CheckTFEDeprecatedVersions(TFEConfig{Organization: "acme"}, "", "", false)
+	require.NoError(t, err)
+
+	args, err := os.ReadFile(filepath.Join(bin, "args"))
+	require.NoError(t, err)
+	configDir := filepath.Join(tfinfra, "setup", "yak_config")
+	assert.Equal(t, "tfe check-versions --file "+filepath.Join(configDir, "tfe_versions.yml")+" --teams "+filepath.Join(configDir, "teams.yml")+" --organization acme --json\n", string(args))
+
+	assert.Equal(t, 4, report.TotalWorkspaces)
+	assert.Equal(t, 3, report.AffectedWorkspaces)
+	require.Len(t, report.Versions, 2)
+
+	assert.Equal(t, "1.4.6", report.Versions[0].Version)
+	assert.Equal(t, "older than minimum version 1.5.0", report.Versions[0].Reason)
+	assert.Nil(t, report.Versions[0].Workspaces[0].Team)
+
+	assert.Equal(t, "1.6.2", report.Versions[1].Version)
+	require.Len(t, report.Versions[1].Workspaces, 2)
+	assert.Equal(t, "api-prod", report.Versions[1].Workspaces[0].Name)
+	assert.Equal(t, "platform@example.com", report.Versions[1].Workspaces[0].Team.Email)
+
+	csv, err := renderTFEDeprecationCSV(report)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(csv), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[2], "1.6.2,deprecated by policy (1.6.*),api-prod,ws-1,platform,platform@example.com,#platform")
+
+	markdown := renderTFEDeprecationMarkdown(report)
+	assert.Contains(t, markdown, "## 1.6.2 (2 workspaces)")
+	assert.Contains(t, markdown, "Please upgrade to Terraform 1.9.8.")
+	assert.Contains(t, markdown, "| db-prod | data |  |  |")
+
+	exported, err := app.ExportTFEDeprecationReport(TFEConfig{Organization: "acme"}, "", "", "md", filepath.Join(t.TempDir(), "reports", "deprecations"))
+	require.NoError(t, err)
+	assert.Equal(t, ".md", filepath.Ext(exported))
+	written, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "## 1.4.6 (1 workspaces)")
+
+	_, err = parseTFEDeprecationReport([]byte("Checking 4 workspaces..."))
+	assert.Error(t, err)
+}
+
+// TestTFEDeprecationReportRejectsUnknownSchema verifies a change of the yak output is an error, not an empty report
+func TestTFEDeprecationReportRejectsUnknownSchema(t *testing.T) {
+	for name, output := range map[string]string{
+		"renamed key":     `{"organization": "acme", "total_workspaces": 4, "deprecatedVersions": []}`,
+		"missing key":     `{"organization": "acme", "total_workspaces": 4}`,
+		"unknown field":   `{"organization": "acme", "total_workspaces": 4, "deprecated_versions": [{"version": "1.4.6", "workspace_names": ["db-prod"]}]}`,
+		"not an object":   `[]`,
+		"trailing output": `{"organization": "acme", "total_workspaces": 0, "deprecated_versions": []} done`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseTFEDeprecationReport([]byte(output))
+			assert.Error(t, err)
+		})
+	}
+
+	report, err := parseTFEDeprecationReport([]byte(`{"organization": "acme", "total_workspaces": 4, "deprecated_versions": null}`))
+	require.NoError(t, err)
+	assert.Empty(t, report.Versions)
+}
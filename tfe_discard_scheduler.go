@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tfeDiscardPolicyFile  = "tfe-discard-policy.json"
+	tfeDiscardHistoryFile = "tfe-discard-history.json"
+
+	// maxTFEDiscardHistory bounds the history file so it does not grow forever
+	maxTFEDiscardHistory = 200
+
+	// tfeDiscardSchedulerTick is how often the scheduler checks whether the policy is due
+	tfeDiscardSchedulerTick = time.Minute
+)
+
+// tfeDiscardableStatuses are the run statuses considered by the cleanup, pending runs only with DiscardPending
+var tfeDiscardableStatuses = []string{"pending", "planned", "cost_estimated", "policy_checked", "policy_soft_failed", "post_plan_completed"}
+
+// TFEDiscardPolicy configures the periodic discard of stale runs
+type TFEDiscardPolicy struct {
+	Enabled           bool       `json:"enabled"`
+	Profile           string     `json:"profile"` // environment profile whose TFE settings and token apply
+	Organization      string     `json:"organization"`
+	AgeHours          int        `json:"ageHours"`
+	DiscardPending    bool       `json:"discardPending"`
+	IncludeWorkspaces []string   `json:"includeWorkspaces,omitempty"` // globs, empty includes every workspace
+	ExcludeWorkspaces []string   `json:"excludeWorkspaces,omitempty"` // globs
+	IntervalMinutes   int        `json:"intervalMinutes"`
+	PreviewedAt       *time.Time `json:"previewedAt,omitempty"` // dry-run preview of the current settings
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+}
+
+// TFEDiscardedRun is a run discarded, or that would be discarded, by the cleanup
+type TFEDiscardedRun struct {
+	RunID         string `json:"runId"`
+	WorkspaceName string `json:"workspaceName"`
+	Status        string `json:"status"`
+	CreatedAt     string `json:"createdAt"`
+	URL           string `json:"url,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TFEDiscardExecution records one execution of the discard policy
+type TFEDiscardExecution struct {
+	ID         string            `json:"id"`
+	DryRun     bool              `json:"dryRun"`
+	Scheduled  bool              `json:"scheduled"`
+	Policy     TFEDiscardPolicy  `json:"policy"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Runs       []TFEDiscardedRun `json:"runs"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// tfeDiscardScheduler periodically applies the discard policy until stopped
+type tfeDiscardScheduler struct {
+	app    *App
+	mu     sync.Mutex // serializes executions and policy updates
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newTFEDiscardScheduler creates a stopped scheduler
+func newTFEDiscardScheduler(app *App) *tfeDiscardScheduler {
+	return &tfeDiscardScheduler{app: app}
+}
+
+// sameSettings reports whether two policies select the same runs, a preview stays valid only for the same settings
+func (p TFEDiscardPolicy) sameSettings(other TFEDiscardPolicy) bool {
+	return p.Profile == other.Profile &&
+		p.Organization == other.Organization &&
+		p.AgeHours == other.AgeHours &&
+		p.DiscardPending == other.DiscardPending &&
+		strings.Join(p.IncludeWorkspaces, ",") == strings.Join(other.IncludeWorkspaces, ",") &&
+		strings.Join(p.ExcludeWorkspaces, ",") == strings.Join(other.ExcludeWorkspaces, ",")
+}
+
+// matchesWorkspace applies the include and exclude lists
+func (p TFEDiscardPolicy) matchesWorkspace(name string) bool {
+	for _, pattern := range p.ExcludeWorkspaces {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+	if len(p.IncludeWorkspaces) == 0 {
+		return true
+	}
+	for _, pattern := range p.IncludeWorkspaces {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isStale reports whether a run is old enough and in a status the policy discards
+func (p TFEDiscardPolicy) isStale(run TFERun, now time.Time) bool {
+	if !run.Actions.IsDiscardable {
+		return false
+	}
+	if run.Status == "pending" && !p.DiscardPending {
+		return false
+	}
+	created, err := time.Parse(time.RFC3339, run.CreatedAt)
+	if err != nil {
+		return false
+	}
+	return now.Sub(created) >= time.Duration(p.AgeHours)*time.Hour
+}
+
+// loadTFEDiscardPolicy returns the saved policy, disabled with defaults when none is saved
+func loadTFEDiscardPolicy() (TFEDiscardPolicy, error) {
+	policy := TFEDiscardPolicy{AgeHours: 24, IntervalMinutes: 60}
+	_, err := loadStateFile(tfeDiscardPolicyFile, &policy)
+	return policy, err
+}
+
+// GetTFEDiscardPolicy returns the stale-run cleanup policy
+func (a *App) GetTFEDiscardPolicy() (TFEDiscardPolicy, error) {
+	return loadTFEDiscardPolicy()
+}
+
+// SaveTFEDiscardPolicy saves the cleanup policy. Changing which runs are selected invalidates the dry-run
+// preview, and the policy cannot be enabled until a preview of its current settings was run.
+func (a *App) SaveTFEDiscardPolicy(policy TFEDiscardPolicy) (TFEDiscardPolicy, error) {
+	if policy.Organization == "" {
+		return policy, fmt.Errorf("organization is required")
+	}
+	if policy.AgeHours <= 0 {
+		return policy, fmt.Errorf("age must be at least one hour")
+	}
+	if policy.IntervalMinutes < 5 {
+		return policy, fmt.Errorf("interval must be at least 5 minutes")
+	}
+	for _, pattern := range append(append([]string{}, policy.IncludeWorkspaces...), policy.ExcludeWorkspaces...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return policy, fmt.Errorf("invalid workspace pattern '%s': %v", pattern, err)
+		}
+	}
+
+	// The scheduler keeps using the profile the policy was saved from after switching profiles
+	if policy.Profile == "" {
+		policy.Profile = a.tfeProfileName()
+	}
+
+	s := a.tfeDiscardScheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := loadTFEDiscardPolicy()
+	if err != nil {
+		return policy, err
+	}
+	policy.LastRunAt = current.LastRunAt
+	policy.PreviewedAt = nil
+	if current.sameSettings(policy) {
+		policy.PreviewedAt = current.PreviewedAt
+	}
+	if policy.Enabled && policy.PreviewedAt == nil {
+		return policy, fmt.Errorf("run a dry-run preview of this policy before enabling it")
+	}
+
+	return policy, saveStateFile(tfeDiscardPolicyFile, policy)
+}
+
+// PreviewTFEDiscardPolicy lists the runs the saved policy would discard without discarding them
+func (a *App) PreviewTFEDiscardPolicy() (*TFEDiscardExecution, error) {
+	return a.tfeDiscardScheduler.execute(context.Background(), true, false)
+}
+
+// RunTFEDiscardPolicyNow applies the saved policy immediately
+func (a *App) RunTFEDiscardPolicyNow() (*TFEDiscardExecution, error) {
+	return a.tfeDiscardScheduler.execute(context.Background(), false, false)
+}
+
+// GetTFEDiscardHistory returns previous executions of the policy, most recent first
+func (a *App) GetTFEDiscardHistory() ([]TFEDiscardExecution, error) {
+	var history []TFEDiscardExecution
+	if _, err := loadStateFile(tfeDiscardHistoryFile, &history); err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []TFEDiscardExecution{}
+	}
+	return history, nil
+}
+
+// recordTFEDiscardExecution prepends an execution to the history
+func recordTFEDiscardExecution(execution TFEDiscardExecution) error {
+	var history []TFEDiscardExecution
+	return updateStateFile(tfeDiscardHistoryFile, &history, func() error {
+		history = append([]TFEDiscardExecution{execution}, history...)
+		if len(history) > maxTFEDiscardHistory {
+			history = history[:maxTFEDiscardHistory]
+		}
+		return nil
+	})
+}
+
+// execute applies the policy once and records the execution in the history
+func (s *tfeDiscardScheduler) execute(ctx context.Context, dryRun, scheduled bool) (*TFEDiscardExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy, err := loadTFEDiscardPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if policy.Organization == "" {
+		return nil, fmt.Errorf("no TFE discard policy is configured")
+	}
+	if !dryRun && policy.PreviewedAt == nil {
+		return nil, fmt.Errorf("run a dry-run preview of this policy before discarding runs")
+	}
+
+	execution := TFEDiscardExecution{
+		ID:        fmt.Sprintf("discard-%d", time.Now().UnixNano()),
+		DryRun:    dryRun,
+		Scheduled: scheduled,
+		Policy:    policy,
+		StartedAt: time.Now(),
+		Runs:      []TFEDiscardedRun{},
+	}
+
+	config, err := s.app.tfeConfigForProfile(policy.Profile)
+	if err == nil && config.Token == "" {
+		err = fmt.Errorf("no TFE token is configured for profile '%s'", policy.Profile)
+	}
+	if err != nil {
+		if !scheduled {
+			return nil, err
+		}
+		// A scheduled execution waits for the next interval instead of failing on every tick
+		execution.Error = err.Error()
+	} else {
+		config.Organization = policy.Organization
+		runs, err := s.discardStaleRuns(ctx, config, policy, dryRun)
+		execution.Runs = append(execution.Runs, runs...)
+		if err != nil {
+			execution.Error = err.Error()
+		}
+	}
+	execution.FinishedAt = time.Now()
+
+	// A failed preview did not show which runs would be discarded, it must not open the gate
+	now := execution.FinishedAt
+	if dryRun {
+		if execution.Error == "" {
+			policy.PreviewedAt = &now
+		}
+	} else {
+		policy.LastRunAt = &now
+	}
+	if err := saveStateFile(tfeDiscardPolicyFile, policy); err != nil {
+		return &execution, err
+	}
+	if err := recordTFEDiscardExecution(execution); err != nil {
+		return &execution, err
+	}
+	s.app.emitEvent("tfe-discard:executed", execution)
+	return &execution, nil
+}
+
+// discardStaleRuns finds stale runs of the selected workspaces and discards them unless dryRun is set
+func (s *tfeDiscardScheduler) discardStaleRuns(ctx context.Context, config TFEConfig, policy TFEDiscardPolicy, dryRun bool) ([]TFEDiscardedRun, error) {
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	workspaces, err := client.listWorkspaces(ctx, config.Organization, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TFE workspaces: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("filter[status]", strings.Join(tfeDiscardableStatuses, ","))
+
+	now := time.Now()
+	var results []TFEDiscardedRun
+	for _, ws := range workspaces {
+		if !policy.matchesWorkspace(ws.Name) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		resources, _, err := client.list(ctx, "/workspaces/"+url.PathEscape(ws.ID)+"/runs", query)
+		if err != nil {
+			results = append(results, TFEDiscardedRun{WorkspaceName: ws.Name, Error: fmt.Sprintf("failed to list runs: %v", err)})
+			continue
+		}
+
+		for _, resource := range resources {
+			run, err := resource.toTFERun(client, config.Organization, ws.Name, nil)
+			if err != nil || !policy.isStale(run, now) {
+				continue
+			}
+
+			result := TFEDiscardedRun{RunID: run.ID, WorkspaceName: ws.Name, Status: run.Status, CreatedAt: run.CreatedAt, URL: run.URL}
+			if !dryRun {
+				if err := client.runAction(ctx, run.ID, "discard", "Discarded by the Yak GUI stale-run cleanup"); err != nil {
+					result.Error = err.Error()
+				}
+			}
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].WorkspaceName != results[j].WorkspaceName {
+			return results[i].WorkspaceName < results[j].WorkspaceName
+		}
+		return results[i].CreatedAt < results[j].CreatedAt
+	})
+	return results, nil
+}
+
+// due reports whether the enabled policy should run now
+func (p TFEDiscardPolicy) due(now time.Time) bool {
+	if !p.Enabled || p.PreviewedAt == nil {
+		return false
+	}
+	if p.LastRunAt == nil {
+		return true
+	}
+	return now.Sub(*p.LastRunAt) >= time.Duration(p.IntervalMinutes)*time.Minute
+}
+
+// start launches the scheduler loop
+func (s *tfeDiscardScheduler) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(tfeDiscardSchedulerTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				policy, err := loadTFEDiscardPolicy()
+				if err != nil {
+					fmt.Printf("Warning: failed to load TFE discard policy: %v\n", err)
+					continue
+				}
+				if !policy.due(time.Now()) {
+					continue
+				}
+				if _, err := s.execute(ctx, false, true); err != nil {
+					fmt.Printf("Warning: scheduled TFE run cleanup failed: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// stop ends the scheduler loop and waits for an execution in progress to finish
+func (s *tfeDiscardScheduler) stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
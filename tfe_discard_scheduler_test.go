@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTFEDiscardPolicyPreviewBeforeRun verifies the preview gate, workspace filters and the history
+func TestTFEDiscardPolicyPreviewBeforeRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFE_TOKEN", "")
+
+	var mu sync.Mutex
+	var discarded []string
+
+	recent := testRunResource("run-recent", "planned", true, true, true)
+	recent["attributes"].(map[string]interface{})["created-at"] = time.Now().Format(time.RFC3339)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "ws-1", "type": "workspaces", "attributes": map[string]interface{}{"name": "api-prod"}},
+				{"id": "ws-2", "type": "workspaces", "attributes": map[string]interface{}{"name": "sandbox"}},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1/runs", func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Query().Get("filter[status]"), "planned")
+		writeJSONAPI(w, map[string]interface{}{
+			"data": []map[string]interface{}{
+				testRunResource("run-old", "planned", true, true, true),
+				testRunResource("run-queued", "pending", false, true, true),
+				recent,
+			},
+		})
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-2/runs", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("excluded workspace must not be inspected")
+	})
+	mux.HandleFunc("/api/v2/runs/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		discarded = append(discarded, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+	require.NoError(t, app.SetTFEConfig(config))
+
+	policy := TFEDiscardPolicy{
+		Enabled:           true,
+		Organization:      "acme",
+		AgeHours:          2,
+		ExcludeWorkspaces: []string{"sand*"},
+		IntervalMinutes:   60,
+	}
+	_, err := app.SaveTFEDiscardPolicy(policy)
+	assert.ErrorContains(t, err, "preview")
+
+	policy.Enabled = false
+	_, err = app.SaveTFEDiscardPolicy(policy)
+	require.NoError(t, err)
+	_, err = app.RunTFEDiscardPolicyNow()
+	assert.ErrorContains(t, err, "preview")
+
+	preview, err := app.PreviewTFEDiscardPolicy()
+	require.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	require.Len(t, preview.Runs, 1)
+	assert.Equal(t, "run-old", preview.Runs[0].RunID)
+	assert.Empty(t, discarded)
+
+	policy.Enabled = true
+	saved, err := app.SaveTFEDiscardPolicy(policy)
+	require.NoError(t, err)
+	require.NotNil(t, saved.PreviewedAt)
+	assert.Equal(t, defaultTFEProfile, saved.Profile)
+	assert.True(t, saved.due(time.Now()))
+
+	// The policy keeps the TFE settings of the profile it was saved from
+	app.env.Store(newEnvContext().withProfile("staging"))
+	execution, err := app.RunTFEDiscardPolicyNow()
+	require.NoError(t, err)
+	assert.False(t, execution.DryRun)
+	assert.Equal(t, []string{"/api/v2/runs/run-old/actions/discard"}, discarded)
+
+	saved, err = app.GetTFEDiscardPolicy()
+	require.NoError(t, err)
+	assert.False(t, saved.due(time.Now()), "the policy ran within its interval")
+
+	// Changing which runs are selected requires a new preview
+	policy.DiscardPending = true
+	_, err = app.SaveTFEDiscardPolicy(policy)
+	assert.ErrorContains(t, err, "preview")
+
+	history, err := app.GetTFEDiscardHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.False(t, history[0].DryRun)
+	assert.True(t, history[1].DryRun)
+
+	app.tfeDiscardScheduler.start()
+	app.tfeDiscardScheduler.stop()
+}
+
+// TestScheduledTFEDiscardWithoutToken verifies a scheduled execution without a token is recorded and waits for the interval
+func TestScheduledTFEDiscardWithoutToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFE_TOKEN", "")
+	t.Setenv("TFE_ENDPOINT", "")
+
+	previewed := time.Now().Add(-time.Hour)
+	require.NoError(t, saveStateFile(tfeDiscardPolicyFile, TFEDiscardPolicy{
+		Enabled:         true,
+		Profile:         defaultTFEProfile,
+		Organization:    "acme",
+		AgeHours:        2,
+		IntervalMinutes: 60,
+		PreviewedAt:     &previewed,
+	}))
+	app := NewApp()
+
+	_, err := app.RunTFEDiscardPolicyNow()
+	assert.ErrorContains(t, err, "no TFE token")
+
+	execution, err := app.tfeDiscardScheduler.execute(context.Background(), false, true)
+	require.NoError(t, err)
+	assert.Contains(t, execution.Error, "no TFE token is configured for profile 'default'")
+
+	policy, err := app.GetTFEDiscardPolicy()
+	require.NoError(t, err)
+	require.NotNil(t, policy.LastRunAt)
+	assert.False(t, policy.due(time.Now()), "the next attempt waits for the interval")
+
+	history, err := app.GetTFEDiscardHistory()
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Scheduled)
+}
+
+// TestFailedTFEDiscardPreview verifies a preview that fails does not allow enabling or running the policy
+func TestFailedTFEDiscardPreview(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TFE_TOKEN", "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+	require.NoError(t, app.SetTFEConfig(config))
+	policy := TFEDiscardPolicy{Organization: "acme", AgeHours: 2, IntervalMinutes: 60}
+	_, err := app.SaveTFEDiscardPolicy(policy)
+	require.NoError(t, err)
+
+	preview, err := app.PreviewTFEDiscardPolicy()
+	require.NoError(t, err)
+	assert.Contains(t, preview.Error, "failed to list TFE workspaces")
+
+	saved, err := app.GetTFEDiscardPolicy()
+	require.NoError(t, err)
+	assert.Nil(t, saved.PreviewedAt)
+
+	_, err = app.RunTFEDiscardPolicyNow()
+	assert.ErrorContains(t, err, "preview")
+	policy.Enabled = true
+	_, err = app.SaveTFEDiscardPolicy(policy)
+	assert.ErrorContains(t, err, "preview")
+}
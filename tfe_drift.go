@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	tfeDriftFile = "tfe-drift.json"
+
+	// maxTFEDriftScans bounds how many finished scans are kept on disk
+	maxTFEDriftScans = 50
+)
+
+// Drift states
+const (
+	driftStatusDrifted = "drifted"
+	driftStatusInSync  = "in-sync"
+	driftStatusErrored = "errored"
+)
+
+// tfeRunPollInterval is how often a queued run is polled until it finishes
+var tfeRunPollInterval = 10 * time.Second
+
+// TFEDriftState is the last known drift state of a workspace
+type TFEDriftState struct {
+	Organization   string          `json:"organization"`
+	Workspace      string          `json:"workspace"`
+	Status         string          `json:"status"`                   // drifted, in-sync, errored
+	PreviousStatus string          `json:"previousStatus,omitempty"` // last drifted or in-sync status before this check
+	Changed        bool            `json:"changed"`                  // drift appeared or disappeared at this check
+	RunID          string          `json:"runId,omitempty"`
+	URL            string          `json:"url,omitempty"`
+	Summary        *TFEPlanSummary `json:"summary,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	ScanID         string          `json:"scanId"`
+	CheckedAt      time.Time       `json:"checkedAt"`
+	ChangedAt      *time.Time      `json:"changedAt,omitempty"`
+}
+
+// TFEDriftScan is a refresh-only plan fan-out across the workspaces of a selector
+type TFEDriftScan struct {
+	ID           string               `json:"id"`
+	Organization string               `json:"organization"`
+	Profile      string               `json:"profile,omitempty"` // environment profile the scan was started under
+	Selector     TFEWorkspaceSelector `json:"selector"`
+	Workspaces   []string             `json:"workspaces"`
+	Status       string               `json:"status"` // running, completed, canceled, interrupted
+	Total        int                  `json:"total"`
+	Completed    int                  `json:"completed"`
+	Drifted      int                  `json:"drifted"`
+	Errored      int                  `json:"errored"`
+	StartedAt    time.Time            `json:"startedAt"`
+	FinishedAt   *time.Time           `json:"finishedAt,omitempty"`
+}
+
+// TFEDriftReport summarizes the drift state of an organization
+type TFEDriftReport struct {
+	Organization         string          `json:"organization"`
+	LastScan             *TFEDriftScan   `json:"lastScan,omitempty"`
+	Total                int             `json:"total"`
+	Drifted              int             `json:"drifted"`
+	InSync               int             `json:"inSync"`
+	Errored              int             `json:"errored"`
+	ChangedSinceLastScan []TFEDriftState `json:"changedSinceLastScan"`
+	Workspaces           []TFEDriftState `json:"workspaces"`
+}
+
+// tfeDriftStore is the on-disk layout of the drift store, states are keyed by organization/workspace
+type tfeDriftStore struct {
+	States map[string]TFEDriftState `json:"states"`
+	Scans  []TFEDriftScan           `json:"scans"`
+}
+
+// tfeDriftScanner runs drift scans in the background and records their results
+type tfeDriftScanner struct {
+	app     *App
+	mu      sync.Mutex
+	running map[string]*runningTFEDriftScan
+}
+
+type runningTFEDriftScan struct {
+	organization string
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// newTFEDriftScanner creates a scanner without running scans
+func newTFEDriftScanner(app *App) *tfeDriftScanner {
+	return &tfeDriftScanner{app: app, running: make(map[string]*runningTFEDriftScan)}
+}
+
+// createRefreshOnlyRun queues a speculative refresh-only run on a workspace
+func (c *tfeClient) createRefreshOnlyRun(ctx context.Context, workspaceID, message string) (string, error) {
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "runs",
+			"attributes": map[string]interface{}{
+				"message":      message,
+				"refresh-only": true,
+				"plan-only":    true,
+			},
+			"relationships": map[string]interface{}{
+				"workspace": map[string]interface{}{
+					"data": map[string]string{"type": "workspaces", "id": workspaceID},
+				},
+			},
+		},
+	}
+
+	var resp tfeSingleResponse
+	if err := c.do(ctx, http.MethodPost, "/runs", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.ID, nil
+}
+
+// planTFEWorkspaceRefreshOnly queues a refresh-only plan through the TFE API and waits for it to finish
+func (a *App) planTFEWorkspaceRefreshOnly(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+	start := time.Now()
+	result := TFEPlanResult{
+		WorkspaceName: workspace,
+		Message:       execution.Message,
+		CreatedAt:     start,
+	}
+	fail := func(format string, args ...interface{}) TFEPlanResult {
+		result.Status = "errored"
+		result.Error = fmt.Sprintf(format, args...)
+		result.Duration = time.Since(start).Round(time.Second).String()
+		return result
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tfeWorkspacePlanTimeout)
+	defer cancel()
+
+	ws, err := client.getWorkspace(ctx, config.Organization, workspace)
+	if err != nil {
+		return fail("failed to get workspace: %v", err)
+	}
+
+	message := execution.Message
+	if message == "" {
+		message = "Drift detection from Yak GUI"
+	}
+	runID, err := client.createRefreshOnlyRun(ctx, ws.ID, message)
+	if err != nil {
+		return fail("failed to queue refresh-only plan: %v", err)
+	}
+	result.RunID = runID
+
+	ticker := time.NewTicker(tfeRunPollInterval)
+	defer ticker.Stop()
+	for {
+		run, err := client.getRun(ctx, config.Organization, runID)
+		if err == nil && run.Status == "policy_soft_failed" {
+			// The plan is complete but the run would hold the workspace queue until someone discards it
+			if err := client.runAction(ctx, runID, "discard", "Drift scan finished"); err != nil {
+				fmt.Printf("Warning: failed to discard soft-failed drift run %s: %v\n", runID, err)
+			}
+			result.Status = run.Status
+			result.HasChanges = run.HasChanges
+			result.URL = run.URL
+			result.Duration = time.Since(start).Round(time.Second).String()
+			return result
+		}
+		if err == nil && tfeFinalRunStatuses[run.Status] {
+			result.Status = run.Status
+			result.HasChanges = run.HasChanges
+			result.URL = run.URL
+			result.Duration = time.Since(start).Round(time.Second).String()
+			if run.Status != "planned_and_finished" {
+				result.Error = fmt.Sprintf("refresh-only plan finished with status %s", run.Status)
+			}
+			return result
+		}
+
+		select {
+		case <-ctx.Done():
+			// Leave the TFE queue clean when the scan is canceled or times out
+			cancelCtx, cancelCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			client.runAction(cancelCtx, runID, "cancel", "Drift scan canceled")
+			cancelCancel()
+			return fail("refresh-only plan did not finish: %v", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// driftStateKey identifies a workspace in the drift store
+func driftStateKey(organization, workspace string) string {
+	return organization + "/" + workspace
+}
+
+// load reads the drift store, the caller must hold s.mu
+func (s *tfeDriftScanner) load() (*tfeDriftStore, error) {
+	store := &tfeDriftStore{}
+	if _, err := loadStateFile(tfeDriftFile, store); err != nil {
+		return nil, err
+	}
+	if store.States == nil {
+		store.States = map[string]TFEDriftState{}
+	}
+	return store, nil
+}
+
+// update applies fn to the drift store and saves it
+func (s *tfeDriftScanner) update(fn func(store *tfeDriftStore)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store := &tfeDriftStore{}
+	return updateStateFile(tfeDriftFile, store, func() error {
+		if store.States == nil {
+			store.States = map[string]TFEDriftState{}
+		}
+		fn(store)
+
+		sort.Slice(store.Scans, func(i, j int) bool { return store.Scans[i].StartedAt.After(store.Scans[j].StartedAt) })
+		if len(store.Scans) > maxTFEDriftScans {
+			store.Scans = store.Scans[:maxTFEDriftScans]
+		}
+		return nil
+	})
+}
+
+// putScan replaces or adds a scan in the store
+func (store *tfeDriftStore) putScan(scan TFEDriftScan) {
+	for i := range store.Scans {
+		if store.Scans[i].ID == scan.ID {
+			store.Scans[i] = scan
+			return
+		}
+	}
+	store.Scans = append(store.Scans, scan)
+}
+
+// driftStateFromResult derives the new drift state of a workspace from a refresh-only plan
+func driftStateFromResult(previous *TFEDriftState, organization, scanID string, result TFEPlanResult, now time.Time) TFEDriftState {
+	state := TFEDriftState{
+		Organization: organization,
+		Workspace:    result.WorkspaceName,
+		RunID:        result.RunID,
+		URL:          result.URL,
+		Summary:      result.Summary,
+		ScanID:       scanID,
+		CheckedAt:    now,
+	}
+
+	lastKnown := ""
+	if previous != nil {
+		state.ChangedAt = previous.ChangedAt
+		lastKnown = previous.Status
+		if lastKnown == driftStatusErrored {
+			lastKnown = previous.PreviousStatus
+		}
+	}
+	state.PreviousStatus = lastKnown
+
+	if result.isErrored() {
+		// A failed check says nothing about drift, keep the last known status for the next comparison
+		state.Status = driftStatusErrored
+		state.Error = result.Error
+		return state
+	}
+
+	state.Status = driftStatusInSync
+	if result.HasChanges {
+		state.Status = driftStatusDrifted
+	}
+	if state.Status != lastKnown && (lastKnown != "" || state.Status == driftStatusDrifted) {
+		state.Changed = true
+		state.ChangedAt = &now
+	}
+	return state
+}
+
+// StartTFEDriftScan queues refresh-only plans on the selected workspaces at a bounded concurrency.
+// A "tfe-drift:result" event is emitted per workspace and "tfe-drift:done" once the scan finishes.
+func (a *App) StartTFEDriftScan(config TFEConfig, selector TFEWorkspaceSelector, concurrency int) (*TFEDriftScan, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("drift scans need a TFE API token")
+	}
+	if concurrency <= 0 || concurrency > defaultTFEPlanConcurrency {
+		concurrency = defaultTFEPlanConcurrency
+	}
+
+	all, err := a.GetTFEWorkspaces(config)
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []string
+	for _, ws := range all {
+		if selector.matches(ws) {
+			workspaces = append(workspaces, ws.Name)
+		}
+	}
+	if len(workspaces) == 0 {
+		return nil, fmt.Errorf("no workspaces match the drift scan selector")
+	}
+	sort.Strings(workspaces)
+
+	s := a.tfeDriftScanner
+	env := a.currentEnv()
+	scan := TFEDriftScan{
+		ID:           fmt.Sprintf("drift-%d", time.Now().UnixNano()),
+		Organization: config.Organization,
+		Profile:      env.profile,
+		Selector:     selector,
+		Workspaces:   workspaces,
+		Status:       "running",
+		Total:        len(workspaces),
+		StartedAt:    time.Now(),
+	}
+
+	ctx, cancel := context.WithCancel(withEnvContext(context.Background(), env))
+	running := &runningTFEDriftScan{organization: config.Organization, cancel: cancel, done: make(chan struct{})}
+
+	s.mu.Lock()
+	for _, r := range s.running {
+		if r.organization == config.Organization {
+			s.mu.Unlock()
+			cancel()
+			return nil, fmt.Errorf("a drift scan is already running for organization %s", config.Organization)
+		}
+	}
+	s.running[scan.ID] = running
+	s.mu.Unlock()
+
+	if err := s.update(func(store *tfeDriftStore) { store.putScan(scan) }); err != nil {
+		fmt.Printf("Warning: failed to persist drift scan %s: %v\n", scan.ID, err)
+	}
+
+	go s.run(ctx, config, scan, concurrency, running)
+	return &scan, nil
+}
+
+// run executes the refresh-only fan-out of a scan and records each workspace state
+func (s *tfeDriftScanner) run(ctx context.Context, config TFEConfig, scan TFEDriftScan, concurrency int, running *runningTFEDriftScan) {
+	defer close(running.done)
+	defer running.cancel()
+
+	execution := TFEPlanExecution{
+		Message:     fmt.Sprintf("Drift scan %s", scan.ID),
+		Wait:        true,
+		RefreshOnly: true,
+	}
+
+	s.app.tfePlanJobs.fanOutTFEPlans(ctx, config, scan.Workspaces, execution, concurrency, func(result TFEPlanResult) {
+		var state TFEDriftState
+		err := s.update(func(store *tfeDriftStore) {
+			key := driftStateKey(scan.Organization, result.WorkspaceName)
+			var previous *TFEDriftState
+			if existing, ok := store.States[key]; ok {
+				previous = &existing
+			}
+			state = driftStateFromResult(previous, scan.Organization, scan.ID, result, time.Now())
+			store.States[key] = state
+
+			scan.Completed++
+			switch state.Status {
+			case driftStatusDrifted:
+				scan.Drifted++
+			case driftStatusErrored:
+				scan.Errored++
+			}
+			store.putScan(scan)
+		})
+		if err != nil {
+			fmt.Printf("Warning: failed to record drift of %s: %v\n", result.WorkspaceName, err)
+		}
+		s.app.emitEvent("tfe-drift:result", state)
+	})
+
+	now := time.Now()
+	scan.FinishedAt = &now
+	scan.Status = "completed"
+	if ctx.Err() != nil {
+		scan.Status = "canceled"
+	}
+	if err := s.update(func(store *tfeDriftStore) { store.putScan(scan) }); err != nil {
+		fmt.Printf("Warning: failed to persist drift scan %s: %v\n", scan.ID, err)
+	}
+
+	s.mu.Lock()
+	delete(s.running, scan.ID)
+	s.mu.Unlock()
+	s.app.emitEvent("tfe-drift:done", scan)
+}
+
+// CancelTFEDriftScan stops a running drift scan, queued plans are canceled on TFE
+func (a *App) CancelTFEDriftScan(id string) error {
+	s := a.tfeDriftScanner
+	s.mu.Lock()
+	running := s.running[id]
+	s.mu.Unlock()
+
+	if running == nil {
+		return fmt.Errorf("drift scan '%s' is not running", id)
+	}
+	running.cancel()
+	<-running.done
+	return nil
+}
+
+// recover marks scans that were running when the application last stopped as interrupted
+func (s *tfeDriftScanner) recover() error {
+	return s.update(func(store *tfeDriftStore) {
+		for i := range store.Scans {
+			if store.Scans[i].Status == "running" && s.running[store.Scans[i].ID] == nil {
+				store.Scans[i].Status = "interrupted"
+			}
+		}
+	})
+}
+
+// stopAll cancels every running scan and waits for them to persist their state
+func (s *tfeDriftScanner) stopAll() {
+	s.mu.Lock()
+	var scans []*runningTFEDriftScan
+	for _, running := range s.running {
+		scans = append(scans, running)
+	}
+	s.mu.Unlock()
+
+	for _, running := range scans {
+		running.cancel()
+		<-running.done
+	}
+}
+
+// GetTFEDriftScans returns running and past drift scans, most recent first
+func (a *App) GetTFEDriftScans() ([]TFEDriftScan, error) {
+	s := a.tfeDriftScanner
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	scans := store.Scans
+	if scans == nil {
+		scans = []TFEDriftScan{}
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].StartedAt.After(scans[j].StartedAt) })
+	return scans, nil
+}
+
+// GetDriftReport summarizes the drift state of an organization and the workspaces whose drift changed in the last scan
+func (a *App) GetDriftReport(organization string) (*TFEDriftReport, error) {
+	s := a.tfeDriftScanner
+	s.mu.Lock()
+	store, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TFEDriftReport{
+		Organization:         organization,
+		ChangedSinceLastScan: []TFEDriftState{},
+		Workspaces:           []TFEDriftState{},
+	}
+
+	sort.Slice(store.Scans, func(i, j int) bool { return store.Scans[i].StartedAt.After(store.Scans[j].StartedAt) })
+	for i := range store.Scans {
+		if store.Scans[i].Organization == organization && store.Scans[i].Status != "running" {
+			report.LastScan = &store.Scans[i]
+			break
+		}
+	}
+
+	for _, state := range store.States {
+		if state.Organization != organization {
+			continue
+		}
+		report.Workspaces = append(report.Workspaces, state)
+		switch state.Status {
+		case driftStatusDrifted:
+			report.Drifted++
+		case driftStatusInSync:
+			report.InSync++
+		case driftStatusErrored:
+			report.Errored++
+		}
+		if report.LastScan != nil && state.ScanID == report.LastScan.ID && state.Changed {
+			report.ChangedSinceLastScan = append(report.ChangedSinceLastScan, state)
+		}
+	}
+	report.Total = len(report.Workspaces)
+
+	// Drifted workspaces first
+	sort.Slice(report.Workspaces, func(i, j int) bool {
+		wi, wj := report.Workspaces[i], report.Workspaces[j]
+		if (wi.Status == driftStatusDrifted) != (wj.Status == driftStatusDrifted) {
+			return wi.Status == driftStatusDrifted
+		}
+		return wi.Workspace < wj.Workspace
+	})
+	sort.Slice(report.ChangedSinceLastScan, func(i, j int) bool {
+		return report.ChangedSinceLastScan[i].Workspace < report.ChangedSinceLastScan[j].Workspace
+	})
+	return report, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTFEDriftScans verifies refresh-only runs are queued and drift changes are reported between scans
+func TestTFEDriftScans(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	interval := tfeRunPollInterval
+	tfeRunPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { tfeRunPollInterval = interval })
+
+	var mu sync.Mutex
+	drifted := map[string]bool{"ws-db": true}
+	runWorkspace := map[string]string{}
+	polls := map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "ws-api", "type": "workspaces", "attributes": map[string]interface{}{"name": "api", "tag-names": []string{"prod"}}},
+				{"id": "ws-db", "type": "workspaces", "attributes": map[string]interface{}{"name": "db", "tag-names": []string{"prod"}}},
+				{"id": "ws-sandbox", "type": "workspaces", "attributes": map[string]interface{}{"name": "sandbox"}},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v2/organizations/acme/workspaces/")
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{"id": "ws-" + name, "type": "workspaces", "attributes": map[string]interface{}{"name": name}},
+		})
+	})
+	mux.HandleFunc("/api/v2/runs", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Data struct {
+				Attributes    map[string]interface{} `json:"attributes"`
+				Relationships struct {
+					Workspace struct {
+						Data tfeResourceRef `json:"data"`
+					} `json:"workspace"`
+				} `json:"relationships"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body.Data.Attributes["refresh-only"])
+		assert.Equal(t, true, body.Data.Attributes["plan-only"])
+
+		mu.Lock()
+		id := "run-" + body.Data.Relationships.Workspace.Data.ID + "-" + time.Now().Format("150405.000000000")
+		runWorkspace[id] = body.Data.Relationships.Workspace.Data.ID
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		writeJSONAPI(w, map[string]interface{}{"data": map[string]interface{}{"id": id, "type": "runs"}})
+	})
+	mux.HandleFunc("/api/v2/runs/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v2/runs/")
+		if strings.HasSuffix(rest, "/plan/json-output") {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"resource_drift": []map[string]interface{}{
+					{"address": "aws_db_instance.main", "type": "aws_db_instance", "mode": "managed", "change": map[string]interface{}{"actions": []string{"update"}}},
+				},
+			})
+			return
+		}
+
+		mu.Lock()
+		wsID := runWorkspace[rest]
+		polls[rest]++
+		status := "planning"
+		if polls[rest] > 1 {
+			status = "planned_and_finished"
+		}
+		hasChanges := drifted[wsID]
+		mu.Unlock()
+
+		run := testRunResource(rest, status, false, false, false)
+		run["attributes"].(map[string]interface{})["has-changes"] = hasChanges
+		run["relationships"].(map[string]interface{})["workspace"] = map[string]interface{}{"data": map[string]string{"id": wsID, "type": "workspaces"}}
+		writeJSONAPI(w, map[string]interface{}{"data": run})
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+	scanAndWait := func() {
+		scan, err := app.StartTFEDriftScan(config, TFEWorkspaceSelector{Tag: "prod"}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api", "db"}, scan.Workspaces)
+
+		_, err = app.StartTFEDriftScan(config, TFEWorkspaceSelector{}, 2)
+		assert.Error(t, err, "only one scan per organization")
+
+		app.tfeDriftScanner.mu.Lock()
+		running := app.tfeDriftScanner.running[scan.ID]
+		app.tfeDriftScanner.mu.Unlock()
+		if running != nil {
+			<-running.done
+		}
+	}
+
+	scanAndWait()
+	report, err := app.GetDriftReport("acme")
+	require.NoError(t, err)
+	require.NotNil(t, report.LastScan)
+	assert.Equal(t, "completed", report.LastScan.Status)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 1, report.Drifted)
+	assert.Equal(t, 1, report.InSync)
+	assert.Equal(t, "db", report.Workspaces[0].Workspace)
+	require.NotNil(t, report.Workspaces[0].Summary)
+	assert.Equal(t, 1, report.Workspaces[0].Summary.Update)
+	require.Len(t, report.ChangedSinceLastScan, 1, "new drift is reported on the first scan")
+
+	mu.Lock()
+	drifted["ws-api"] = true
+	mu.Unlock()
+
+	scanAndWait()
+	report, err = app.GetDriftReport("acme")
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Drifted)
+	require.Len(t, report.ChangedSinceLastScan, 1)
+	assert.Equal(t, "api", report.ChangedSinceLastScan[0].Workspace)
+	assert.Equal(t, driftStatusInSync, report.ChangedSinceLastScan[0].PreviousStatus)
+
+	scans, err := app.GetTFEDriftScans()
+	require.NoError(t, err)
+	assert.Len(t, scans, 2)
+}
+
+// TestTFEDriftSoftFailedRun verifies a refresh-only run stopped by a soft policy failure is reported and discarded
+func TestTFEDriftSoftFailedRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var mu sync.Mutex
+	var discarded []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/api", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{"id": "ws-1", "type": "workspaces", "attributes": map[string]interface{}{"name": "api"}},
+		})
+	})
+	mux.HandleFunc("/api/v2/runs", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		writeJSONAPI(w, map[string]interface{}{"data": map[string]interface{}{"id": "run-1", "type": "runs"}})
+	})
+	mux.HandleFunc("/api/v2/runs/run-1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{"data": testRunResource("run-1", "policy_soft_failed", false, false, true)})
+	})
+	mux.HandleFunc("/api/v2/runs/run-1/actions/discard", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		discarded = append(discarded, "run-1")
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+	result := app.planTFEWorkspaceRefreshOnly(context.Background(), config, "api", TFEPlanExecution{})
+	assert.Equal(t, "policy_soft_failed", result.Status)
+	assert.False(t, result.isErrored(), "the plan finished, its drift is known")
+	assert.True(t, result.HasChanges)
+	mu.Lock()
+	assert.Equal(t, []string{"run-1"}, discarded)
+	mu.Unlock()
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+const (
+	tfeLockAuditFile = "tfe-lock-audit.json"
+
+	// maxTFELockAuditEntries bounds the audit log so it does not grow forever
+	maxTFELockAuditEntries = 5000
+)
+
+// tfeFinalRunStatuses are run statuses after which a workspace can be locked safely.
+// policy_soft_failed is not final, the run waits for a policy override or a discard.
+var tfeFinalRunStatuses = map[string]bool{
+	"applied":              true,
+	"planned_and_finished": true,
+	"discarded":            true,
+	"errored":              true,
+	"canceled":             true,
+	"force_canceled":       true,
+}
+
+// TFEWorkspaceLock represents the lock state of a workspace
+type TFEWorkspaceLock struct {
+	WorkspaceID   string     `json:"workspaceId"`
+	WorkspaceName string     `json:"workspaceName"`
+	Locked        bool       `json:"locked"`
+	LockedBy      string     `json:"lockedBy,omitempty"`     // username, team name or run ID holding the lock
+	LockedByType  string     `json:"lockedByType,omitempty"` // users, teams or runs
+	Reason        string     `json:"reason,omitempty"`       // from the audit log, empty when locked outside of the GUI
+	Since         *time.Time `json:"since,omitempty"`
+}
+
+// TFELockResult is the outcome of locking or unlocking one workspace
+type TFELockResult struct {
+	WorkspaceName string `json:"workspaceName"`
+	Success       bool   `json:"success"`
+	Locked        *bool  `json:"locked"` // lock state after the attempt, nil when it could not be read
+	Message       string `json:"message,omitempty"`
+	Warning       string `json:"warning,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TFELockAuditEntry records a lock or unlock attempt
+type TFELockAuditEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Organization string    `json:"organization"`
+	Workspace    string    `json:"workspace"`
+	Action       string    `json:"action"` // lock, unlock, force-unlock
+	Reason       string    `json:"reason,omitempty"`
+	User         string    `json:"user"`
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// lockAction is the API action path for a lock operation
+func lockAction(lock, force bool) string {
+	switch {
+	case lock:
+		return "lock"
+	case force:
+		return "force-unlock"
+	default:
+		return "unlock"
+	}
+}
+
+// setWorkspaceLock locks or unlocks a workspace, a reason is only sent when locking
+func (c *tfeClient) setWorkspaceLock(ctx context.Context, workspaceID, action, reason string) error {
+	var body interface{}
+	if action == "lock" && reason != "" {
+		body = map[string]string{"reason": reason}
+	}
+	return c.do(ctx, http.MethodPost, "/workspaces/"+url.PathEscape(workspaceID)+"/actions/"+action, nil, body, nil)
+}
+
+// toTFEWorkspaceLock maps a workspace resource and its included lock holder into a lock state
+func (r tfeResource) toTFEWorkspaceLock(included []tfeResource) (TFEWorkspaceLock, error) {
+	var attrs tfeWorkspaceAttributes
+	if err := json.Unmarshal(r.Attributes, &attrs); err != nil {
+		return TFEWorkspaceLock{}, fmt.Errorf("failed to parse workspace %s: %w", r.ID, err)
+	}
+
+	lock := TFEWorkspaceLock{WorkspaceID: r.ID, WorkspaceName: attrs.Name, Locked: attrs.Locked}
+	holder, ok := r.Relationships["locked-by"]
+	if !attrs.Locked || !ok || holder.Data == nil {
+		return lock, nil
+	}
+
+	lock.LockedBy = holder.Data.ID
+	lock.LockedByType = holder.Data.Type
+	for _, inc := range included {
+		if inc.Type != holder.Data.Type || inc.ID != holder.Data.ID {
+			continue
+		}
+		var holderAttrs struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+		}
+		if json.Unmarshal(inc.Attributes, &holderAttrs) == nil {
+			if holderAttrs.Username != "" {
+				lock.LockedBy = holderAttrs.Username
+			} else if holderAttrs.Name != "" {
+				lock.LockedBy = holderAttrs.Name
+			}
+		}
+	}
+	return lock, nil
+}
+
+// getWorkspaceLock fetches the lock state of a workspace by ID or name
+func (c *tfeClient) getWorkspaceLock(ctx context.Context, organization, workspace string) (TFEWorkspaceLock, error) {
+	ws, err := c.getWorkspace(ctx, organization, workspace)
+	if err != nil {
+		return TFEWorkspaceLock{}, err
+	}
+
+	query := url.Values{}
+	query.Set("include", "locked_by")
+
+	var resp tfeSingleResponse
+	if err := c.do(ctx, http.MethodGet, "/workspaces/"+url.PathEscape(ws.ID), query, nil, &resp); err != nil {
+		return TFEWorkspaceLock{}, err
+	}
+	return resp.Data.toTFEWorkspaceLock(resp.Included)
+}
+
+// currentLocalUser returns the operator name recorded in the audit log
+func currentLocalUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// recordTFELockAudit prepends an entry to the lock audit log
+func recordTFELockAudit(entry TFELockAuditEntry) error {
+	var entries []TFELockAuditEntry
+	return updateStateFile(tfeLockAuditFile, &entries, func() error {
+		entries = append([]TFELockAuditEntry{entry}, entries...)
+		if len(entries) > maxTFELockAuditEntries {
+			entries = entries[:maxTFELockAuditEntries]
+		}
+		return nil
+	})
+}
+
+// GetTFELockAuditLog returns lock audit entries, most recent first, optionally for a single workspace
+func (a *App) GetTFELockAuditLog(workspace string, limit int) ([]TFELockAuditEntry, error) {
+	var entries []TFELockAuditEntry
+	if _, err := loadStateFile(tfeLockAuditFile, &entries); err != nil {
+		return nil, err
+	}
+
+	filtered := []TFELockAuditEntry{}
+	for _, entry := range entries {
+		if workspace != "" && entry.Workspace != workspace {
+			continue
+		}
+		filtered = append(filtered, entry)
+		if limit > 0 && len(filtered) == limit {
+			break
+		}
+	}
+	return filtered, nil
+}
+
+// annotateTFEWorkspaceLock fills the reason and lock time from the last successful lock in the audit log
+func annotateTFEWorkspaceLock(lock *TFEWorkspaceLock, organization string, audit []TFELockAuditEntry) {
+	if !lock.Locked {
+		return
+	}
+	for _, entry := range audit {
+		if entry.Workspace != lock.WorkspaceName || entry.Organization != organization || !entry.Success {
+			continue
+		}
+		// An unlock more recent than any lock means the current lock was taken outside of the GUI
+		if entry.Action == "lock" {
+			lock.Reason = entry.Reason
+			since := entry.Timestamp
+			lock.Since = &since
+		}
+		return
+	}
+}
+
+// GetTFEWorkspaceLocks returns who holds the lock of each workspace, why and since when.
+// Without workspace names it returns every locked workspace of the organization.
+func (a *App) GetTFEWorkspaceLocks(config TFEConfig, workspaceNames []string) ([]TFEWorkspaceLock, error) {
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var locks []TFEWorkspaceLock
+	if len(workspaceNames) == 0 {
+		if config.Organization == "" {
+			return nil, &SiteNotConfiguredError{Setting: "tfe.organization"}
+		}
+		query := url.Values{}
+		query.Set("include", "locked_by")
+		resources, included, err := client.list(ctx, "/organizations/"+url.PathEscape(config.Organization)+"/workspaces", query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list TFE workspaces: %w", err)
+		}
+		for _, resource := range resources {
+			lock, err := resource.toTFEWorkspaceLock(included)
+			if err != nil {
+				return nil, err
+			}
+			if lock.Locked {
+				locks = append(locks, lock)
+			}
+		}
+	} else {
+		for _, name := range workspaceNames {
+			lock, err := client.getWorkspaceLock(ctx, config.Organization, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get lock of workspace %s: %w", name, err)
+			}
+			locks = append(locks, lock)
+		}
+	}
+
+	audit, err := a.GetTFELockAuditLog("", 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range locks {
+		annotateTFEWorkspaceLock(&locks[i], config.Organization, audit)
+	}
+	if locks == nil {
+		locks = []TFEWorkspaceLock{}
+	}
+	return locks, nil
+}
+
+// LockTFEWorkspace locks workspaces with a reason and returns a result per workspace.
+// With checkStatus, workspaces whose current run is still in progress are not locked.
+func (a *App) LockTFEWorkspace(config TFEConfig, workspaceNames []string, reason string, checkStatus bool) ([]TFELockResult, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("a lock reason is required")
+	}
+	return a.changeTFEWorkspaceLocks(config, workspaceNames, true, reason, checkStatus, false)
+}
+
+// UnlockTFEWorkspace unlocks workspaces and returns a result per workspace, force releases locks held by others
+func (a *App) UnlockTFEWorkspace(config TFEConfig, workspaceNames []string, force bool) ([]TFELockResult, error) {
+	return a.changeTFEWorkspaceLocks(config, workspaceNames, false, "", false, force)
+}
+
+// changeTFEWorkspaceLocks locks or unlocks each workspace in turn and audits every attempt
+func (a *App) changeTFEWorkspaceLocks(config TFEConfig, workspaceNames []string, lock bool, reason string, checkStatus, force bool) ([]TFELockResult, error) {
+	if len(workspaceNames) == 0 {
+		return nil, fmt.Errorf("at least one workspace is required")
+	}
+
+	action := lockAction(lock, force)
+	operator := currentLocalUser()
+
+	results := make([]TFELockResult, 0, len(workspaceNames))
+	for _, name := range workspaceNames {
+		var result TFELockResult
+		var err error
+		if config.Token != "" {
+			result, err = a.changeTFEWorkspaceLockWithAPI(config, name, action, reason, checkStatus)
+		} else {
+			result, err = a.changeTFEWorkspaceLockWithYak(config, name, lock, reason, checkStatus, force)
+		}
+		result.WorkspaceName = name
+		result.Success = err == nil
+
+		entry := TFELockAuditEntry{
+			Timestamp:    time.Now(),
+			Organization: config.Organization,
+			Workspace:    name,
+			Action:       action,
+			Reason:       reason,
+			User:         operator,
+			Success:      err == nil,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			entry.Error = err.Error()
+		}
+		results = append(results, result)
+
+		if auditErr := recordTFELockAudit(entry); auditErr != nil {
+			fmt.Printf("Warning: failed to record lock audit entry for %s: %v\n", name, auditErr)
+		}
+	}
+	return results, nil
+}
+
+// changeTFEWorkspaceLockWithAPI locks or unlocks a single workspace through the TFE API
+func (a *App) changeTFEWorkspaceLockWithAPI(config TFEConfig, name, action, reason string, checkStatus bool) (TFELockResult, error) {
+	var result TFELockResult
+	client, err := newTFEClient(config)
+	if err != nil {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	current, err := client.getWorkspaceLock(ctx, config.Organization, name)
+	if err != nil {
+		return result, err
+	}
+	result.Locked = &current.Locked
+
+	if action == "lock" {
+		if current.Locked {
+			return result, fmt.Errorf("workspace is already locked by %s", current.LockedBy)
+		}
+		if checkStatus {
+			ws, err := client.getWorkspace(ctx, config.Organization, current.WorkspaceID)
+			if err != nil {
+				return result, err
+			}
+			if ws.LastRun != "" {
+				run, err := client.getRun(ctx, config.Organization, ws.LastRun)
+				if err != nil {
+					return result, err
+				}
+				switch {
+				case run.Status == "policy_soft_failed":
+					return result, fmt.Errorf("run %s failed a soft policy check and waits for an override or a discard", run.ID)
+				case !tfeFinalRunStatuses[run.Status]:
+					return result, fmt.Errorf("run %s is in progress (%s)", run.ID, run.Status)
+				}
+			}
+		}
+	} else if !current.Locked {
+		result.Message = "workspace was not locked"
+		return result, nil
+	}
+
+	if err := client.setWorkspaceLock(ctx, current.WorkspaceID, action, reason); err != nil {
+		// The request may have failed after TFE applied it, read the state back
+		result.Locked = nil
+		if after, getErr := client.getWorkspaceLock(ctx, config.Organization, current.WorkspaceID); getErr == nil {
+			result.Locked = &after.Locked
+		}
+		return result, err
+	}
+
+	locked := action == "lock"
+	result.Locked = &locked
+	switch {
+	case locked:
+		result.Message = fmt.Sprintf("locked: %s", reason)
+	case current.LockedBy != "":
+		result.Message = fmt.Sprintf("released lock held by %s", current.LockedBy)
+	default:
+		result.Message = "unlocked"
+	}
+	return result, nil
+}
+
+// changeTFEWorkspaceLockWithYak locks or unlocks a single workspace with yak, used when no API token is available.
+// yak cannot send a lock reason, the result warns that it is only kept in the audit log.
+func (a *App) changeTFEWorkspaceLockWithYak(config TFEConfig, name string, lock bool, reason string, checkStatus, force bool) (TFELockResult, error) {
+	var result TFELockResult
+	if lock && reason != "" {
+		result.Warning = "the lock reason was not sent to TFE without an API token, it is only recorded in the local audit log"
+	}
+
+	args := []string{"tfe", "workspace", "unlock"}
+	if lock {
+		args[2] = "lock"
+	}
+	if config.Organization != "" {
+		args = append(args, "--organization", config.Organization)
+	}
+	args = append(args, "--workspaces", name)
+	if lock && checkStatus {
+		args = append(args, "--check-status")
+	}
+	if !lock && force {
+		args = append(args, "--force")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cmd := a.yakCommand(ctx, args...)
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
+		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
+	)
+
+	// The lock state is unknown when yak fails, there is no token to read it back
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return result, fmt.Errorf("%w - %s", err, strings.TrimSpace(string(output)))
+	}
+	result.Locked = &lock
+	result.Message = strings.TrimSpace(string(output))
+	return result, nil
+}
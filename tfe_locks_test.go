@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTFEWorkspaceLocksWithReasonAndAudit verifies bulk locking, lock holders and the audit log
+func TestTFEWorkspaceLocksWithReasonAndAudit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var mu sync.Mutex
+	locked := map[string]bool{"ws-2": true}
+	names := map[string]string{"api": "ws-1", "db": "ws-2"}
+	var lockReason string
+
+	workspaceDoc := func(id string) map[string]interface{} {
+		name := ""
+		for n, wsID := range names {
+			if wsID == id {
+				name = n
+			}
+		}
+		resource := map[string]interface{}{
+			"id":         id,
+			"type":       "workspaces",
+			"attributes": map[string]interface{}{"name": name, "locked": locked[id]},
+		}
+		doc := map[string]interface{}{"data": resource}
+		if locked[id] {
+			resource["relationships"] = map[string]interface{}{
+				"locked-by": map[string]interface{}{"data": map[string]string{"id": "user-1", "type": "users"}},
+			}
+			doc["included"] = []map[string]interface{}{
+				{"id": "user-1", "type": "users", "attributes": map[string]string{"username": "alice"}},
+			}
+		}
+		return doc
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, ok := names[r.URL.Path[len("/api/v2/organizations/acme/workspaces/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSONAPI(w, workspaceDoc(id))
+	})
+	mux.HandleFunc("/api/v2/workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v2/workspaces/ws-1", "/api/v2/workspaces/ws-2":
+			writeJSONAPI(w, workspaceDoc(r.URL.Path[len("/api/v2/workspaces/"):]))
+		case "/api/v2/workspaces/ws-1/actions/lock":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			lockReason = body["reason"]
+			locked["ws-1"] = true
+			writeJSONAPI(w, workspaceDoc("ws-1"))
+		case "/api/v2/workspaces/ws-1/actions/unlock":
+			locked["ws-1"] = false
+			writeJSONAPI(w, workspaceDoc("ws-1"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+
+	_, err := app.LockTFEWorkspace(config, []string{"api"}, " ", false)
+	assert.Error(t, err, "a lock reason is required")
+
+	results, err := app.LockTFEWorkspace(config, []string{"api", "db"}, "incident INC-42", false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.Contains(t, results[1].Error, "already locked by alice")
+	require.NotNil(t, results[1].Locked)
+	assert.True(t, *results[1].Locked, "the failed lock reports the lock held by alice")
+	assert.Equal(t, "incident INC-42", lockReason)
+
+	locks, err := app.GetTFEWorkspaceLocks(config, []string{"api", "db"})
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.True(t, locks[0].Locked)
+	assert.Equal(t, "alice", locks[0].LockedBy)
+	assert.Equal(t, "incident INC-42", locks[0].Reason)
+	require.NotNil(t, locks[0].Since)
+	assert.Empty(t, locks[1].Reason, "db was locked outside of the GUI")
+
+	results, err = app.UnlockTFEWorkspace(config, []string{"api", "db"}, false)
+	require.NoError(t, err)
+	assert.True(t, results[0].Success)
+	require.NotNil(t, results[0].Locked)
+	assert.False(t, *results[0].Locked)
+	assert.False(t, results[1].Success, "TFE rejects the unlock of db")
+	require.NotNil(t, results[1].Locked)
+	assert.True(t, *results[1].Locked, "the failed unlock reports db as still locked")
+
+	audit, err := app.GetTFELockAuditLog("api", 0)
+	require.NoError(t, err)
+	require.Len(t, audit, 2)
+	assert.Equal(t, "unlock", audit[0].Action)
+	assert.Equal(t, "lock", audit[1].Action)
+	assert.Equal(t, "incident INC-42", audit[1].Reason)
+
+	failed, err := app.GetTFELockAuditLog("db", 0)
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	assert.False(t, failed[0].Success)
+	assert.False(t, failed[1].Success)
+}
+
+// TestTFEWorkspaceLockWithYak verifies the yak fallback warns that the reason is not sent and reports unknown state on failure
+func TestTFEWorkspaceLockWithYak(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "yak"), []byte(`#!/bin/sh
+case "$*" in
+*--workspaces\ db*) echo "workspace db not found"; exit 1 ;;
+esac
+echo "done"
+`), 0755))
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	app := NewApp()
+	results, err := app.LockTFEWorkspace(TFEConfig{Organization: "acme"}, []string{"api", "db"}, "incident INC-42", false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.True(t, results[0].Success)
+	require.NotNil(t, results[0].Locked)
+	assert.True(t, *results[0].Locked)
+	assert.Contains(t, results[0].Warning, "lock reason was not sent")
+
+	assert.False(t, results[1].Success)
+	assert.Nil(t, results[1].Locked, "the lock state of db is unknown")
+
+	audit, err := app.GetTFELockAuditLog("api", 0)
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	assert.Equal(t, "incident INC-42", audit[0].Reason)
+}
+
+// TestTFEWorkspaceLockWithSoftFailedRun verifies a run waiting for a policy override blocks a checked lock
+func TestTFEWorkspaceLockWithSoftFailedRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	workspace := map[string]interface{}{
+		"data": map[string]interface{}{
+			"id":         "ws-1",
+			"type":       "workspaces",
+			"attributes": map[string]interface{}{"name": "api", "locked": false},
+			"relationships": map[string]interface{}{
+				"current-run": map[string]interface{}{"data": map[string]string{"id": "run-1", "type": "runs"}},
+			},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/api", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, workspace)
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, workspace)
+	})
+	mux.HandleFunc("/api/v2/runs/run-1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{"data": testRunResource("run-1", "policy_soft_failed", false, false, true)})
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1/actions/lock", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the workspace must not be locked while its run waits for an override")
+	})
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+	results, err := app.LockTFEWorkspace(config, []string{"api"}, "maintenance", true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.Contains(t, results[0].Error, "run-1 failed a soft policy check")
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tfePlanJobsFile = "tfe-plan-jobs.json"
+
+	// maxTFEPlanJobs bounds how many finished plan jobs are kept on disk
+	maxTFEPlanJobs = 50
+
+	// defaultTFEPlanConcurrency is the number of workspaces planned in parallel
+	defaultTFEPlanConcurrency = 8
+
+	// tfeWorkspacePlanTimeout bounds a single workspace plan, queueing on TFE can take a while
+	tfeWorkspacePlanTimeout = 30 * time.Minute
+
+	// tfePlanRunTagPrefix starts the tag identifying the TFE run of a workspace plan in its message
+	tfePlanRunTagPrefix = "yak-gui-plan:"
+)
+
+// TFEPlanJob represents a background plan fan-out across workspaces
+type TFEPlanJob struct {
+	ID           string           `json:"id"`
+	Organization string           `json:"organization"`
+	Profile      string           `json:"profile,omitempty"` // environment profile the job was started under
+	Execution    TFEPlanExecution `json:"execution"`
+	Workspaces   []string         `json:"workspaces"`
+	Status       string           `json:"status"` // running, completed, canceled, interrupted
+	Total        int              `json:"total"`
+	Completed    int              `json:"completed"`
+	Changes      int              `json:"changes"`
+	NoChanges    int              `json:"noChanges"`
+	Errored      int              `json:"errored"`
+	Destructive  int              `json:"destructive"`
+	Results      []TFEPlanResult  `json:"results"`
+	StartedAt    time.Time        `json:"startedAt"`
+	FinishedAt   *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// TFEPlanJobEvent is emitted to the frontend for every finished workspace plan
+type TFEPlanJobEvent struct {
+	JobID       string        `json:"jobId"`
+	Result      TFEPlanResult `json:"result"`
+	Total       int           `json:"total"`
+	Completed   int           `json:"completed"`
+	Changes     int           `json:"changes"`
+	NoChanges   int           `json:"noChanges"`
+	Errored     int           `json:"errored"`
+	Destructive int           `json:"destructive"`
+}
+
+// tfePlanJobManager tracks running plan jobs and persists their progress
+type tfePlanJobManager struct {
+	app     *App
+	mu      sync.Mutex
+	running map[string]*runningTFEPlanJob
+
+	// planWorkspace plans a single workspace, it is replaceable for tests
+	planWorkspace func(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult
+
+	// summarizePlan fetches the resource-level changes of a run planned in a plan mode, it is replaceable for tests
+	summarizePlan func(ctx context.Context, config TFEConfig, runID, mode string) (*TFEPlanSummary, error)
+}
+
+type runningTFEPlanJob struct {
+	job    *TFEPlanJob
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newTFEPlanJobManager(app *App) *tfePlanJobManager {
+	manager := &tfePlanJobManager{
+		app:     app,
+		running: make(map[string]*runningTFEPlanJob),
+	}
+	manager.planWorkspace = app.planTFEWorkspace
+	manager.summarizePlan = app.fetchTFEPlanSummary
+	return manager
+}
+
+// isErrored reports whether a plan result represents a failed plan
+func (r TFEPlanResult) isErrored() bool {
+	return r.Error != "" || r.Status == "errored" || r.Status == "canceled"
+}
+
+// record adds a result to the job and updates the aggregate counts
+func (j *TFEPlanJob) record(result TFEPlanResult) {
+	j.Results = append(j.Results, result)
+	j.Completed++
+	switch {
+	case result.isErrored():
+		j.Errored++
+	case result.HasChanges:
+		j.Changes++
+	default:
+		j.NoChanges++
+	}
+	if result.Summary != nil && result.Summary.Destructive {
+		j.Destructive++
+	}
+}
+
+// planTFEWorkspace runs yak tfe plan for a single workspace and waits for the run to finish
+func (a *App) planTFEWorkspace(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+	if execution.RefreshOnly {
+		return a.planTFEWorkspaceRefreshOnly(ctx, config, workspace, execution)
+	}
+
+	start := time.Now()
+	result := TFEPlanResult{
+		WorkspaceName: workspace,
+		Message:       execution.Message,
+		CreatedAt:     start,
+	}
+
+	args := []string{"tfe", "plan"}
+	if config.Organization != "" {
+		args = append(args, "--organization", config.Organization)
+	}
+	args = append(args, "--version", execution.TerraformVersion, "--workspaces", workspace)
+	if message := taggedRunMessage(execution.Message, execution.runTag); message != "" {
+		args = append(args, "--message", message)
+	}
+	args = append(args, "--wait", "--json")
+
+	ctx, cancel := context.WithTimeout(ctx, tfeWorkspacePlanTimeout)
+	defer cancel()
+
+	cmd := a.yakCommand(ctx, args...)
+	cmd.Env = append(cmd.Env,
+		fmt.Sprintf("TFE_ENDPOINT=%s", config.Endpoint),
+		fmt.Sprintf("TFE_TOKEN=%s", config.Token),
+	)
+
+	output, err := cmd.CombinedOutput()
+	result.Duration = time.Since(start).Round(time.Second).String()
+	if err != nil {
+		result.Status = "errored"
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Error = fmt.Sprintf("plan timed out after %s", tfeWorkspacePlanTimeout)
+		} else {
+			result.Error = fmt.Sprintf("%v - %s", err, string(output))
+		}
+		return result
+	}
+
+	var results []TFEPlanResult
+	if err := json.Unmarshal(output, &results); err != nil || len(results) == 0 {
+		result.Status = "errored"
+		result.Error = fmt.Sprintf("failed to parse TFE plan response: %s", string(output))
+		return result
+	}
+
+	planned := results[0]
+	planned.WorkspaceName = workspace
+	planned.Duration = result.Duration
+	if planned.CreatedAt.IsZero() {
+		planned.CreatedAt = start
+	}
+	return planned
+}
+
+// taggedRunMessage appends the run tag to a run message
+func taggedRunMessage(message, tag string) string {
+	if tag == "" {
+		return message
+	}
+	if message == "" {
+		return "[" + tag + "]"
+	}
+	return message + " [" + tag + "]"
+}
+
+// cancelTFEPlanRun cancels the run of an interrupted plan and reports whether a run was found. yak is killed before
+// it reports the run ID, the run is then the one whose message carries the tag of the plan. Without a match nothing
+// is canceled, the workspace may be shared and its other runs belong to someone else.
+func cancelTFEPlanRun(ctx context.Context, config TFEConfig, workspace, runID, tag string) (bool, error) {
+	if runID == "" && tag == "" {
+		return false, nil
+	}
+	client, err := newTFEClient(config)
+	if err != nil {
+		return false, err
+	}
+	ws, err := client.getWorkspace(ctx, config.Organization, workspace)
+	if err != nil {
+		return false, err
+	}
+	runs, err := client.listRuns(ctx, config.Organization, ws, 10)
+	if err != nil {
+		return false, err
+	}
+
+	for _, run := range runs {
+		if runID != "" && run.ID != runID {
+			continue
+		}
+		if runID == "" && !strings.Contains(run.Message, "["+tag+"]") {
+			continue
+		}
+		switch {
+		case run.Actions.IsCancelable:
+			return true, client.runAction(ctx, run.ID, "cancel", "Plan job canceled from Yak GUI")
+		case run.Actions.IsDiscardable:
+			return true, client.runAction(ctx, run.ID, "discard", "Plan job canceled from Yak GUI")
+		default:
+			// The run already finished
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// resolvePlanWorkspaces returns the explicit workspace list, or the workspaces of the requested owner
+func (a *App) resolvePlanWorkspaces(config TFEConfig, execution TFEPlanExecution) ([]string, error) {
+	if len(execution.WorkspaceNames) > 0 {
+		return execution.WorkspaceNames, nil
+	}
+	if execution.Owner == "" {
+		return nil, fmt.Errorf("either workspace names or an owner is required")
+	}
+
+	workspaces, err := a.GetTFEWorkspaces(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, ws := range workspaces {
+		if ws.Owner == execution.Owner {
+			names = append(names, ws.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no workspaces found for owner %s", execution.Owner)
+	}
+	return names, nil
+}
+
+// fanOutTFEPlans plans every workspace with bounded concurrency and calls onResult as each plan finishes.
+// Workspaces that were not started before ctx is canceled are skipped.
+func (m *tfePlanJobManager) fanOutTFEPlans(ctx context.Context, config TFEConfig, workspaces []string, execution TFEPlanExecution, concurrency int, onResult func(TFEPlanResult)) {
+	if concurrency <= 0 {
+		concurrency = defaultTFEPlanConcurrency
+	}
+
+	var wg sync.WaitGroup
+	var resultMu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+
+	for _, workspace := range workspaces {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(workspace string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			workspaceExecution := execution
+			workspaceExecution.runTag = fmt.Sprintf("%s%s-%d", tfePlanRunTagPrefix, workspace, time.Now().UnixNano())
+			result := m.planWorkspace(ctx, config, workspace, workspaceExecution)
+			if ctx.Err() != nil && result.Error != "" {
+				result.Status = "canceled"
+				result.Error = "plan canceled"
+
+				// Killing yak leaves its run queued on TFE, refresh-only plans cancel their own run
+				if !execution.RefreshOnly {
+					result.Error = "plan canceled, its TFE run could not be identified and may still be queued"
+					if config.Token != "" {
+						cancelCtx, cancelCancel := context.WithTimeout(context.Background(), 30*time.Second)
+						found, err := cancelTFEPlanRun(cancelCtx, config, workspace, result.RunID, workspaceExecution.runTag)
+						cancelCancel()
+						switch {
+						case err != nil:
+							result.Error = fmt.Sprintf("plan canceled, failed to cancel its TFE run: %v", err)
+						case found:
+							result.Error = "plan canceled"
+						}
+					}
+				}
+			}
+
+			// Resource-level details need the TFE API, plans without changes have nothing to summarize
+			if config.Token != "" && result.RunID != "" && result.HasChanges && !result.isErrored() {
+				mode := planModeNormal
+				if execution.RefreshOnly {
+					mode = planModeRefreshOnly
+				}
+				summary, err := m.summarizePlan(ctx, config, result.RunID, mode)
+				if err != nil {
+					fmt.Printf("Warning: failed to summarize plan of %s: %v\n", workspace, err)
+				} else {
+					result.Summary = summary
+				}
+			}
+
+			resultMu.Lock()
+			defer resultMu.Unlock()
+			onResult(result)
+		}(workspace)
+	}
+
+	wg.Wait()
+}
+
+// StartTFEPlanJob starts planning each workspace in the background and returns immediately.
+// A "tfe-plan:result" event is emitted per workspace and "tfe-plan:done" once the job finishes.
+func (a *App) StartTFEPlanJob(config TFEConfig, execution TFEPlanExecution) (*TFEPlanJob, error) {
+	if execution.TerraformVersion == "" {
+		return nil, fmt.Errorf("terraform version is required")
+	}
+
+	workspaces, err := a.resolvePlanWorkspaces(config, execution)
+	if err != nil {
+		return nil, err
+	}
+
+	m := a.tfePlanJobs
+	env := a.currentEnv()
+	job := &TFEPlanJob{
+		ID:           fmt.Sprintf("plan-%d", time.Now().UnixNano()),
+		Organization: config.Organization,
+		Profile:      env.profile,
+		Execution:    execution,
+		Workspaces:   workspaces,
+		Status:       "running",
+		Total:        len(workspaces),
+		Results:      []TFEPlanResult{},
+		StartedAt:    time.Now(),
+	}
+
+	ctx, cancel := context.WithCancel(withEnvContext(context.Background(), env))
+	running := &runningTFEPlanJob{job: job, cancel: cancel, done: make(chan struct{})}
+
+	m.mu.Lock()
+	m.running[job.ID] = running
+	snapshot := *job
+	m.mu.Unlock()
+
+	if err := m.persist(snapshot); err != nil {
+		fmt.Printf("Warning: failed to persist TFE plan job %s: %v\n", job.ID, err)
+	}
+
+	go m.run(ctx, config, running)
+
+	return &snapshot, nil
+}
+
+// run executes the fan-out of a job and persists its final state
+func (m *tfePlanJobManager) run(ctx context.Context, config TFEConfig, running *runningTFEPlanJob) {
+	job := running.job
+	defer close(running.done)
+	defer running.cancel()
+
+	m.fanOutTFEPlans(ctx, config, job.Workspaces, job.Execution, defaultTFEPlanConcurrency, func(result TFEPlanResult) {
+		m.mu.Lock()
+		job.record(result)
+		event := TFEPlanJobEvent{
+			JobID:       job.ID,
+			Result:      result,
+			Total:       job.Total,
+			Completed:   job.Completed,
+			Changes:     job.Changes,
+			NoChanges:   job.NoChanges,
+			Errored:     job.Errored,
+			Destructive: job.Destructive,
+		}
+		snapshot := *job
+		snapshot.Results = append([]TFEPlanResult(nil), job.Results...)
+		m.mu.Unlock()
+
+		m.app.emitEvent("tfe-plan:result", event)
+		if err := m.persist(snapshot); err != nil {
+			fmt.Printf("Warning: failed to persist TFE plan job %s: %v\n", job.ID, err)
+		}
+	})
+
+	m.mu.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	if ctx.Err() != nil {
+		job.Status = "canceled"
+	} else {
+		job.Status = "completed"
+	}
+	snapshot := *job
+	delete(m.running, job.ID)
+	m.mu.Unlock()
+
+	if err := m.persist(snapshot); err != nil {
+		fmt.Printf("Warning: failed to persist TFE plan job %s: %v\n", job.ID, err)
+	}
+	m.app.emitEvent("tfe-plan:done", snapshot)
+}
+
+// persist stores the job in the plan job history, replacing a previous version of it
+func (m *tfePlanJobManager) persist(job TFEPlanJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []TFEPlanJob
+	return updateStateFile(tfePlanJobsFile, &jobs, func() error {
+		replaced := false
+		for i := range jobs {
+			if jobs[i].ID == job.ID {
+				jobs[i] = job
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			jobs = append(jobs, job)
+		}
+
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].StartedAt.After(jobs[j].StartedAt)
+		})
+		if len(jobs) > maxTFEPlanJobs {
+			jobs = jobs[:maxTFEPlanJobs]
+		}
+		return nil
+	})
+}
+
+// recover marks jobs that were running when the application last stopped as interrupted
+func (m *tfePlanJobManager) recover() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []TFEPlanJob
+	if _, err := loadStateFile(tfePlanJobsFile, &jobs); err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range jobs {
+		if jobs[i].Status == "running" && m.running[jobs[i].ID] == nil {
+			jobs[i].Status = "interrupted"
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return saveStateFile(tfePlanJobsFile, jobs)
+}
+
+// stopAll cancels every running job and waits for them to persist their state
+func (m *tfePlanJobManager) stopAll() {
+	m.mu.Lock()
+	var jobs []*runningTFEPlanJob
+	for _, running := range m.running {
+		jobs = append(jobs, running)
+	}
+	m.mu.Unlock()
+
+	for _, running := range jobs {
+		running.cancel()
+		<-running.done
+	}
+}
+
+// GetTFEPlanJobs returns running and past plan jobs, most recent first
+func (a *App) GetTFEPlanJobs() ([]TFEPlanJob, error) {
+	m := a.tfePlanJobs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []TFEPlanJob
+	if _, err := loadStateFile(tfePlanJobsFile, &jobs); err != nil {
+		return nil, err
+	}
+	if jobs == nil {
+		jobs = []TFEPlanJob{}
+	}
+
+	// Running jobs are more up to date in memory than on disk
+	for i := range jobs {
+		if running, ok := m.running[jobs[i].ID]; ok {
+			jobs[i] = *running.job
+			jobs[i].Results = append([]TFEPlanResult(nil), running.job.Results...)
+		}
+	}
+	return jobs, nil
+}
+
+// GetTFEPlanJob returns a single plan job
+func (a *App) GetTFEPlanJob(id string) (*TFEPlanJob, error) {
+	jobs, err := a.GetTFEPlanJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.ID == id {
+			return &job, nil
+		}
+	}
+	return nil, fmt.Errorf("TFE plan job '%s' not found", id)
+}
+
+// CancelTFEPlanJob cancels a running plan job, workspaces already planning are interrupted and, when the config
+// has a token, their TFE runs are canceled through the runs API
+func (a *App) CancelTFEPlanJob(id string) error {
+	m := a.tfePlanJobs
+	m.mu.Lock()
+	running, ok := m.running[id]
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("TFE plan job '%s' is not running", id)
+	}
+	running.cancel()
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitTFEPlanJob waits for a plan job to finish
+func waitTFEPlanJob(t *testing.T, app *App, id string) {
+	t.Helper()
+	app.tfePlanJobs.mu.Lock()
+	running := app.tfePlanJobs.running[id]
+	app.tfePlanJobs.mu.Unlock()
+	if running == nil {
+		return
+	}
+	select {
+	case <-running.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("plan job %s did not finish", id)
+	}
+}
+
+// TestTFEPlanJobResults verifies results are counted and summarized as each workspace finishes
+func TestTFEPlanJobResults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	results := map[string]TFEPlanResult{
+		"api":   {RunID: "run-api", Status: "planned", HasChanges: true},
+		"db":    {RunID: "run-db", Status: "planned_and_finished"},
+		"cache": {Status: "errored", Error: "exit status 1"},
+	}
+	app.tfePlanJobs.planWorkspace = func(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+		result := results[workspace]
+		result.WorkspaceName = workspace
+		return result
+	}
+	var summarized []string
+	app.tfePlanJobs.summarizePlan = func(ctx context.Context, config TFEConfig, runID, mode string) (*TFEPlanSummary, error) {
+		assert.Equal(t, planModeNormal, mode)
+		summarized = append(summarized, runID)
+		return &TFEPlanSummary{Delete: 1, Destructive: true}, nil
+	}
+
+	job, err := app.StartTFEPlanJob(TFEConfig{Organization: "acme", Token: "token"}, TFEPlanExecution{
+		WorkspaceNames:   []string{"api", "db", "cache"},
+		TerraformVersion: "1.9.0",
+	})
+	require.NoError(t, err)
+	waitTFEPlanJob(t, app, job.ID)
+
+	job, err = app.GetTFEPlanJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", job.Status)
+	assert.Equal(t, 3, job.Completed)
+	assert.Equal(t, 1, job.Changes)
+	assert.Equal(t, 1, job.NoChanges)
+	assert.Equal(t, 1, job.Errored)
+	assert.Equal(t, 1, job.Destructive)
+	assert.Equal(t, []string{"run-api"}, summarized, "only plans with changes are summarized")
+}
+
+// TestCancelTFEPlanJob verifies a canceled job interrupts its plans and cancels only the TFE runs carrying their tag
+func TestCancelTFEPlanJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		tagged  bool
+		actions []string
+		error   string
+	}{
+		{
+			name:    "run carries the tag",
+			tagged:  true,
+			actions: []string{"/api/v2/runs/run-queued/actions/cancel"},
+			error:   "plan canceled",
+		},
+		{
+			// The newest unfinished run may belong to a colleague planning the same workspace
+			name:  "no run carries the tag",
+			error: "plan canceled, its TFE run could not be identified and may still be queued",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			var mu sync.Mutex
+			var actions []string
+			var tag string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/organizations/acme/workspaces/api", func(w http.ResponseWriter, r *http.Request) {
+				writeJSONAPI(w, map[string]interface{}{
+					"data": map[string]interface{}{"id": "ws-1", "type": "workspaces", "attributes": map[string]string{"name": "api"}},
+				})
+			})
+			mux.HandleFunc("/api/v2/workspaces/ws-1/runs", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				queued := testRunResource("run-queued", "planning", false, true, false)
+				queued["attributes"].(map[string]interface{})["created-at"] = time.Now().UTC().Format(time.RFC3339)
+				if tt.tagged {
+					queued["attributes"].(map[string]interface{})["message"] = taggedRunMessage("Upgrade", tag)
+				}
+				colleague := testRunResource("run-colleague", "planning", false, true, false)
+				colleague["attributes"].(map[string]interface{})["created-at"] = time.Now().UTC().Format(time.RFC3339)
+				colleague["attributes"].(map[string]interface{})["message"] = "Queued manually"
+				writeJSONAPI(w, map[string]interface{}{"data": []interface{}{colleague, queued}})
+			})
+			mux.HandleFunc("/api/v2/runs/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				actions = append(actions, r.URL.Path)
+				mu.Unlock()
+				w.WriteHeader(http.StatusAccepted)
+			})
+			config := newTestTFEServer(t, mux)
+
+			app := NewApp()
+			started := make(chan struct{})
+			app.tfePlanJobs.planWorkspace = func(ctx context.Context, config TFEConfig, workspace string, execution TFEPlanExecution) TFEPlanResult {
+				mu.Lock()
+				tag = execution.runTag
+				mu.Unlock()
+				close(started)
+				<-ctx.Done()
+				return TFEPlanResult{WorkspaceName: workspace, Status: "errored", Error: "signal: killed"}
+			}
+
+			job, err := app.StartTFEPlanJob(config, TFEPlanExecution{WorkspaceNames: []string{"api"}, TerraformVersion: "1.9.0"})
+			require.NoError(t, err)
+			<-started
+			require.NoError(t, app.CancelTFEPlanJob(job.ID))
+			waitTFEPlanJob(t, app, job.ID)
+
+			job, err = app.GetTFEPlanJob(job.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "canceled", job.Status)
+			require.Len(t, job.Results, 1)
+			assert.Equal(t, "canceled", job.Results[0].Status)
+			assert.Equal(t, tt.error, job.Results[0].Error)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.True(t, strings.HasPrefix(tag, tfePlanRunTagPrefix+"api-"))
+			assert.Equal(t, tt.actions, actions)
+
+			assert.Error(t, app.CancelTFEPlanJob(job.ID), "a finished job is not running")
+		})
+	}
+}
+
+// TestTaggedRunMessage verifies the plan tag is appended to the run message
+func TestTaggedRunMessage(t *testing.T) {
+	assert.Equal(t, "Upgrade [yak-gui-plan:api-1]", taggedRunMessage("Upgrade", "yak-gui-plan:api-1"))
+	assert.Equal(t, "[yak-gui-plan:api-1]", taggedRunMessage("", "yak-gui-plan:api-1"))
+	assert.Equal(t, "Upgrade", taggedRunMessage("Upgrade", ""))
+}
+
+// TestRecoverTFEPlanJobs verifies jobs left running by a previous instance are marked interrupted
+func TestRecoverTFEPlanJobs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := NewApp()
+
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: "running", want: "interrupted"},
+		{status: "completed", want: "completed"},
+		{status: "canceled", want: "canceled"},
+	}
+	for i, tt := range tests {
+		require.NoError(t, app.tfePlanJobs.persist(TFEPlanJob{
+			ID:        tt.status,
+			Status:    tt.status,
+			StartedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	require.NoError(t, app.tfePlanJobs.recover())
+	for _, tt := range tests {
+		job, err := app.GetTFEPlanJob(tt.status)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, job.Status, tt.status)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Resource change actions
+const (
+	changeCreate  = "create"
+	changeUpdate  = "update"
+	changeDelete  = "delete"
+	changeReplace = "replace"
+)
+
+// Plan modes of a run, they decide which section of the JSON plan holds the changes
+const (
+	planModeNormal      = "normal"
+	planModeRefreshOnly = "refresh-only"
+)
+
+// TFEResourceChange represents a single resource change in a plan
+type TFEResourceChange struct {
+	Address      string `json:"address"`
+	ResourceType string `json:"resourceType"`
+	Action       string `json:"action"` // create, update, delete, replace
+}
+
+// TFEPlanSummary represents the resource-level changes of a plan
+type TFEPlanSummary struct {
+	Create      int                 `json:"create"`
+	Update      int                 `json:"update"`
+	Delete      int                 `json:"delete"`
+	Replace     int                 `json:"replace"`
+	Destructive bool                `json:"destructive"` // true when the plan deletes or replaces resources
+	Changes     []TFEResourceChange `json:"changes"`
+}
+
+// TFEWorkspaceResourceChange is a resource change tagged with its workspace
+type TFEWorkspaceResourceChange struct {
+	Workspace string `json:"workspace"`
+	Address   string `json:"address"`
+	Action    string `json:"action"`
+}
+
+// TFEPlanChangeGroup aggregates the changes of one resource type across workspaces
+type TFEPlanChangeGroup struct {
+	ResourceType string                       `json:"resourceType"`
+	Create       int                          `json:"create"`
+	Update       int                          `json:"update"`
+	Delete       int                          `json:"delete"`
+	Replace      int                          `json:"replace"`
+	Destructive  bool                         `json:"destructive"`
+	Workspaces   []string                     `json:"workspaces"`
+	Changes      []TFEWorkspaceResourceChange `json:"changes"`
+}
+
+// TFEPlanChangeReport summarizes the changes of many workspace plans
+type TFEPlanChangeReport struct {
+	Workspaces            int                  `json:"workspaces"`
+	WorkspacesWithChanges []string             `json:"workspacesWithChanges"`
+	DestructiveWorkspaces []string             `json:"destructiveWorkspaces"`
+	Unsummarized          []string             `json:"unsummarized"` // workspaces with changes but no resource details
+	Groups                []TFEPlanChangeGroup `json:"groups"`
+}
+
+// terraformResourceChange is a resource entry of the `terraform show -json` plan format
+type terraformResourceChange struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Mode    string `json:"mode"`
+	Change  struct {
+		Actions []string `json:"actions"`
+	} `json:"change"`
+}
+
+// terraformPlanJSON is the subset of the `terraform show -json` plan format used for summaries
+type terraformPlanJSON struct {
+	ResourceChanges []terraformResourceChange `json:"resource_changes"`
+	ResourceDrift   []terraformResourceChange `json:"resource_drift"`
+}
+
+// summarizeTerraformPlan parses a JSON plan into resource-level changes. Refresh-only plans change nothing,
+// the differences they find are reported as drift; normal plans list drift next to their changes, it is ignored.
+func summarizeTerraformPlan(data []byte, mode string) (*TFEPlanSummary, error) {
+	var plan terraformPlanJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+
+	var resources []terraformResourceChange
+	switch mode {
+	case planModeNormal:
+		resources = plan.ResourceChanges
+	case planModeRefreshOnly:
+		resources = plan.ResourceDrift
+	default:
+		return nil, fmt.Errorf("unknown plan mode '%s'", mode)
+	}
+
+	summary := &TFEPlanSummary{Changes: []TFEResourceChange{}}
+	for _, rc := range resources {
+		// Data sources are read, never changed
+		if rc.Mode == "data" {
+			continue
+		}
+
+		action := planChangeAction(rc.Change.Actions)
+		if action == "" {
+			continue
+		}
+
+		switch action {
+		case changeCreate:
+			summary.Create++
+		case changeUpdate:
+			summary.Update++
+		case changeDelete:
+			summary.Delete++
+			summary.Destructive = true
+		case changeReplace:
+			summary.Replace++
+			summary.Destructive = true
+		}
+
+		summary.Changes = append(summary.Changes, TFEResourceChange{
+			Address:      rc.Address,
+			ResourceType: rc.Type,
+			Action:       action,
+		})
+	}
+
+	sort.Slice(summary.Changes, func(i, j int) bool {
+		return summary.Changes[i].Address < summary.Changes[j].Address
+	})
+	return summary, nil
+}
+
+// planChangeAction maps Terraform change actions to a single action, no-op and read yield ""
+func planChangeAction(actions []string) string {
+	hasCreate, hasDelete := false, false
+	for _, action := range actions {
+		switch action {
+		case "create":
+			hasCreate = true
+		case "delete":
+			hasDelete = true
+		case "update":
+			return changeUpdate
+		}
+	}
+
+	switch {
+	case hasCreate && hasDelete:
+		return changeReplace
+	case hasCreate:
+		return changeCreate
+	case hasDelete:
+		return changeDelete
+	default:
+		return ""
+	}
+}
+
+// getPlanJSON downloads the JSON execution plan of a run
+func (c *tfeClient) getPlanJSON(ctx context.Context, runID string) ([]byte, error) {
+	var raw json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(runID)+"/plan/json-output", nil, nil, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// fetchTFEPlanSummary fetches and summarizes the plan of a run planned in the given mode
+func (a *App) fetchTFEPlanSummary(ctx context.Context, config TFEConfig, runID, mode string) (*TFEPlanSummary, error) {
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.getPlanJSON(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan JSON for run %s: %w", runID, err)
+	}
+	return summarizeTerraformPlan(data, mode)
+}
+
+// GetTFEPlanSummary returns the resource-level changes of the plan of a run
+func (a *App) GetTFEPlanSummary(config TFEConfig, runID string) (*TFEPlanSummary, error) {
+	if runID == "" {
+		return nil, fmt.Errorf("run ID is required")
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	run, err := client.getRun(ctx, config.Organization, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TFE run %s: %w", runID, err)
+	}
+	mode := planModeNormal
+	if run.RefreshOnly {
+		mode = planModeRefreshOnly
+	}
+	return a.fetchTFEPlanSummary(ctx, config, runID, mode)
+}
+
+// buildTFEPlanChangeReport groups plan changes across workspaces by resource type
+func buildTFEPlanChangeReport(results []TFEPlanResult) *TFEPlanChangeReport {
+	report := &TFEPlanChangeReport{
+		Workspaces:            len(results),
+		WorkspacesWithChanges: []string{},
+		DestructiveWorkspaces: []string{},
+		Unsummarized:          []string{},
+		Groups:                []TFEPlanChangeGroup{},
+	}
+
+	groups := make(map[string]*TFEPlanChangeGroup)
+	for _, result := range results {
+		if result.isErrored() || !result.HasChanges {
+			continue
+		}
+		report.WorkspacesWithChanges = append(report.WorkspacesWithChanges, result.WorkspaceName)
+
+		if result.Summary == nil {
+			report.Unsummarized = append(report.Unsummarized, result.WorkspaceName)
+			continue
+		}
+		if result.Summary.Destructive {
+			report.DestructiveWorkspaces = append(report.DestructiveWorkspaces, result.WorkspaceName)
+		}
+
+		for _, change := range result.Summary.Changes {
+			group, ok := groups[change.ResourceType]
+			if !ok {
+				group = &TFEPlanChangeGroup{ResourceType: change.ResourceType}
+				groups[change.ResourceType] = group
+			}
+
+			switch change.Action {
+			case changeCreate:
+				group.Create++
+			case changeUpdate:
+				group.Update++
+			case changeDelete:
+				group.Delete++
+				group.Destructive = true
+			case changeReplace:
+				group.Replace++
+				group.Destructive = true
+			}
+
+			if len(group.Workspaces) == 0 || group.Workspaces[len(group.Workspaces)-1] != result.WorkspaceName {
+				group.Workspaces = append(group.Workspaces, result.WorkspaceName)
+			}
+			group.Changes = append(group.Changes, TFEWorkspaceResourceChange{
+				Workspace: result.WorkspaceName,
+				Address:   change.Address,
+				Action:    change.Action,
+			})
+		}
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+
+	// Destructive groups first, then by number of changes
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.Destructive != gj.Destructive {
+			return gi.Destructive
+		}
+		if len(gi.Changes) != len(gj.Changes) {
+			return len(gi.Changes) > len(gj.Changes)
+		}
+		return gi.ResourceType < gj.ResourceType
+	})
+	sort.Strings(report.WorkspacesWithChanges)
+	sort.Strings(report.DestructiveWorkspaces)
+	sort.Strings(report.Unsummarized)
+
+	return report
+}
+
+// GetTFEPlanJobChangeReport groups the changes of a plan job by resource type and highlights destructive plans
+func (a *App) GetTFEPlanJobChangeReport(jobID string) (*TFEPlanChangeReport, error) {
+	job, err := a.GetTFEPlanJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTFEPlanChangeReport(job.Results), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readPlanFixture reads a `terraform show -json` plan from testdata
+func readPlanFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+// TestPlanChangeAction verifies Terraform change actions collapse into a single action
+func TestPlanChangeAction(t *testing.T) {
+	tests := []struct {
+		actions []string
+		want    string
+	}{
+		{actions: []string{"create"}, want: changeCreate},
+		{actions: []string{"update"}, want: changeUpdate},
+		{actions: []string{"delete"}, want: changeDelete},
+		{actions: []string{"delete", "create"}, want: changeReplace},
+		{actions: []string{"create", "delete"}, want: changeReplace},
+		{actions: []string{"no-op"}, want: ""},
+		{actions: []string{"read"}, want: ""},
+		{actions: nil, want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, planChangeAction(tt.actions), "%v", tt.actions)
+	}
+}
+
+// TestSummarizeTerraformPlan verifies the changes of `terraform show -json` plans are counted and sorted
+func TestSummarizeTerraformPlan(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    TFEPlanSummary
+	}{
+		{
+			fixture: "plan-changes.json",
+			want: TFEPlanSummary{
+				Create:      1,
+				Update:      1,
+				Delete:      1,
+				Replace:     2,
+				Destructive: true,
+				Changes: []TFEResourceChange{
+					{Address: "aws_iam_role.deployer", ResourceType: "aws_iam_role", Action: changeCreate},
+					{Address: "aws_instance.bastion", ResourceType: "aws_instance", Action: changeReplace},
+					{Address: "aws_lb.public", ResourceType: "aws_lb", Action: changeReplace},
+					{Address: "aws_s3_bucket.logs", ResourceType: "aws_s3_bucket", Action: changeUpdate},
+					{Address: "aws_security_group.legacy", ResourceType: "aws_security_group", Action: changeDelete},
+				},
+			},
+		},
+		{
+			fixture: "plan-no-changes.json",
+			want:    TFEPlanSummary{Changes: []TFEResourceChange{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			summary, err := summarizeTerraformPlan(readPlanFixture(t, tt.fixture), planModeNormal)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *summary)
+		})
+	}
+
+	_, err := summarizeTerraformPlan([]byte("Plan: 1 to add"), planModeNormal)
+	assert.Error(t, err)
+}
+
+// TestSummarizeTerraformPlanMode verifies drift is summarized for refresh-only plans only
+func TestSummarizeTerraformPlanMode(t *testing.T) {
+	tests := []struct {
+		fixture string
+		mode    string
+		changes []TFEResourceChange
+	}{
+		{
+			fixture: "plan-refresh-only.json",
+			mode:    planModeRefreshOnly,
+			changes: []TFEResourceChange{
+				{Address: "aws_db_instance.main", ResourceType: "aws_db_instance", Action: changeUpdate},
+				{Address: "aws_security_group_rule.legacy", ResourceType: "aws_security_group_rule", Action: changeDelete},
+			},
+		},
+		{
+			// A normal plan without changes still reports what drifted, it must not be summarized as changes
+			fixture: "plan-drift-no-changes.json",
+			mode:    planModeNormal,
+			changes: []TFEResourceChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			summary, err := summarizeTerraformPlan(readPlanFixture(t, tt.fixture), tt.mode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changes, summary.Changes)
+		})
+	}
+
+	_, err := summarizeTerraformPlan(readPlanFixture(t, "plan-refresh-only.json"), "destroy")
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const tfeResolversFile = "tfe-resolvers.json"
+
+// TFEResolverRule maps workspace names matching a regular expression to a value.
+// The value may reference capture groups of the pattern, e.g. "$1-aws-$2".
+type TFEResolverRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Value   string `json:"value" yaml:"value"`
+}
+
+// TFEResolverConfig configures how the owner and environment of workspaces are resolved for an organization.
+// Tags carrying the configured prefixes win over name rules, rules are evaluated in order.
+type TFEResolverConfig struct {
+	OwnerTagPrefix       string            `json:"ownerTagPrefix,omitempty" yaml:"ownerTagPrefix"`
+	EnvironmentTagPrefix string            `json:"environmentTagPrefix,omitempty" yaml:"environmentTagPrefix"`
+	OwnerRules           []TFEResolverRule `json:"ownerRules" yaml:"ownerRules"`
+	EnvironmentRules     []TFEResolverRule `json:"environmentRules" yaml:"environmentRules"`
+}
+
+// tfeWorkspaceResolver fills in the owner and environment of a workspace
+type tfeWorkspaceResolver interface {
+	Resolve(workspace *TFEWorkspace)
+}
+
+// compiledResolverRule is a TFEResolverRule with its pattern compiled
+type compiledResolverRule struct {
+	re    *regexp.Regexp
+	value string
+}
+
+// ruleWorkspaceResolver resolves owner and environment from tags and name rules
+type ruleWorkspaceResolver struct {
+	ownerTagPrefix       string
+	environmentTagPrefix string
+	ownerRules           []compiledResolverRule
+	environmentRules     []compiledResolverRule
+}
+
+// newRuleWorkspaceResolver compiles a resolver configuration
+func newRuleWorkspaceResolver(config TFEResolverConfig) (*ruleWorkspaceResolver, error) {
+	ownerRules, err := compileResolverRules(config.OwnerRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner rule: %w", err)
+	}
+	environmentRules, err := compileResolverRules(config.EnvironmentRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment rule: %w", err)
+	}
+
+	return &ruleWorkspaceResolver{
+		ownerTagPrefix:       config.OwnerTagPrefix,
+		environmentTagPrefix: config.EnvironmentTagPrefix,
+		ownerRules:           ownerRules,
+		environmentRules:     environmentRules,
+	}, nil
+}
+
+func compileResolverRules(rules []TFEResolverRule) ([]compiledResolverRule, error) {
+	compiled := make([]compiledResolverRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", rule.Pattern, err)
+		}
+		compiled = append(compiled, compiledResolverRule{re: re, value: rule.Value})
+	}
+	return compiled, nil
+}
+
+// Resolve sets the owner and environment of the workspace, keeping values that are already set
+func (r *ruleWorkspaceResolver) Resolve(workspace *TFEWorkspace) {
+	if workspace.Owner == "" {
+		workspace.Owner = resolveWorkspaceValue(workspace, r.ownerTagPrefix, r.ownerRules)
+	}
+	if workspace.Environment == "" {
+		workspace.Environment = resolveWorkspaceValue(workspace, r.environmentTagPrefix, r.environmentRules)
+	}
+}
+
+func resolveWorkspaceValue(workspace *TFEWorkspace, tagPrefix string, rules []compiledResolverRule) string {
+	if tagPrefix != "" {
+		for _, tag := range workspace.Tags {
+			if strings.HasPrefix(tag, tagPrefix) && len(tag) > len(tagPrefix) {
+				return strings.TrimPrefix(tag, tagPrefix)
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		if match := rule.re.FindStringSubmatchIndex(workspace.Name); match != nil {
+			return string(rule.re.ExpandString(nil, rule.value, workspace.Name, match))
+		}
+	}
+
+	return "unknown"
+}
+
+// tfeResolverFor returns the workspace resolver configured for an organization
+func (a *App) tfeResolverFor(organization string) (tfeWorkspaceResolver, error) {
+	config, err := a.GetTFEResolverConfig(organization)
+	if err != nil {
+		return nil, err
+	}
+	return newRuleWorkspaceResolver(config)
+}
+
+// GetTFEResolverConfig returns the owner/environment resolver configuration of an organization
+func (a *App) GetTFEResolverConfig(organization string) (TFEResolverConfig, error) {
+	configs := map[string]TFEResolverConfig{}
+	if _, err := loadStateFile(tfeResolversFile, &configs); err != nil {
+		return TFEResolverConfig{}, err
+	}
+
+	if config, ok := configs[organization]; ok {
+		return config, nil
+	}
+
+	// Organizations without a saved configuration use the naming rules of the site config
+	site, err := a.siteConfig()
+	if err != nil {
+		return TFEResolverConfig{}, err
+	}
+	return site.TFE.Resolver, nil
+}
+
+// SaveTFEResolverConfig validates and saves the owner/environment resolver configuration of an organization
+func (a *App) SaveTFEResolverConfig(organization string, config TFEResolverConfig) error {
+	if organization == "" {
+		return fmt.Errorf("TFE organization is required")
+	}
+	if _, err := newRuleWorkspaceResolver(config); err != nil {
+		return err
+	}
+
+	configs := map[string]TFEResolverConfig{}
+	return updateStateFile(tfeResolversFile, &configs, func() error {
+		configs[organization] = config
+		return nil
+	})
+}
+
+// ResetTFEResolverConfig removes the saved resolver configuration so the site config rules apply again
+func (a *App) ResetTFEResolverConfig(organization string) error {
+	configs := map[string]TFEResolverConfig{}
+	return updateStateFile(tfeResolversFile, &configs, func() error {
+		delete(configs, organization)
+		return nil
+	})
+}
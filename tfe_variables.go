@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Variable categories
+const (
+	tfeVariableTerraform = "terraform"
+	tfeVariableEnv       = "env"
+)
+
+// TFEVariable represents a workspace variable, Value is always empty for sensitive variables
+type TFEVariable struct {
+	ID          string `json:"id"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category"` // terraform or env
+	HCL         bool   `json:"hcl"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+// TFEVariableInput describes a variable to create or update. Sensitive values are never returned, so an empty
+// Value keeps the stored value when updating a sensitive variable.
+type TFEVariableInput struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category"` // terraform (default) or env
+	HCL         bool   `json:"hcl"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+// TFEVariableMatch is a variable found in a workspace by a cross-workspace search
+type TFEVariableMatch struct {
+	WorkspaceID   string      `json:"workspaceId"`
+	WorkspaceName string      `json:"workspaceName"`
+	Variable      TFEVariable `json:"variable"`
+}
+
+// tfeVariableAttributes are the variable attributes of the TFE API
+type tfeVariableAttributes struct {
+	Key         string  `json:"key"`
+	Value       *string `json:"value"`
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+	HCL         bool    `json:"hcl"`
+	Sensitive   bool    `json:"sensitive"`
+}
+
+// toTFEVariable maps a variable resource, dropping the value of sensitive variables
+func (r tfeResource) toTFEVariable() (TFEVariable, error) {
+	var attrs tfeVariableAttributes
+	if err := json.Unmarshal(r.Attributes, &attrs); err != nil {
+		return TFEVariable{}, fmt.Errorf("failed to parse variable %s: %w", r.ID, err)
+	}
+
+	variable := TFEVariable{
+		ID:          r.ID,
+		Key:         attrs.Key,
+		Description: attrs.Description,
+		Category:    attrs.Category,
+		HCL:         attrs.HCL,
+		Sensitive:   attrs.Sensitive,
+	}
+	if attrs.Value != nil && !attrs.Sensitive {
+		variable.Value = *attrs.Value
+	}
+	return variable, nil
+}
+
+// listVariables returns the variables of a workspace
+func (c *tfeClient) listVariables(ctx context.Context, workspaceID string) ([]TFEVariable, error) {
+	resources, _, err := c.list(ctx, "/workspaces/"+url.PathEscape(workspaceID)+"/vars", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make([]TFEVariable, 0, len(resources))
+	for _, resource := range resources {
+		variable, err := resource.toTFEVariable()
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].Category != variables[j].Category {
+			return variables[i].Category > variables[j].Category // terraform before env
+		}
+		return variables[i].Key < variables[j].Key
+	})
+	return variables, nil
+}
+
+// saveVariable creates a variable, or updates it when variableID is set. The value of a sensitive
+// variable is left out of an update when empty so that TFE keeps the stored one.
+func (c *tfeClient) saveVariable(ctx context.Context, workspaceID, variableID string, input TFEVariableInput) (TFEVariable, error) {
+	attributes := map[string]interface{}{
+		"key":         input.Key,
+		"value":       input.Value,
+		"description": input.Description,
+		"category":    input.Category,
+		"hcl":         input.HCL,
+		"sensitive":   input.Sensitive,
+	}
+	if variableID != "" && input.Sensitive && input.Value == "" {
+		delete(attributes, "value")
+	}
+	data := map[string]interface{}{
+		"type":       "vars",
+		"attributes": attributes,
+	}
+
+	method, path := http.MethodPost, "/workspaces/"+url.PathEscape(workspaceID)+"/vars"
+	if variableID != "" {
+		data["id"] = variableID
+		method, path = http.MethodPatch, path+"/"+url.PathEscape(variableID)
+	}
+
+	var resp tfeSingleResponse
+	if err := c.do(ctx, method, path, nil, map[string]interface{}{"data": data}, &resp); err != nil {
+		return TFEVariable{}, err
+	}
+	return resp.Data.toTFEVariable()
+}
+
+// deleteVariable removes a variable from a workspace
+func (c *tfeClient) deleteVariable(ctx context.Context, workspaceID, variableID string) error {
+	return c.do(ctx, http.MethodDelete, "/workspaces/"+url.PathEscape(workspaceID)+"/vars/"+url.PathEscape(variableID), nil, nil, nil)
+}
+
+// normalizeTFEVariableCategory defaults to the terraform category and rejects unknown ones
+func normalizeTFEVariableCategory(category string) (string, error) {
+	switch strings.ToLower(category) {
+	case "", tfeVariableTerraform:
+		return tfeVariableTerraform, nil
+	case tfeVariableEnv:
+		return tfeVariableEnv, nil
+	default:
+		return "", fmt.Errorf("unknown variable category '%s', use terraform or env", category)
+	}
+}
+
+// findVariable returns the variable with a key in a category, or nil
+func findVariable(variables []TFEVariable, key, category string) *TFEVariable {
+	for i := range variables {
+		if variables[i].Key == key && variables[i].Category == category {
+			return &variables[i]
+		}
+	}
+	return nil
+}
+
+// GetTFEWorkspaceVariables returns the Terraform and environment variables of a workspace, sensitive values are never returned
+func (a *App) GetTFEWorkspaceVariables(config TFEConfig, workspace string) ([]TFEVariable, error) {
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ws, err := client.getWorkspace(ctx, config.Organization, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TFE workspace %s: %w", workspace, err)
+	}
+
+	variables, err := client.listVariables(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variables of workspace %s: %w", workspace, err)
+	}
+	return variables, nil
+}
+
+// SetTFEWorkspaceVariable creates or updates a workspace variable identified by its key and category
+func (a *App) SetTFEWorkspaceVariable(config TFEConfig, workspace string, input TFEVariableInput) (*TFEVariable, error) {
+	input.Key = strings.TrimSpace(input.Key)
+	if input.Key == "" {
+		return nil, fmt.Errorf("variable key is required")
+	}
+	category, err := normalizeTFEVariableCategory(input.Category)
+	if err != nil {
+		return nil, err
+	}
+	input.Category = category
+	if input.HCL && category == tfeVariableEnv {
+		return nil, fmt.Errorf("environment variables cannot be HCL")
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ws, err := client.getWorkspace(ctx, config.Organization, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TFE workspace %s: %w", workspace, err)
+	}
+
+	variables, err := client.listVariables(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variables of workspace %s: %w", workspace, err)
+	}
+
+	variableID := ""
+	if existing := findVariable(variables, input.Key, category); existing != nil {
+		// TFE refuses to reveal a sensitive value by turning the flag off
+		if existing.Sensitive && !input.Sensitive {
+			return nil, fmt.Errorf("variable %s is sensitive and cannot be made non-sensitive, delete and recreate it", input.Key)
+		}
+		variableID = existing.ID
+	}
+
+	variable, err := client.saveVariable(ctx, ws.ID, variableID, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save variable %s on workspace %s: %w", input.Key, workspace, err)
+	}
+	return &variable, nil
+}
+
+// DeleteTFEWorkspaceVariable deletes a workspace variable identified by its key and category
+func (a *App) DeleteTFEWorkspaceVariable(config TFEConfig, workspace, key, category string) error {
+	category, err := normalizeTFEVariableCategory(category)
+	if err != nil {
+		return err
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ws, err := client.getWorkspace(ctx, config.Organization, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to get TFE workspace %s: %w", workspace, err)
+	}
+
+	variables, err := client.listVariables(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list variables of workspace %s: %w", workspace, err)
+	}
+
+	existing := findVariable(variables, key, category)
+	if existing == nil {
+		return fmt.Errorf("%s variable %s not found on workspace %s", category, key, workspace)
+	}
+
+	if err := client.deleteVariable(ctx, ws.ID, existing.ID); err != nil {
+		return fmt.Errorf("failed to delete variable %s on workspace %s: %w", key, workspace, err)
+	}
+	return nil
+}
+
+// FindTFEVariable searches every workspace of the organization for variables with a key.
+// An empty category matches both Terraform and environment variables.
+func (a *App) FindTFEVariable(config TFEConfig, key, category string) ([]TFEVariableMatch, error) {
+	if key == "" {
+		return nil, fmt.Errorf("variable key is required")
+	}
+	if category != "" {
+		normalized, err := normalizeTFEVariableCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		category = normalized
+	}
+
+	client, err := newTFEClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	workspaces, err := client.listWorkspaces(ctx, config.Organization, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TFE workspaces: %w", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		matches  = []TFEVariableMatch{}
+		firstErr error
+	)
+	sem := make(chan struct{}, defaultTFEPlanConcurrency)
+	for _, ws := range workspaces {
+		wg.Add(1)
+		go func(ws TFEWorkspace) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			variables, err := client.listVariables(ctx, ws.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to list variables of workspace %s: %w", ws.Name, err)
+				}
+				return
+			}
+			for _, variable := range variables {
+				if variable.Key == key && (category == "" || variable.Category == category) {
+					matches = append(matches, TFEVariableMatch{WorkspaceID: ws.ID, WorkspaceName: ws.Name, Variable: variable})
+				}
+			}
+		}(ws)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].WorkspaceName != matches[j].WorkspaceName {
+			return matches[i].WorkspaceName < matches[j].WorkspaceName
+		}
+		return matches[i].Variable.Category > matches[j].Variable.Category
+	})
+	return matches, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testVariableStore is an in-memory stand-in for the TFE variables API
+type testVariableStore struct {
+	mu     sync.Mutex
+	nextID int
+	vars   map[string][]map[string]interface{} // workspace ID -> variable attributes with "id"
+}
+
+// resource renders a stored variable, it leaks sensitive values on purpose to check they are dropped
+func (s *testVariableStore) resource(v map[string]interface{}) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	for k, value := range v {
+		if k != "id" {
+			attrs[k] = value
+		}
+	}
+	return map[string]interface{}{"id": v["id"], "type": "vars", "attributes": attrs}
+}
+
+func (s *testVariableStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// /api/v2/workspaces/{id}/vars[/{var}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2/workspaces/"), "/")
+	wsID := parts[0]
+
+	switch {
+	case len(parts) == 1:
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{
+				"id": wsID, "type": "workspaces",
+				"attributes": map[string]interface{}{"name": strings.TrimPrefix(wsID, "ws-")},
+			},
+		})
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		var data []map[string]interface{}
+		for _, v := range s.vars[wsID] {
+			data = append(data, s.resource(v))
+		}
+		writeJSONAPI(w, map[string]interface{}{"data": data})
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var body struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.nextID++
+		v := body.Data.Attributes
+		v["id"] = fmt.Sprintf("var-%d", s.nextID)
+		s.vars[wsID] = append(s.vars[wsID], v)
+		w.WriteHeader(http.StatusCreated)
+		writeJSONAPI(w, map[string]interface{}{"data": s.resource(v)})
+	case len(parts) == 3:
+		for i, v := range s.vars[wsID] {
+			if v["id"] != parts[2] {
+				continue
+			}
+			if r.Method == http.MethodDelete {
+				s.vars[wsID] = append(s.vars[wsID][:i], s.vars[wsID][i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var body struct {
+				Data struct {
+					Attributes map[string]interface{} `json:"attributes"`
+				} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			for k, value := range body.Data.Attributes {
+				v[k] = value
+			}
+			writeJSONAPI(w, map[string]interface{}{"data": s.resource(v)})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestTFEWorkspaceVariables verifies variable listing, upserts, deletion and the cross-workspace search
+func TestTFEWorkspaceVariables(t *testing.T) {
+	store := &testVariableStore{vars: map[string][]map[string]interface{}{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v2/organizations/acme/workspaces/")
+		writeJSONAPI(w, map[string]interface{}{
+			"data": map[string]interface{}{"id": "ws-" + name, "type": "workspaces", "attributes": map[string]interface{}{"name": name}},
+		})
+	})
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONAPI(w, map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "ws-api", "type": "workspaces", "attributes": map[string]interface{}{"name": "api"}},
+				{"id": "ws-db", "type": "workspaces", "attributes": map[string]interface{}{"name": "db"}},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v2/workspaces/", store.handle)
+	config := newTestTFEServer(t, mux)
+
+	app := NewApp()
+
+	_, err := app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "region", Value: "eu-west-1"})
+	require.NoError(t, err)
+	secret, err := app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "DB_PASSWORD", Value: "hunter2", Category: "env", Sensitive: true})
+	require.NoError(t, err)
+	assert.Empty(t, secret.Value, "sensitive values must not be returned")
+
+	// Editing the description of a sensitive variable without a value keeps the stored secret
+	_, err = app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "DB_PASSWORD", Category: "env", Sensitive: true, Description: "database"})
+	require.NoError(t, err)
+	store.mu.Lock()
+	assert.Equal(t, "hunter2", store.vars["ws-api"][1]["value"])
+	assert.Equal(t, "database", store.vars["ws-api"][1]["description"])
+	store.mu.Unlock()
+
+	_, err = app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "X", Category: "env", HCL: true})
+	assert.Error(t, err)
+	_, err = app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "DB_PASSWORD", Value: "plain", Category: "env"})
+	assert.ErrorContains(t, err, "cannot be made non-sensitive")
+
+	// Setting an existing key updates it in place
+	updated, err := app.SetTFEWorkspaceVariable(config, "api", TFEVariableInput{Key: "region", Value: "eu-central-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central-1", updated.Value)
+	_, err = app.SetTFEWorkspaceVariable(config, "db", TFEVariableInput{Key: "region", Value: "us-east-1", Description: "primary"})
+	require.NoError(t, err)
+
+	variables, err := app.GetTFEWorkspaceVariables(config, "api")
+	require.NoError(t, err)
+	require.Len(t, variables, 2)
+	assert.Equal(t, "region", variables[0].Key)
+	assert.Equal(t, "terraform", variables[0].Category)
+	assert.Equal(t, "DB_PASSWORD", variables[1].Key)
+	assert.True(t, variables[1].Sensitive)
+	assert.Empty(t, variables[1].Value)
+
+	matches, err := app.FindTFEVariable(config, "region", "terraform")
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "api", matches[0].WorkspaceName)
+	assert.Equal(t, "eu-central-1", matches[0].Variable.Value)
+	assert.Equal(t, "us-east-1", matches[1].Variable.Value)
+
+	require.NoError(t, app.DeleteTFEWorkspaceVariable(config, "api", "DB_PASSWORD", "env"))
+	assert.Error(t, app.DeleteTFEWorkspaceVariable(config, "api", "DB_PASSWORD", "env"))
+
+	matches, err = app.FindTFEVariable(config, "DB_PASSWORD", "")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}